@@ -0,0 +1,279 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/QuickOrBeDead/GoLangLearning/format"
+	"github.com/QuickOrBeDead/GoLangLearning/highlight"
+	"github.com/QuickOrBeDead/GoLangLearning/lexer"
+	"github.com/QuickOrBeDead/GoLangLearning/parser"
+)
+
+// DefaultMaxBodySize is the request body limit of the handler when
+// Handler.MaxBodySize is not set.
+const DefaultMaxBodySize = 1 << 20
+
+// Handler serves the JSON API:
+//
+//	POST /api/tokenize  returns {"tokens": [...]}
+//	POST /api/highlight returns {"html": "...", "theme": "..."}
+//	POST /api/format    returns {"css": "..."}
+//
+// The request body is either the stylesheet with a text/css content type,
+// with the options in the query string, or a Request as JSON.
+//
+// GET /api/live streams the token changes of a live editing session as
+// server-sent events, and POST /api/live/edit sends the edits of the session.
+// The mode query parameter of /api/live is the Mode of the session.
+type Handler struct {
+	MaxBodySize int64
+	mux         *http.ServeMux
+	live        *liveSessions
+}
+
+// Request is the JSON body of a request. Theme is used by /api/highlight and
+// the indentation and brace options by /api/format. Mode is "css", "scss",
+// "less", "declarations" for the value of a style attribute or "html" for a
+// document with embedded CSS; only the first three can be formatted.
+type Request struct {
+	CSS         string `json:"css"`
+	Theme       string `json:"theme,omitempty"`
+	Mode        string `json:"mode,omitempty"`
+	IndentWidth *int   `json:"indentWidth,omitempty"`
+	UseTabs     bool   `json:"useTabs,omitempty"`
+	BraceStyle  string `json:"braceStyle,omitempty"`
+}
+
+// Error is the body of a failed request inside an "error" object. Line and
+// Col are set for syntax errors.
+type Error struct {
+	Status  int    `json:"-"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Line    int    `json:"line,omitempty"`
+	Col     int    `json:"col,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func NewHandler() *Handler {
+	h := &Handler{MaxBodySize: DefaultMaxBodySize, mux: http.NewServeMux(), live: &liveSessions{sessions: make(map[string]*liveSession)}}
+	h.mux.HandleFunc("/api/tokenize", h.post(tokenize))
+	h.mux.HandleFunc("/api/highlight", h.post(highlightHTML))
+	h.mux.HandleFunc("/api/format", h.post(formatCSS))
+	h.mux.HandleFunc("/api/live", h.liveStream)
+	h.mux.HandleFunc("/api/live/edit", h.liveEdit)
+	h.mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, &Error{Status: http.StatusNotFound, Code: "not_found", Message: "unknown endpoint " + r.URL.Path})
+	})
+
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+type endpoint func(req *Request) (interface{}, *Error)
+
+func (h *Handler) post(e endpoint) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeError(w, &Error{Status: http.StatusMethodNotAllowed, Code: "method_not_allowed", Message: r.Method + " is not allowed, use POST"})
+			return
+		}
+
+		req, err := h.readRequest(w, r)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		res, err := e(req)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, res)
+	}
+}
+
+func (h *Handler) maxBodySize() int64 {
+	if h.MaxBodySize <= 0 {
+		return DefaultMaxBodySize
+	}
+
+	return h.MaxBodySize
+}
+
+func (h *Handler) readBody(w http.ResponseWriter, r *http.Request) ([]byte, *Error) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, h.maxBodySize()))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, &Error{Status: http.StatusRequestEntityTooLarge, Code: "too_large", Message: fmt.Sprintf("the request body is larger than %d bytes", h.maxBodySize())}
+		}
+
+		return nil, &Error{Status: http.StatusBadRequest, Code: "bad_request", Message: err.Error()}
+	}
+
+	return body, nil
+}
+
+func (h *Handler) readRequest(w http.ResponseWriter, r *http.Request) (*Request, *Error) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		mediaType = ""
+	}
+
+	body, e := h.readBody(w, r)
+	if e != nil {
+		return nil, e
+	}
+
+	switch mediaType {
+	case "text/css":
+		req := &Request{CSS: string(body), Theme: r.URL.Query().Get("theme"), Mode: r.URL.Query().Get("mode"), BraceStyle: r.URL.Query().Get("brace")}
+		if indent := r.URL.Query().Get("indent"); indent != "" {
+			n, err := strconv.Atoi(indent)
+			if err != nil {
+				return nil, &Error{Status: http.StatusBadRequest, Code: "invalid_option", Message: "invalid indent " + strconv.Quote(indent)}
+			}
+			req.IndentWidth = &n
+		}
+		req.UseTabs = r.URL.Query().Get("tabs") == "true"
+
+		return req, nil
+	case "application/json":
+		req := &Request{}
+		if err := json.Unmarshal(body, req); err != nil {
+			return nil, &Error{Status: http.StatusBadRequest, Code: "invalid_json", Message: err.Error()}
+		}
+
+		return req, nil
+	default:
+		return nil, &Error{Status: http.StatusUnsupportedMediaType, Code: "unsupported_media_type", Message: "the content type must be text/css or application/json"}
+	}
+}
+
+// modeDialect returns the dialect the CSS of a request in mode is lexed in.
+func modeDialect(mode string) (lexer.Dialect, *Error) {
+	switch mode {
+	case "", "css", "declarations", "html":
+		return lexer.CSS, nil
+	case "scss", "less":
+		d, _ := lexer.ParseDialect(mode)
+		return d, nil
+	default:
+		return lexer.CSS, &Error{Status: http.StatusBadRequest, Code: "invalid_option", Message: "unknown mode " + strconv.Quote(mode)}
+	}
+}
+
+func tokenize(req *Request) (interface{}, *Error) {
+	dialect, e := modeDialect(req.Mode)
+	if e != nil {
+		return nil, e
+	}
+
+	tokens := []highlight.JSONToken{}
+	l := lexer.Lexer{Text: []rune(req.CSS), Dialect: dialect}
+	for v := l.NextToken(); v.Type != lexer.EOF; v = l.NextToken() {
+		tokens = append(tokens, highlight.NewJSONToken(v))
+	}
+
+	return map[string]interface{}{"tokens": tokens}, nil
+}
+
+func highlightHTML(req *Request) (interface{}, *Error) {
+	theme := req.Theme
+	if theme == "" {
+		theme = highlight.DefaultTheme
+	}
+
+	if _, ok := highlight.Theme(theme); !ok {
+		return nil, &Error{Status: http.StatusBadRequest, Code: "invalid_option", Message: "unknown theme " + strconv.Quote(theme)}
+	}
+
+	dialect, e := modeDialect(req.Mode)
+	if e != nil {
+		return nil, e
+	}
+
+	opts := highlight.Options{Specificity: true, Properties: true, Colors: true, Dialect: dialect}
+	var html string
+	switch req.Mode {
+	case "declarations":
+		opts.DeclarationList = true
+		html = highlight.String(req.CSS, opts)
+	case "html":
+		html = highlight.DocumentString(req.CSS, opts)
+	default:
+		html = highlight.String(req.CSS, opts)
+	}
+
+	return map[string]string{"html": html, "theme": theme}, nil
+}
+
+func formatCSS(req *Request) (interface{}, *Error) {
+	dialect, e := modeDialect(req.Mode)
+	if e != nil {
+		return nil, e
+	}
+
+	if req.Mode == "declarations" || req.Mode == "html" {
+		return nil, &Error{Status: http.StatusBadRequest, Code: "invalid_option", Message: "only stylesheets can be formatted, not mode " + strconv.Quote(req.Mode)}
+	}
+
+	opts := format.DefaultOptions
+	if req.IndentWidth != nil {
+		if *req.IndentWidth < 0 || *req.IndentWidth > 16 {
+			return nil, &Error{Status: http.StatusBadRequest, Code: "invalid_option", Message: "the indent width must be between 0 and 16"}
+		}
+		opts.IndentWidth = *req.IndentWidth
+	}
+	opts.UseTabs = req.UseTabs
+
+	switch req.BraceStyle {
+	case "", "same-line":
+		opts.BraceStyle = format.SameLine
+	case "next-line":
+		opts.BraceStyle = format.NextLine
+	default:
+		return nil, &Error{Status: http.StatusBadRequest, Code: "invalid_option", Message: "unknown brace style " + strconv.Quote(req.BraceStyle)}
+	}
+
+	var sb strings.Builder
+	err := format.Format(&sb, &lexer.Lexer{Text: []rune(req.CSS), Dialect: dialect}, opts)
+	if err != nil {
+		e := &Error{Status: http.StatusUnprocessableEntity, Code: "syntax_error", Message: err.Error()}
+		var syntaxErr parser.Error
+		if errors.As(err, &syntaxErr) {
+			e.Message, e.Line, e.Col = syntaxErr.Msg, syntaxErr.Line, syntaxErr.Col
+		}
+
+		return nil, e
+	}
+
+	return map[string]string{"css": sb.String()}, nil
+}
+
+func writeError(w http.ResponseWriter, err *Error) {
+	writeJSON(w, err.Status, map[string]*Error{"error": err})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func request(t *testing.T, h http.Handler, method string, path string, contentType string, body string) (int, map[string]interface{}) {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+		t.Fatalf("%s %s content type (expected) application/json != %q (actual)", method, path, ct)
+	}
+
+	res := map[string]interface{}{}
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatalf("%s %s invalid json %q", method, path, w.Body.String())
+	}
+
+	return w.Code, res
+}
+
+func TestTokenize(t *testing.T) {
+	for _, v := range []struct{ contentType, body string }{
+		{"text/css; charset=utf-8", "a {"},
+		{"application/json", `{"css": "a {"}`},
+	} {
+		status, res := request(t, NewHandler(), http.MethodPost, "/api/tokenize", v.contentType, v.body)
+		if status != http.StatusOK {
+			t.Fatalf("%s status (expected) 200 != %d (actual) %v", v.contentType, status, res)
+		}
+
+		tokens := res["tokens"].([]interface{})
+		if len(tokens) != 3 {
+			t.Fatalf("%s len(tokens) (expected) 3 != %d (actual)", v.contentType, len(tokens))
+		}
+
+		brace := tokens[2].(map[string]interface{})
+		if brace["type"] != "LeftBrace" || brace["value"] != "{" || brace["start"] != 2.0 || brace["col"] != 3.0 {
+			t.Fatalf("unexpected token %v", brace)
+		}
+	}
+
+	status, res := request(t, NewHandler(), http.MethodPost, "/api/tokenize?mode=scss", "text/css", "$a")
+	if tokens := res["tokens"].([]interface{}); status != http.StatusOK || len(tokens) != 1 || tokens[0].(map[string]interface{})["type"] != "Variable" {
+		t.Fatalf("unexpected response %d %v", status, res)
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	status, res := request(t, NewHandler(), http.MethodPost, "/api/highlight?theme=dark", "text/css", "<b>")
+	if status != http.StatusOK || res["theme"] != "dark" {
+		t.Fatalf("unexpected response %d %v", status, res)
+	}
+
+	if html := res["html"].(string); strings.Contains(html, "<b>") || !strings.Contains(html, `<span class="tok-delim cat-punctuation">&lt;</span>`) {
+		t.Fatalf("unexpected html %q", html)
+	}
+
+	status, res = request(t, NewHandler(), http.MethodPost, "/api/highlight", "application/json", `{"css": "<p style=\"b:c\">", "mode": "html"}`)
+	if html := res["html"].(string); status != http.StatusOK || !strings.Contains(html, `<span class="tok-ident cat-name tok-property">b</span>`) || !strings.Contains(html, `<span class="html-tag">&lt;p style=&#34;</span>`) {
+		t.Fatalf("unexpected response %d %v", status, res)
+	}
+
+	status, res = request(t, NewHandler(), http.MethodPost, "/api/highlight?mode=declarations", "text/css", "b:c")
+	if html := res["html"].(string); status != http.StatusOK || !strings.HasPrefix(html, `<span class="tok-ident cat-name tok-property">b</span>`) {
+		t.Fatalf("unexpected response %d %v", status, res)
+	}
+
+	status, res = request(t, NewHandler(), http.MethodPost, "/api/highlight?mode=scss", "text/css", "a { $b: c; // d\n}")
+	if html := res["html"].(string); status != http.StatusOK || !strings.Contains(html, `<span class="tok-variable cat-name">$b</span>`) || !strings.Contains(html, `<span class="tok-comment cat-trivia">// d</span>`) {
+		t.Fatalf("unexpected response %d %v", status, res)
+	}
+
+	status, res = request(t, NewHandler(), http.MethodPost, "/api/highlight", "application/json", `{"css": ".a { .b(); }", "mode": "less"}`)
+	if html := res["html"].(string); status != http.StatusOK || !strings.Contains(html, `<span class="tok-mixin cat-name">.b(</span>`) {
+		t.Fatalf("unexpected response %d %v", status, res)
+	}
+
+	status, res = request(t, NewHandler(), http.MethodPost, "/api/highlight", "application/json", `{"css": "a", "theme": "missing"}`)
+	if status != http.StatusBadRequest || res["error"].(map[string]interface{})["code"] != "invalid_option" {
+		t.Fatalf("unexpected response %d %v", status, res)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	status, res := request(t, NewHandler(), http.MethodPost, "/api/format?indent=2", "text/css", "a{b:c}")
+	if status != http.StatusOK || res["css"] != "a {\n  b: c;\n}\n" {
+		t.Fatalf("unexpected response %d %v", status, res)
+	}
+
+	status, res = request(t, NewHandler(), http.MethodPost, "/api/format", "application/json", `{"css": "a{b:c}", "useTabs": true, "braceStyle": "next-line"}`)
+	if status != http.StatusOK || res["css"] != "a\n{\n\tb: c;\n}\n" {
+		t.Fatalf("unexpected response %d %v", status, res)
+	}
+
+	status, res = request(t, NewHandler(), http.MethodPost, "/api/format?mode=scss&indent=2", "text/css", "a{$b:c;// d\n&:hover{e:f}}")
+	if status != http.StatusOK || res["css"] != "a {\n  $b: c;\n  // d\n  &:hover {\n    e: f;\n  }\n}\n" {
+		t.Fatalf("unexpected response %d %v", status, res)
+	}
+
+	status, res = request(t, NewHandler(), http.MethodPost, "/api/format", "text/css", "a {\n  b: c")
+	e := res["error"].(map[string]interface{})
+	if status != http.StatusUnprocessableEntity || e["code"] != "syntax_error" || e["line"] != 2.0 || e["col"] != 7.0 {
+		t.Fatalf("unexpected response %d %v", status, res)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	h := NewHandler()
+	h.MaxBodySize = 4
+	values := []struct {
+		method, path, contentType, body string
+		status                          int
+		code                            string
+	}{
+		{http.MethodGet, "/api/tokenize", "", "", http.StatusMethodNotAllowed, "method_not_allowed"},
+		{http.MethodPost, "/api/missing", "text/css", "a", http.StatusNotFound, "not_found"},
+		{http.MethodPost, "/api/tokenize", "text/plain", "a", http.StatusUnsupportedMediaType, "unsupported_media_type"},
+		{http.MethodPost, "/api/tokenize", "application/json", "{", http.StatusBadRequest, "invalid_json"},
+		{http.MethodPost, "/api/tokenize", "text/css", "a { b: c }", http.StatusRequestEntityTooLarge, "too_large"},
+		{http.MethodPost, "/api/format?indent=x", "text/css", "a{}", http.StatusBadRequest, "invalid_option"},
+		{http.MethodPost, "/api/highlight?mode=x", "text/css", "a{}", http.StatusBadRequest, "invalid_option"},
+		{http.MethodPost, "/api/tokenize?mode=x", "text/css", "a{}", http.StatusBadRequest, "invalid_option"},
+		{http.MethodPost, "/api/format?mode=html", "text/css", "a{}", http.StatusBadRequest, "invalid_option"},
+		{http.MethodGet, "/api/live?mode=x", "", "", http.StatusBadRequest, "invalid_option"},
+	}
+
+	for _, v := range values {
+		status, res := request(t, h, v.method, v.path, v.contentType, v.body)
+		e, ok := res["error"].(map[string]interface{})
+		if status != v.status || !ok || e["code"] != v.code || e["message"] == "" {
+			t.Fatalf("%s %s (expected) %d %s != %d %v (actual)", v.method, v.path, v.status, v.code, status, res)
+		}
+	}
+}
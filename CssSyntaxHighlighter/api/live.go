@@ -0,0 +1,212 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/QuickOrBeDead/GoLangLearning/highlight"
+	"github.com/QuickOrBeDead/GoLangLearning/incremental"
+	"github.com/QuickOrBeDead/GoLangLearning/lexer"
+)
+
+// MaxLiveSessions is the number of live editing sessions the handler keeps
+// at the same time.
+const MaxLiveSessions = 100
+
+// liveEventBuffer is the number of events a session queues for a slow
+// client before they are replaced with a reset event.
+const liveEventBuffer = 64
+
+// LiveEdits is the body of POST /api/live/edit.
+type LiveEdits struct {
+	Session string             `json:"session"`
+	Edits   []incremental.Edit `json:"edits"`
+}
+
+// LiveEvent is the data of the events of GET /api/live. A "session" or
+// "reset" event holds all the tokens, a "change" event replaces Delete
+// tokens from From with Tokens.
+type LiveEvent struct {
+	Session string                `json:"session,omitempty"`
+	Version int                   `json:"version"`
+	From    int                   `json:"from"`
+	Delete  int                   `json:"delete"`
+	Tokens  []highlight.JSONToken `json:"tokens"`
+}
+
+type liveSession struct {
+	mu      sync.Mutex
+	doc     *incremental.Document
+	version int
+	events  chan []byte
+}
+
+type liveSessions struct {
+	mu       sync.Mutex
+	sessions map[string]*liveSession
+}
+
+func jsonTokens(tokens []lexer.Token) []highlight.JSONToken {
+	v := make([]highlight.JSONToken, len(tokens))
+	for i, t := range tokens {
+		v[i] = highlight.NewJSONToken(t)
+	}
+
+	return v
+}
+
+func event(name string, e LiveEvent) []byte {
+	data, _ := json.Marshal(e)
+	return []byte(fmt.Sprintf("event: %s\ndata: %s\n\n", name, data))
+}
+
+// liveStream starts a session in the mode of the query and streams its
+// events until the client goes away. The first event is a "session" event
+// with the id the edits are posted with.
+func (h *Handler) liveStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeError(w, &Error{Status: http.StatusMethodNotAllowed, Code: "method_not_allowed", Message: r.Method + " is not allowed, use GET"})
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, &Error{Status: http.StatusInternalServerError, Code: "streaming_unsupported", Message: "the connection does not support streaming"})
+		return
+	}
+
+	dialect, err := modeDialect(r.URL.Query().Get("mode"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	id, s, err := h.live.start(dialect)
+	if err != nil {
+		w.Header().Set("Retry-After", "10")
+		writeError(w, err)
+		return
+	}
+	defer h.live.stop(id)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Write(event("session", LiveEvent{Session: id, Tokens: []highlight.JSONToken{}}))
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e := <-s.events:
+			if _, err := w.Write(e); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func (h *Handler) liveEdit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, &Error{Status: http.StatusMethodNotAllowed, Code: "method_not_allowed", Message: r.Method + " is not allowed, use POST"})
+		return
+	}
+
+	body, e := h.readBody(w, r)
+	if e != nil {
+		writeError(w, e)
+		return
+	}
+
+	req := &LiveEdits{}
+	if err := json.Unmarshal(body, req); err != nil {
+		writeError(w, &Error{Status: http.StatusBadRequest, Code: "invalid_json", Message: err.Error()})
+		return
+	}
+
+	s := h.live.get(req.Session)
+	if s == nil {
+		writeError(w, &Error{Status: http.StatusNotFound, Code: "unknown_session", Message: "unknown session " + req.Session})
+		return
+	}
+
+	version, e := s.apply(req.Edits, h.maxBodySize())
+	if e != nil {
+		writeError(w, e)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]int{"version": version})
+}
+
+func (l *liveSessions) start(dialect lexer.Dialect) (string, *liveSession, *Error) {
+	b := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return "", nil, &Error{Status: http.StatusInternalServerError, Code: "internal", Message: err.Error()}
+	}
+	id := hex.EncodeToString(b)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.sessions) >= MaxLiveSessions {
+		return "", nil, &Error{Status: http.StatusServiceUnavailable, Code: "too_many_sessions", Message: "too many live sessions, try again later"}
+	}
+
+	s := &liveSession{doc: incremental.NewWithDialect("", dialect), events: make(chan []byte, liveEventBuffer)}
+	l.sessions[id] = s
+	return id, s, nil
+}
+
+func (l *liveSessions) stop(id string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.sessions, id)
+}
+
+func (l *liveSessions) get(id string) *liveSession {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.sessions[id]
+}
+
+// apply applies the edits in order and queues a change event for each. When
+// the client does not keep up, the queued events are dropped for a reset.
+func (s *liveSession) apply(edits []incremental.Edit, maxSize int64) (int, *Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, edit := range edits {
+		if int64(len(s.doc.Text())+len(edit.Insert)) > maxSize {
+			return s.version, &Error{Status: http.StatusRequestEntityTooLarge, Code: "too_large", Message: fmt.Sprintf("the text is larger than %d bytes", maxSize)}
+		}
+
+		c, err := s.doc.Apply(edit)
+		if err != nil {
+			return s.version, &Error{Status: http.StatusBadRequest, Code: "invalid_edit", Message: err.Error()}
+		}
+		s.version++
+
+		select {
+		case s.events <- event("change", LiveEvent{Version: s.version, From: c.From, Delete: c.Delete, Tokens: jsonTokens(c.Tokens)}):
+		default:
+			for len(s.events) > 0 {
+				select {
+				case <-s.events:
+				default:
+				}
+			}
+			s.events <- event("reset", LiveEvent{Version: s.version, Tokens: jsonTokens(s.doc.Tokens())})
+		}
+	}
+
+	return s.version, nil
+}
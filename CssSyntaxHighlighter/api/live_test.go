@@ -0,0 +1,141 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/QuickOrBeDead/GoLangLearning/incremental"
+	"github.com/QuickOrBeDead/GoLangLearning/lexer"
+)
+
+// readEvent reads the next server-sent event of a stream.
+func readEvent(t *testing.T, r *bufio.Reader) (string, LiveEvent) {
+	name, e := "", LiveEvent{}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			return name, e
+		case strings.HasPrefix(line, "event: "):
+			name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func postEdits(t *testing.T, url string, body string) (int, map[string]interface{}) {
+	res, err := http.Post(url+"/api/live/edit", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	v := map[string]interface{}{}
+	json.NewDecoder(res.Body).Decode(&v)
+	return res.StatusCode, v
+}
+
+func TestLive(t *testing.T) {
+	server := httptest.NewServer(NewHandler())
+	defer server.Close()
+
+	res, err := http.Get(server.URL + "/api/live")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	if ct := res.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("content type (expected) text/event-stream != %q (actual)", ct)
+	}
+
+	r := bufio.NewReader(res.Body)
+	name, e := readEvent(t, r)
+	if name != "session" || e.Session == "" || len(e.Tokens) != 0 {
+		t.Fatalf("unexpected first event %s %+v", name, e)
+	}
+
+	status, v := postEdits(t, server.URL, `{"session": "`+e.Session+`", "edits": [{"offset": 0, "delete": 0, "insert": "a { b: c }"}, {"offset": 7, "delete": 1, "insert": "red"}]}`)
+	if status != http.StatusOK || v["version"] != 2.0 {
+		t.Fatalf("unexpected response %d %v", status, v)
+	}
+
+	name, e = readEvent(t, r)
+	if name != "change" || e.Version != 1 || e.From != 0 || e.Delete != 0 || len(e.Tokens) != 10 {
+		t.Fatalf("unexpected first change %s %+v", name, e)
+	}
+
+	name, e = readEvent(t, r)
+	if name != "change" || e.Version != 2 || e.From != 4 || e.Delete != 4 || len(e.Tokens) != 4 || e.Tokens[3].Value != "red" || e.Tokens[3].Start != 7 {
+		t.Fatalf("unexpected second change %s %+v", name, e)
+	}
+
+	status, v = postEdits(t, server.URL, `{"session": "`+e.Session+`x", "edits": []}`)
+	if status != http.StatusNotFound {
+		t.Fatalf("unexpected response for an unknown session %d %v", status, v)
+	}
+}
+
+func TestLiveDialect(t *testing.T) {
+	h := NewHandler()
+	id, s, err := h.live.start(lexer.SCSS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.live.stop(id)
+
+	if _, err := s.apply([]incremental.Edit{{Insert: "$a"}}, DefaultMaxBodySize); err != nil {
+		t.Fatal(err)
+	}
+
+	if e := string(<-s.events); !strings.Contains(e, `"type":"Variable"`) {
+		t.Fatalf("unexpected event %q", e)
+	}
+}
+
+func TestLiveReset(t *testing.T) {
+	h := NewHandler()
+	id, s, err := h.live.start(lexer.CSS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < liveEventBuffer+1; i++ {
+		if _, err := s.apply([]incremental.Edit{{Offset: i, Insert: "a"}}, DefaultMaxBodySize); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(s.events) != 1 {
+		t.Fatalf("len(s.events) (expected) 1 != %d (actual)", len(s.events))
+	}
+
+	if e := string(<-s.events); !strings.HasPrefix(e, "event: reset\n") || !strings.Contains(e, `"version":65`) {
+		t.Fatalf("unexpected event %q", e)
+	}
+
+	if _, err := s.apply([]incremental.Edit{{Offset: 100}}, DefaultMaxBodySize); err == nil || err.Code != "invalid_edit" {
+		t.Fatalf("expected an invalid edit error, got %v", err)
+	}
+
+	if _, err := s.apply([]incremental.Edit{{Insert: "abcdefgh"}}, 70); err == nil || err.Code != "too_large" {
+		t.Fatalf("expected a too large error, got %v", err)
+	}
+
+	h.live.stop(id)
+	if h.live.get(id) != nil {
+		t.Fatal("expected the session to be removed")
+	}
+}
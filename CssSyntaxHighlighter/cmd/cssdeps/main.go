@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/QuickOrBeDead/GoLangLearning/deps"
+	"github.com/QuickOrBeDead/GoLangLearning/sourcemap"
+)
+
+func main() {
+	var (
+		root       string
+		unusedDir  string
+		graph      string
+		bundlePath string
+		mapPath    string
+	)
+	flag.StringVar(&root, "root", ".", "the directory that URLs starting with / start from, files outside of it are not read")
+	flag.StringVar(&unusedDir, "unused", "", "report the stylesheets, images and fonts under this directory that are not used")
+	flag.StringVar(&graph, "graph", "", "print the dependency graph: text | dot")
+	flag.StringVar(&bundlePath, "o", "", "write the entry with its imports inlined to this file")
+	flag.StringVar(&mapPath, "map", "", "write a source map of the bundle to this file and link it from the bundle")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: cssdeps [-root dir] [-unused dir] [-graph text|dot] [-o bundle.css [-map bundle.css.map]] entry.css")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	writeGraph := map[string]func(io.Writer, *deps.Graph){"": nil, "text": writeText, "dot": writeDot}
+	write, ok := writeGraph[graph]
+	if !ok || flag.NArg() != 1 || (mapPath != "" && bundlePath == "") {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	entry, err := relativePath(root, flag.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	g, err := deps.Build(os.DirFS(root), entry)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error reading input:", err)
+		os.Exit(2)
+	}
+
+	if write != nil {
+		write(os.Stdout, g)
+	}
+
+	failed := len(g.Problems) > 0
+	for _, p := range g.Problems {
+		fmt.Println(p.String())
+	}
+
+	if unusedDir != "" {
+		dir, err := relativePath(root, unusedDir)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+
+		unused, err := g.Unused(dir)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error reading directory:", err)
+			os.Exit(2)
+		}
+
+		for _, name := range unused {
+			fmt.Println(name + ": unused file")
+		}
+		failed = failed || len(unused) > 0
+	}
+
+	if bundlePath != "" {
+		output, err := relativePath(root, bundlePath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+
+		var b bytes.Buffer
+		var sourceMap *sourcemap.Generator
+		if mapPath != "" {
+			sourceMap = &sourcemap.Generator{}
+		}
+
+		if err := g.BundleWithSourceMap(&b, output, sourceMap); err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(2)
+		}
+
+		if sourceMap != nil {
+			if err := writeSourceMap(root, bundlePath, mapPath, sourceMap); err != nil {
+				fmt.Fprintln(os.Stderr, "error writing source map:", err)
+				os.Exit(2)
+			}
+
+			url, _ := filepath.Rel(filepath.Dir(bundlePath), mapPath)
+			b.WriteString("\n" + sourcemap.Comment(filepath.ToSlash(url)))
+		}
+
+		if err := os.WriteFile(bundlePath, b.Bytes(), 0o644); err != nil {
+			fmt.Fprintln(os.Stderr, "error writing bundle:", err)
+			os.Exit(2)
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// relativePath returns the slash-separated path of the file name relative to
+// the root directory.
+func relativePath(root, name string) (string, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+
+	absName, err := filepath.Abs(name)
+	if err != nil {
+		return "", err
+	}
+
+	rel, err := filepath.Rel(absRoot, absName)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside of the root directory %s", name, root)
+	}
+
+	return filepath.ToSlash(rel), nil
+}
+
+// writeSourceMap writes the source map of the bundle. The sources are the
+// paths from the root directory, which is the source root.
+func writeSourceMap(root, bundlePath, mapPath string, sourceMap *sourcemap.Generator) error {
+	m := sourceMap.Map(filepath.Base(bundlePath))
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return err
+	}
+
+	absMap, err := filepath.Abs(mapPath)
+	if err != nil {
+		return err
+	}
+
+	rel, err := filepath.Rel(filepath.Dir(absMap), absRoot)
+	if err != nil {
+		return err
+	}
+
+	if rel != "." {
+		m.SourceRoot = filepath.ToSlash(rel) + "/"
+	}
+
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(mapPath, b, 0o644)
+}
+
+// writeText writes a line for each reference: the stylesheet, the kind of the
+// reference and the file or, for external URLs, the URL.
+func writeText(w io.Writer, g *deps.Graph) {
+	for _, s := range g.Stylesheets {
+		for _, r := range s.References {
+			target := r.Path
+			if target == "" {
+				target = r.URL
+			}
+
+			fmt.Fprintf(w, "%s: %s %s\n", s.Path, r.Kind, target)
+		}
+	}
+}
+
+// writeDot writes the graph in the Graphviz dot language, with imports as
+// solid and url() references as dashed edges.
+func writeDot(w io.Writer, g *deps.Graph) {
+	fmt.Fprintln(w, "digraph {")
+	for _, s := range g.Stylesheets {
+		for _, r := range s.References {
+			if r.Path == "" {
+				continue
+			}
+
+			style := ""
+			if r.Kind == deps.URL {
+				style = " [style=dashed]"
+			}
+
+			fmt.Fprintf(w, "\t%q -> %q%s;\n", s.Path, r.Path, style)
+		}
+	}
+	fmt.Fprintln(w, "}")
+}
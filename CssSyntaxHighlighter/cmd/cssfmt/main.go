@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/QuickOrBeDead/GoLangLearning/format"
+	"github.com/QuickOrBeDead/GoLangLearning/lexer"
+	"github.com/QuickOrBeDead/GoLangLearning/sourcemap"
+)
+
+func main() {
+	var (
+		write   bool
+		check   bool
+		indent  int
+		tabs    bool
+		brace   string
+		mapPath string
+		dialect string
+	)
+	flag.BoolVar(&write, "w", false, "write the result to the files instead of stdout")
+	flag.BoolVar(&check, "check", false, "list the files that are not formatted and exit with status 1 if there are any")
+	flag.IntVar(&indent, "indent", format.DefaultOptions.IndentWidth, "the number of spaces of one indentation level")
+	flag.BoolVar(&tabs, "tabs", false, "indent with tabs")
+	flag.StringVar(&brace, "brace", "same-line", "the brace style: same-line | next-line")
+	flag.StringVar(&mapPath, "map", "", "write a source map to this file and link it from the output, which is expected next to it")
+	flag.StringVar(&dialect, "dialect", "", "the language of the input: css | scss | less, by default from the file extensions")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: cssfmt [-w | -check] [-indent n] [-tabs] [-brace style] [-dialect name] [-map file] [file ...]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	opts := format.Options{IndentWidth: indent, UseTabs: tabs}
+	switch brace {
+	case "same-line":
+		opts.BraceStyle = format.SameLine
+	case "next-line":
+		opts.BraceStyle = format.NextLine
+	default:
+		fmt.Fprintln(os.Stderr, "invalid brace style:", brace)
+		os.Exit(2)
+	}
+
+	// dialectOf returns the dialect of the -dialect flag, or the one of the
+	// extension of path.
+	dialectOf := lexer.DialectOf
+	if dialect != "" {
+		d, err := lexer.ParseDialect(dialect)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		dialectOf = func(string) lexer.Dialect { return d }
+	}
+
+	if mapPath != "" {
+		if write || check || flag.NArg() != 1 {
+			fmt.Fprintln(os.Stderr, "-map needs one file and writes to stdout")
+			os.Exit(2)
+		}
+
+		err := formatWithSourceMap(flag.Arg(0), mapPath, opts, dialectOf(flag.Arg(0)))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+
+		exit(err)
+		return
+	}
+
+	if flag.NArg() == 0 {
+		if write {
+			fmt.Fprintln(os.Stderr, "cannot use -w with standard input")
+			os.Exit(2)
+		}
+
+		src, err := io.ReadAll(os.Stdin)
+		if err == nil {
+			err = processFile("<stdin>", src, opts, dialectOf(""), false, check)
+		}
+
+		if err != nil && err != errUnformatted {
+			fmt.Fprintln(os.Stderr, err)
+		}
+
+		exit(err)
+		return
+	}
+
+	var failed error
+	for _, path := range flag.Args() {
+		src, err := os.ReadFile(path)
+		if err == nil {
+			err = processFile(path, src, opts, dialectOf(path), write, check)
+		}
+
+		if err != nil {
+			if err != errUnformatted {
+				fmt.Fprintln(os.Stderr, path+":", err)
+			}
+
+			if failed == nil || failed == errUnformatted {
+				failed = err
+			}
+		}
+	}
+
+	exit(failed)
+}
+
+var errUnformatted = fmt.Errorf("not formatted")
+
+// processFile formats src, which is in dialect, and writes it to stdout, or
+// back to path when write is set. In check mode it only prints path when src
+// is not formatted.
+func processFile(path string, src []byte, opts format.Options, dialect lexer.Dialect, write bool, check bool) error {
+	lex := lexer.NewLexer(bytes.NewReader(src))
+	lex.Dialect = dialect
+
+	var out bytes.Buffer
+	if err := format.Format(&out, lex, opts); err != nil {
+		return err
+	}
+
+	switch {
+	case check:
+		if !bytes.Equal(src, out.Bytes()) {
+			fmt.Println(path)
+			return errUnformatted
+		}
+
+		return nil
+	case write:
+		if bytes.Equal(src, out.Bytes()) {
+			return nil
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+
+		return os.WriteFile(path, out.Bytes(), info.Mode().Perm())
+	default:
+		_, err := os.Stdout.Write(out.Bytes())
+		return err
+	}
+}
+
+// formatWithSourceMap formats the file path, which is in dialect, to stdout
+// and writes the source map of the output to mapPath.
+func formatWithSourceMap(path string, mapPath string, opts format.Options, dialect lexer.Dialect) error {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	// The source is named by its path from the source map.
+	source := filepath.ToSlash(path)
+	absMap, err1 := filepath.Abs(mapPath)
+	absPath, err2 := filepath.Abs(path)
+	if err1 == nil && err2 == nil {
+		if rel, err := filepath.Rel(filepath.Dir(absMap), absPath); err == nil {
+			source = filepath.ToSlash(rel)
+		}
+	}
+
+	lex := lexer.NewLexer(bytes.NewReader(src))
+	lex.Dialect = dialect
+
+	var out bytes.Buffer
+	var sourceMap sourcemap.Generator
+	if err := format.FormatWithSourceMap(&out, lex, opts, &sourceMap, source); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+
+	b, err := json.Marshal(sourceMap.Map(""))
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(mapPath, b, 0644); err != nil {
+		return err
+	}
+
+	out.WriteString(sourcemap.Comment(filepath.Base(mapPath)))
+	_, err = os.Stdout.Write(out.Bytes())
+	return err
+}
+
+// exit exits with status 1 when files are not formatted and 2 on errors.
+func exit(err error) {
+	switch {
+	case err == nil:
+		return
+	case err == errUnformatted:
+		os.Exit(1)
+	default:
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/QuickOrBeDead/GoLangLearning/highlight"
+	"github.com/QuickOrBeDead/GoLangLearning/lexer"
+)
+
+// options are the flags of the command.
+type options struct {
+	format    string
+	theme     string
+	palette   highlight.Palette
+	trueColor bool
+	// dialect is the one of the --dialect flag, or nil to take the dialect
+	// of each file from its extension.
+	dialect *lexer.Dialect
+}
+
+func main() {
+	var (
+		o           options
+		dialectName string
+	)
+	flag.StringVar(&o.format, "format", "ansi", "the output format: html | ansi | svg | json | tokens")
+	flag.StringVar(&o.theme, "theme", "dark", "the color theme: "+strings.Join(highlight.Themes(), " | "))
+	flag.StringVar(&dialectName, "dialect", "", "the language of the input: css | scss | less, by default from the file extensions")
+	flag.BoolVar(&o.trueColor, "truecolor", os.Getenv("COLORTERM") == "truecolor" || os.Getenv("COLORTERM") == "24bit", "use 24-bit colors in ansi output")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: csshl [--format=html|ansi|svg|json|tokens] [--theme=name] [--dialect=css|scss|less] [--truecolor] [file ...]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	palette, ok := highlight.Palettes[o.theme]
+	if !ok {
+		fmt.Fprintln(os.Stderr, "invalid theme:", o.theme)
+		os.Exit(2)
+	}
+	o.palette = palette
+
+	switch o.format {
+	case "html", "ansi", "svg", "json", "tokens":
+	default:
+		fmt.Fprintln(os.Stderr, "invalid format:", o.format)
+		os.Exit(2)
+	}
+
+	if dialectName != "" {
+		d, err := lexer.ParseDialect(dialectName)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		o.dialect = &d
+	}
+
+	if flag.NArg() == 0 {
+		lex := lexer.NewLexer(os.Stdin)
+		if o.dialect != nil {
+			lex.Dialect = *o.dialect
+		}
+
+		if err := o.renderer("").Render(os.Stdout, lex); err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// An SVG image shows one input, the other formats label each file.
+	if o.format == "svg" && flag.NArg() > 1 {
+		fmt.Fprintln(os.Stderr, "--format=svg takes one file")
+		os.Exit(2)
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	if o.format == "html" {
+		if err := highlight.HTMLHeader(out, o.theme); err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+	}
+
+	// The files are lexed one by one, so that the positions of each start
+	// at 1:1 and nothing of a file, like an unclosed comment, runs into the
+	// next one.
+	for i, path := range flag.Args() {
+		if err := o.renderFile(out, path, i); err != nil {
+			out.Flush()
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+	}
+
+	if o.format == "html" {
+		highlight.HTMLFooter(out)
+	}
+
+	if err := out.Flush(); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+// renderer returns the renderer of the format for the file named file, or
+// for stdin when file is empty.
+func (o options) renderer(file string) highlight.Renderer {
+	switch o.format {
+	case "html":
+		return highlight.HTMLRenderer{Theme: o.theme, Standalone: file == ""}
+	case "ansi":
+		return highlight.ANSIRenderer{Palette: o.palette, TrueColor: o.trueColor}
+	case "svg":
+		return highlight.SVGRenderer{Palette: o.palette}
+	case "json":
+		return highlight.JSONRenderer{Indent: true, File: file}
+	default:
+		return highlight.TokensRenderer{File: file}
+	}
+}
+
+// renderFile writes the file at path, the i-th of the input, labelled with
+// its path.
+func (o options) renderFile(w io.Writer, path string, i int) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	lex := lexer.NewLexer(f)
+	lex.Dialect = lexer.DialectOf(path)
+	if o.dialect != nil {
+		lex.Dialect = *o.dialect
+	}
+
+	switch o.format {
+	case "html":
+		fmt.Fprintf(w, "<h2 class=\"file\">%s</h2>\n<pre class=\"highlight\">", html.EscapeString(path))
+		defer io.WriteString(w, "</pre>\n")
+	case "ansi":
+		if i > 0 {
+			io.WriteString(w, "\n")
+		}
+		fmt.Fprintf(w, "==> %s <==\n", path)
+	}
+
+	return o.renderer(path).Render(w, lex)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/QuickOrBeDead/GoLangLearning/lexer"
+	"github.com/QuickOrBeDead/GoLangLearning/lint"
+)
+
+func main() {
+	var (
+		configPath   string
+		outputFormat string
+		failOn       string
+	)
+	flag.StringVar(&configPath, "config", "", "the JSON config file of the rules, .csslint.json when it exists")
+	flag.StringVar(&outputFormat, "format", "text", "the output format: text | json | checkstyle")
+	flag.StringVar(&failOn, "fail-on", "warning", "the lowest severity that makes csslint exit with status 1: info | warning | error")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: csslint [-config file] [-format text|json|checkstyle] [-fail-on severity] file ...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	failSeverity, err := lint.ParseSeverity(failOn)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	write := map[string]func(io.Writer, []lint.Problem) error{
+		"text":       lint.WriteText,
+		"json":       lint.WriteJSON,
+		"checkstyle": lint.WriteCheckstyle,
+	}[outputFormat]
+	if write == nil {
+		fmt.Fprintln(os.Stderr, "invalid format:", outputFormat)
+		os.Exit(2)
+	}
+
+	if configPath == "" {
+		if _, err := os.Stat(".csslint.json"); err == nil {
+			configPath = ".csslint.json"
+		}
+	}
+
+	var config *lint.Config
+	if configPath != "" {
+		if config, err = lint.LoadConfig(configPath); err != nil {
+			fmt.Fprintln(os.Stderr, "error reading config:", err)
+			os.Exit(2)
+		}
+	}
+
+	linter, err := lint.New(config, lint.DefaultRules())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "invalid config:", err)
+		os.Exit(2)
+	}
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	problems := []lint.Problem{}
+	for _, path := range flag.Args() {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error reading input:", err)
+			os.Exit(2)
+		}
+
+		p, err := linter.Lint(path, lexer.NewLexer(bytes.NewReader(src)))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, path+":", err)
+			os.Exit(2)
+		}
+		problems = append(problems, p...)
+	}
+
+	if err := write(os.Stdout, problems); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	for _, p := range problems {
+		if p.Severity >= failSeverity {
+			os.Exit(1)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/QuickOrBeDead/GoLangLearning/lexer"
+	"github.com/QuickOrBeDead/GoLangLearning/minify"
+	"github.com/QuickOrBeDead/GoLangLearning/sourcemap"
+)
+
+func main() {
+	var (
+		output  string
+		mapPath string
+		verify  bool
+	)
+	flag.StringVar(&output, "o", "", "the output file, stdout when empty")
+	flag.StringVar(&mapPath, "map", "", "write a source map to this file and link it from the output")
+	flag.BoolVar(&verify, "verify", false, "check that the minified stylesheet has the same tokens and significant whitespace as the input")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: cssmin [-o file] [-map file] [-verify] [file ...]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	var minified bytes.Buffer
+	var sourceMap *sourcemap.Generator
+	if mapPath != "" {
+		sourceMap = &sourcemap.Generator{}
+	}
+
+	// The files are minified one by one, so that nothing of a file, like an
+	// unclosed comment, runs into the next one.
+	if len(flag.Args()) == 0 {
+		if err := minifyInput(&minified, sourceMap, "", "<stdin>", verify); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitCode(err))
+		}
+	}
+
+	for i, f := range flag.Args() {
+		if i > 0 {
+			minified.WriteString("\n")
+			if sourceMap != nil {
+				sourceMap.WriteString("\n")
+			}
+		}
+
+		if err := minifyInput(&minified, sourceMap, f, relativePath(filepath.Dir(mapPath), f), verify); err != nil {
+			fmt.Fprintln(os.Stderr, f+":", err)
+			os.Exit(exitCode(err))
+		}
+	}
+
+	if sourceMap != nil {
+		file := ""
+		if output != "" {
+			file = filepath.Base(output)
+		}
+
+		m := sourceMap.Map(file)
+		if err := writeSourceMap(mapPath, m); err != nil {
+			fmt.Fprintln(os.Stderr, "error writing source map:", err)
+			os.Exit(1)
+		}
+
+		url := filepath.Base(mapPath)
+		if output != "" {
+			url = relativePath(filepath.Dir(output), mapPath)
+		}
+		minified.WriteString("\n" + sourcemap.Comment(url))
+	}
+
+	if output == "" {
+		os.Stdout.Write(minified.Bytes())
+		return
+	}
+
+	if err := os.WriteFile(output, minified.Bytes(), 0644); err != nil {
+		fmt.Fprintln(os.Stderr, "error writing output:", err)
+		os.Exit(1)
+	}
+}
+
+// verifyError is the error of a failed -verify check.
+type verifyError struct {
+	err error
+}
+
+func (e verifyError) Error() string {
+	return "verify failed: " + e.err.Error()
+}
+
+func exitCode(err error) int {
+	if _, ok := err.(verifyError); ok {
+		return 2
+	}
+
+	return 1
+}
+
+// minifyInput minifies the file path, or stdin when path is empty, to out
+// and maps it to source in the source map. With verify the output is checked
+// against the input.
+func minifyInput(out *bytes.Buffer, sourceMap *sourcemap.Generator, path string, source string, verify bool) error {
+	var in io.Reader = os.Stdin
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("error reading input: %w", err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	// The input is kept for the check as it is read, since stdin cannot be
+	// read again.
+	var original bytes.Buffer
+	if verify {
+		in = io.TeeReader(in, &original)
+	}
+
+	var minified bytes.Buffer
+	if err := minify.MinifyWithSourceMap(&minified, lexer.NewLexer(in), sourceMap, source); err != nil {
+		return fmt.Errorf("error minifying: %w", err)
+	}
+
+	if verify {
+		if err := minify.Verify(lexer.NewLexer(&original), lexer.NewLexer(bytes.NewReader(minified.Bytes()))); err != nil {
+			return verifyError{err}
+		}
+	}
+
+	out.Write(minified.Bytes())
+	return nil
+}
+
+// relativePath returns the slash-separated path of the file target from the
+// directory dir, or target when there is none.
+func relativePath(dir string, target string) string {
+	absDir, err1 := filepath.Abs(dir)
+	absTarget, err2 := filepath.Abs(target)
+	if err1 != nil || err2 != nil {
+		return filepath.ToSlash(target)
+	}
+
+	rel, err := filepath.Rel(absDir, absTarget)
+	if err != nil {
+		return filepath.ToSlash(target)
+	}
+
+	return filepath.ToSlash(rel)
+}
+
+func writeSourceMap(path string, m *sourcemap.Map) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, b, 0644)
+}
@@ -0,0 +1,158 @@
+package color
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/QuickOrBeDead/GoLangLearning/lexer"
+	"github.com/QuickOrBeDead/GoLangLearning/parser"
+)
+
+// Color is a color in the sRGB space with components from 0 to 1. The
+// components of a color out of the sRGB gamut, like many lab() and oklch()
+// colors, can be outside of that range until it is clamped.
+type Color struct {
+	R, G, B, A float64
+}
+
+// Parse parses a CSS color value, like "#f00", "rebeccapurple" or
+// "oklch(70% 0.1 120 / 50%)".
+func Parse(css string) (Color, error) {
+	p := parser.New(&lexer.Lexer{Text: []rune(css)})
+	v, err := p.ParseComponentValue()
+	if err != nil {
+		return Color{}, err
+	}
+
+	c, ok := FromValue(v)
+	if !ok {
+		return Color{}, fmt.Errorf("invalid color %q", strings.TrimSpace(css))
+	}
+
+	return c, nil
+}
+
+// FromValue returns the color of a hash token, a named color or one of the
+// rgb(), rgba(), hsl(), hsla(), hwb(), lab(), lch(), oklab() and oklch()
+// functions. It reports false for other values and for the functions with
+// arguments it cannot compute, like calc() or var().
+func FromValue(v parser.ComponentValue) (Color, bool) {
+	switch v := v.(type) {
+	case *parser.Token:
+		switch v.Type {
+		case lexer.HashToken:
+			return fromHex(string(v.Value))
+		case lexer.IdentToken:
+			return Named(string(v.Value))
+		}
+	case *parser.Function:
+		return fromFunction(v)
+	}
+
+	return Color{}, false
+}
+
+// Named returns the color of a named color or transparent. The case of the
+// name is ignored.
+func Named(name string) (Color, bool) {
+	name = strings.ToLower(name)
+	if name == "transparent" {
+		return Color{}, true
+	}
+
+	rgb, ok := namedColors[name]
+	if !ok {
+		return Color{}, false
+	}
+
+	return Color{R: float64(rgb>>16) / 255, G: float64(rgb>>8&0xff) / 255, B: float64(rgb&0xff) / 255, A: 1}, true
+}
+
+// https://www.w3.org/TR/css-color-4/#hex-notation
+func fromHex(hex string) (Color, bool) {
+	digits := make([]float64, len(hex))
+	for i, r := range hex {
+		v, err := strconv.ParseUint(string(r), 16, 8)
+		if err != nil {
+			return Color{}, false
+		}
+		digits[i] = float64(v)
+	}
+
+	switch len(digits) {
+	case 3, 4:
+		c := Color{R: digits[0] * 17 / 255, G: digits[1] * 17 / 255, B: digits[2] * 17 / 255, A: 1}
+		if len(digits) == 4 {
+			c.A = digits[3] * 17 / 255
+		}
+
+		return c, true
+	case 6, 8:
+		c := Color{R: (digits[0]*16 + digits[1]) / 255, G: (digits[2]*16 + digits[3]) / 255, B: (digits[4]*16 + digits[5]) / 255, A: 1}
+		if len(digits) == 8 {
+			c.A = (digits[6]*16 + digits[7]) / 255
+		}
+
+		return c, true
+	default:
+		return Color{}, false
+	}
+}
+
+// Clamp returns the color with its components limited to the 0 to 1 range.
+func (c Color) Clamp() Color {
+	return Color{R: clamp(c.R), G: clamp(c.G), B: clamp(c.B), A: clamp(c.A)}
+}
+
+// Hex returns the color as "#rrggbb", or "#rrggbbaa" when it is not opaque.
+func (c Color) Hex() string {
+	c = c.Clamp()
+	s := fmt.Sprintf("#%02x%02x%02x", byte255(c.R), byte255(c.G), byte255(c.B))
+	if a := byte255(c.A); a != 255 {
+		s += fmt.Sprintf("%02x", a)
+	}
+
+	return s
+}
+
+func (c Color) String() string {
+	return c.Hex()
+}
+
+// RGBString returns the color as "rgb(255 0 0)", or "rgb(255 0 0 / 0.5)"
+// when it is not opaque.
+func (c Color) RGBString() string {
+	c = c.Clamp()
+	return fmt.Sprintf("rgb(%d %d %d%s)", byte255(c.R), byte255(c.G), byte255(c.B), alphaString(c.A))
+}
+
+// HSLString returns the color as "hsl(0 100% 50%)", or "hsl(0 100% 50% /
+// 0.5)" when it is not opaque.
+func (c Color) HSLString() string {
+	hsl := c.Clamp().HSL()
+	return fmt.Sprintf("hsl(%s %s%% %s%%%s)", formatNumber(hsl.H), formatNumber(hsl.S*100), formatNumber(hsl.L*100), alphaString(hsl.Alpha))
+}
+
+func byte255(v float64) uint8 {
+	return uint8(math.Round(v * 255))
+}
+
+func alphaString(a float64) string {
+	if a >= 1 {
+		return ""
+	}
+
+	return " / " + strconv.FormatFloat(math.Round(a*100)/100, 'f', -1, 64)
+}
+
+func formatNumber(v float64) string {
+	v = math.Round(v*10) / 10
+	if v == 0 {
+		// Avoid printing -0.
+		v = 0
+	}
+
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
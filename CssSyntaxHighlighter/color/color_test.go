@@ -0,0 +1,157 @@
+package color
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	values := []struct {
+		css string
+		hex string
+	}{
+		{"#f00", "#ff0000"},
+		{"#F008", "#ff000088"},
+		{"#ff000080", "#ff000080"},
+		{" RebeccaPurple ", "#663399"},
+		{"transparent", "#00000000"},
+		{"rgb(255 0 0)", "#ff0000"},
+		{"rgba(255, 0, 0, .5)", "#ff000080"},
+		{"rgb(100%, 50%, 0%)", "#ff8000"},
+		{"rgb(300 -5 none / 50%)", "#ff000080"},
+		{"rgb(255 0 0 / 2)", "#ff0000"},
+		{"hsl(120deg 100% 50%)", "#00ff00"},
+		{"HSLA(0.5turn, 100%, 50%, 0.25)", "#00ffff40"},
+		{"hsl(270 50 40)", "#663399"},
+		{"hwb(0 0% 0%)", "#ff0000"},
+		{"hwb(90 60% 60%)", "#808080"},
+		{"lab(54.29 80.82 69.88)", "#ff0000"},
+		{"lab(100% 0 0)", "#ffffff"},
+		{"lch(54.29 106.84 40.85)", "#ff0000"},
+		{"oklab(62.8% 0.2249 0.1258)", "#ff0000"},
+		{"oklch(0.628 0.2577 29.23)", "#ff0000"},
+		{"oklch(0% 0 none)", "#000000"},
+		{"oklch(86.64% 0.2948 142.5)", "#00ff00"},
+		{"oklch(100% 0.4 150)", "#00ff30"},
+	}
+
+	for _, v := range values {
+		c, err := Parse(v.css)
+		if err != nil {
+			t.Fatalf("%s: %v", v.css, err)
+		}
+
+		if c.Hex() != v.hex {
+			t.Fatalf("%s (expected) %s != %s (actual)", v.css, v.hex, c.Hex())
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	values := []string{
+		"",
+		"#12345",
+		"#ggg",
+		"notacolor",
+		"red blue",
+		"rgb(1 2)",
+		"rgb(1 2 3 4)",
+		"rgb(1, 2 3)",
+		"rgb(1, 2, 3,)",
+		"rgb(255, 0%, 0)",
+		"rgb(1 2 3 / 4 / 5)",
+		"rgb(1 / 2 3)",
+		"rgb(calc(1) 2 3)",
+		"rgb(none, 1, 1)",
+		"hsl(0, 100, 50%)",
+		"hsl(0 100% 50px)",
+		"hwb(0, 1%, 1%)",
+		"lab(50 1deg 0)",
+		"color(srgb 1 0 0)",
+	}
+
+	for _, css := range values {
+		if c, err := Parse(css); err == nil {
+			t.Fatalf("%s (expected) error != %s (actual)", css, c)
+		}
+	}
+}
+
+func TestStrings(t *testing.T) {
+	values := []struct {
+		css, rgb, hsl string
+	}{
+		{"red", "rgb(255 0 0)", "hsl(0 100% 50%)"},
+		{"rebeccapurple", "rgb(102 51 153)", "hsl(270 50% 40%)"},
+		{"#0000", "rgb(0 0 0 / 0)", "hsl(0 0% 0% / 0)"},
+		{"rgb(0 128 255 / 25%)", "rgb(0 128 255 / 0.25)", "hsl(209.9 100% 50% / 0.25)"},
+		{"lab(100 0 0)", "rgb(255 255 255)", "hsl(0 0% 100%)"},
+	}
+
+	for _, v := range values {
+		c, err := Parse(v.css)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if c.RGBString() != v.rgb || c.HSLString() != v.hsl {
+			t.Fatalf("%s (expected) %s %s != %s %s (actual)", v.css, v.rgb, v.hsl, c.RGBString(), c.HSLString())
+		}
+	}
+}
+
+func TestConversions(t *testing.T) {
+	for _, hex := range []string{"#000000", "#ffffff", "#ff0000", "#00ff00", "#0000ff", "#663399", "#7fffd4", "#123456", "#fedcba80"} {
+		c, err := Parse(hex)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		conversions := map[string]Color{
+			"hsl":   c.HSL().RGB(),
+			"hwb":   c.HWB().RGB(),
+			"lab":   c.Lab().RGB(),
+			"lch":   c.LCH().RGB(),
+			"oklab": c.OKLab().RGB(),
+			"oklch": c.OKLCH().RGB(),
+		}
+
+		for space, converted := range conversions {
+			if converted.Hex() != hex {
+				t.Fatalf("%s through %s (expected) %s != %s (actual)", hex, space, hex, converted.Hex())
+			}
+		}
+	}
+
+	lab := Color{R: 1, A: 1}.Lab()
+	if math.Abs(lab.L-54.29) > 0.01 || math.Abs(lab.A-80.81) > 0.01 || math.Abs(lab.B-69.89) > 0.01 {
+		t.Fatalf("lab of red (expected) 54.29 80.81 69.89 != %.2f %.2f %.2f (actual)", lab.L, lab.A, lab.B)
+	}
+
+	oklch := Color{R: 1, A: 1}.OKLCH()
+	if math.Abs(oklch.L-0.628) > 0.001 || math.Abs(oklch.C-0.2577) > 0.001 || math.Abs(oklch.H-29.23) > 0.01 {
+		t.Fatalf("oklch of red (expected) 0.628 0.2577 29.23 != %.3f %.4f %.2f (actual)", oklch.L, oklch.C, oklch.H)
+	}
+}
+
+func TestContrast(t *testing.T) {
+	values := []struct {
+		a, b     string
+		contrast float64
+	}{
+		{"black", "white", 21},
+		{"white", "black", 21},
+		{"red", "red", 1},
+		{"#777", "#fff", 4.48},
+		{"#767676", "white", 4.54},
+		{"#1e1e1e", "#d4d4d4", 11.25},
+	}
+
+	for _, v := range values {
+		a, _ := Parse(v.a)
+		b, _ := Parse(v.b)
+		if c := Contrast(a, b); math.Abs(c-v.contrast) > 0.005 {
+			t.Fatalf("contrast of %s and %s (expected) %.2f != %.2f (actual)", v.a, v.b, v.contrast, c)
+		}
+	}
+}
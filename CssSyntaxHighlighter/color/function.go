@@ -0,0 +1,211 @@
+package color
+
+import (
+	"math"
+	"strings"
+
+	"github.com/QuickOrBeDead/GoLangLearning/lexer"
+	"github.com/QuickOrBeDead/GoLangLearning/parser"
+)
+
+// component is the value of a color function argument. none is set for the
+// none keyword, which counts as zero.
+type component struct {
+	token lexer.Token
+	none  bool
+}
+
+func (c component) is(t lexer.TokenType) bool {
+	return !c.none && c.token.Type == t
+}
+
+// number returns the value of a number, or of a percentage scaled so that
+// 100% is percent. It reports false for other tokens.
+func (c component) number(percent float64) (float64, bool) {
+	switch {
+	case c.none:
+		return 0, true
+	case c.token.Type == lexer.NumberToken:
+		return c.token.Number, true
+	case c.token.Type == lexer.PercentageToken:
+		return c.token.Number / 100 * percent, true
+	default:
+		return 0, false
+	}
+}
+
+// hue returns a number or an angle in degrees.
+// https://www.w3.org/TR/css-color-4/#hue-syntax
+func (c component) hue() (float64, bool) {
+	switch {
+	case c.none:
+		return 0, true
+	case c.token.Type == lexer.NumberToken:
+		return c.token.Number, true
+	case c.token.Type != lexer.DimensionToken:
+		return 0, false
+	}
+
+	switch strings.ToLower(string(c.token.Unit)) {
+	case "deg":
+		return c.token.Number, true
+	case "grad":
+		return c.token.Number * 360 / 400, true
+	case "rad":
+		return c.token.Number * 180 / math.Pi, true
+	case "turn":
+		return c.token.Number * 360, true
+	default:
+		return 0, false
+	}
+}
+
+// alpha returns the alpha of a color, 1 when it is omitted.
+func (c *component) alpha() (float64, bool) {
+	if c == nil {
+		return 1, true
+	}
+
+	a, ok := c.number(1)
+	return math.Max(0, math.Min(1, a)), ok
+}
+
+// arguments splits the arguments of a color function into its three
+// components and the optional alpha. legacy is set when they are separated
+// by commas, which is only allowed in rgb() and hsl() and does not allow none.
+// https://www.w3.org/TR/css-color-4/#color-syntax-legacy
+func arguments(f *parser.Function) (components []component, alpha *component, legacy bool, ok bool) {
+	values := []parser.ComponentValue{}
+	for _, v := range f.Values {
+		if t, ok := v.(*parser.Token); !ok || !t.Type.IsTrivia() {
+			values = append(values, v)
+		}
+	}
+
+	args := []component{}
+	slash := -1
+	for i, v := range values {
+		t, ok := v.(*parser.Token)
+		if !ok {
+			return nil, nil, false, false
+		}
+
+		switch {
+		case t.Type == lexer.CommaToken:
+			legacy = true
+			if i%2 == 0 || i == len(values)-1 {
+				return nil, nil, false, false
+			}
+		case t.Type == lexer.DelimToken && string(t.Val) == "/":
+			if slash >= 0 {
+				return nil, nil, false, false
+			}
+			slash = len(args)
+		case t.Type == lexer.IdentToken && strings.EqualFold(string(t.Value), "none"):
+			args = append(args, component{none: true})
+		default:
+			args = append(args, component{token: t.Token})
+		}
+	}
+
+	if legacy {
+		// Every other value must be a comma.
+		if slash >= 0 || len(values) != 2*len(args)-1 {
+			return nil, nil, false, false
+		}
+
+		for _, a := range args {
+			if a.none {
+				return nil, nil, false, false
+			}
+		}
+	} else if slash >= 0 && slash != len(args)-1 {
+		return nil, nil, false, false
+	}
+
+	switch {
+	case len(args) == 3 && slash < 0:
+		return args, nil, legacy, true
+	case len(args) == 4 && (legacy || slash == 3):
+		return args[:3], &args[3], legacy, true
+	default:
+		return nil, nil, false, false
+	}
+}
+
+func fromFunction(f *parser.Function) (Color, bool) {
+	name := strings.ToLower(f.Name())
+	args, alpha, legacy, ok := arguments(f)
+	if !ok {
+		return Color{}, false
+	}
+
+	a, ok := alpha.alpha()
+	if !ok {
+		return Color{}, false
+	}
+
+	switch name {
+	case "rgb", "rgba":
+		return rgb(args, a, legacy)
+	case "hsl", "hsla":
+		h, ok1 := args[0].hue()
+		s, ok2 := args[1].number(100)
+		l, ok3 := args[2].number(100)
+		if legacy && (!args[1].is(lexer.PercentageToken) || !args[2].is(lexer.PercentageToken)) {
+			return Color{}, false
+		}
+
+		return HSL{H: h, S: clamp(s / 100), L: clamp(l / 100), Alpha: a}.RGB(), ok1 && ok2 && ok3
+	case "hwb":
+		h, ok1 := args[0].hue()
+		w, ok2 := args[1].number(100)
+		b, ok3 := args[2].number(100)
+		return HWB{H: h, W: clamp(w / 100), B: clamp(b / 100), Alpha: a}.RGB(), !legacy && ok1 && ok2 && ok3
+	case "lab":
+		l, ok1 := args[0].number(100)
+		x, ok2 := args[1].number(125)
+		y, ok3 := args[2].number(125)
+		return Lab{L: math.Max(0, l), A: x, B: y, Alpha: a}.RGB(), !legacy && ok1 && ok2 && ok3
+	case "lch":
+		l, ok1 := args[0].number(100)
+		c, ok2 := args[1].number(150)
+		h, ok3 := args[2].hue()
+		return LCH{L: math.Max(0, l), C: math.Max(0, c), H: h, Alpha: a}.RGB(), !legacy && ok1 && ok2 && ok3
+	case "oklab":
+		l, ok1 := args[0].number(1)
+		x, ok2 := args[1].number(0.4)
+		y, ok3 := args[2].number(0.4)
+		return OKLab{L: clamp(l), A: x, B: y, Alpha: a}.RGB(), !legacy && ok1 && ok2 && ok3
+	case "oklch":
+		l, ok1 := args[0].number(1)
+		c, ok2 := args[1].number(0.4)
+		h, ok3 := args[2].hue()
+		return OKLCH{L: clamp(l), C: math.Max(0, c), H: h, Alpha: a}.RGB(), !legacy && ok1 && ok2 && ok3
+	default:
+		return Color{}, false
+	}
+}
+
+// https://www.w3.org/TR/css-color-4/#rgb-functions
+func rgb(args []component, alpha float64, legacy bool) (Color, bool) {
+	var c [3]float64
+	for i, arg := range args {
+		// The legacy syntax does not mix numbers and percentages.
+		if legacy && arg.token.Type != args[0].token.Type {
+			return Color{}, false
+		}
+
+		v, ok := arg.number(255)
+		if !ok {
+			return Color{}, false
+		}
+		c[i] = clamp(v / 255)
+	}
+
+	return Color{R: c[0], G: c[1], B: c[2], A: alpha}, true
+}
+
+func clamp(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
@@ -0,0 +1,301 @@
+package color
+
+import (
+	"math"
+)
+
+// HSL is a color in the hsl() form. H is in degrees, S and L are from 0 to 1.
+type HSL struct {
+	H, S, L, Alpha float64
+}
+
+// HWB is a color in the hwb() form. H is in degrees, W and B are from 0 to 1.
+type HWB struct {
+	H, W, B, Alpha float64
+}
+
+// Lab is a color in the CIE Lab space with the D50 white point, L is from 0
+// to 100.
+// https://www.w3.org/TR/css-color-4/#cie-lab
+type Lab struct {
+	L, A, B, Alpha float64
+}
+
+// LCH is the polar form of Lab, H is in degrees.
+type LCH struct {
+	L, C, H, Alpha float64
+}
+
+// OKLab is a color in the Oklab space, L is from 0 to 1.
+// https://www.w3.org/TR/css-color-4/#ok-lab
+type OKLab struct {
+	L, A, B, Alpha float64
+}
+
+// OKLCH is the polar form of OKLab, H is in degrees.
+type OKLCH struct {
+	L, C, H, Alpha float64
+}
+
+// https://www.w3.org/TR/css-color-4/#hsl-to-rgb
+func (c HSL) RGB() Color {
+	h := normalizeHue(c.H)
+	f := func(n float64) float64 {
+		k := math.Mod(n+h/30, 12)
+		a := c.S * math.Min(c.L, 1-c.L)
+		return c.L - a*math.Max(-1, math.Min(k-3, math.Min(9-k, 1)))
+	}
+
+	return Color{R: f(0), G: f(8), B: f(4), A: c.Alpha}
+}
+
+// https://www.w3.org/TR/css-color-4/#hwb-to-rgb
+func (c HWB) RGB() Color {
+	if c.W+c.B >= 1 {
+		gray := c.W / (c.W + c.B)
+		return Color{R: gray, G: gray, B: gray, A: c.Alpha}
+	}
+
+	rgb := HSL{H: c.H, S: 1, L: 0.5}.RGB()
+	scale := func(v float64) float64 {
+		return v*(1-c.W-c.B) + c.W
+	}
+
+	return Color{R: scale(rgb.R), G: scale(rgb.G), B: scale(rgb.B), A: c.Alpha}
+}
+
+// d50 is the white point of Lab.
+var d50 = [3]float64{0.3457 / 0.3585, 1, (1 - 0.3457 - 0.3585) / 0.3585}
+
+const (
+	labEpsilon = 216.0 / 24389
+	labKappa   = 24389.0 / 27
+)
+
+// https://www.w3.org/TR/css-color-4/#color-conversion-code
+func (c Lab) RGB() Color {
+	fy := (c.L + 16) / 116
+	fx := fy + c.A/500
+	fz := fy - c.B/200
+	f := func(t float64) float64 {
+		if t3 := t * t * t; t3 > labEpsilon {
+			return t3
+		}
+
+		return (116*t - 16) / labKappa
+	}
+
+	y := c.L / labKappa
+	if c.L > labKappa*labEpsilon {
+		y = fy * fy * fy
+	}
+
+	xyz := [3]float64{f(fx) * d50[0], y * d50[1], f(fz) * d50[2]}
+	return linearToColor(multiply(xyzToLinearSRGB, multiply(d50ToD65, xyz)), c.Alpha)
+}
+
+func (c LCH) RGB() Color {
+	return c.Lab().RGB()
+}
+
+func (c LCH) Lab() Lab {
+	h := c.H * math.Pi / 180
+	return Lab{L: c.L, A: c.C * math.Cos(h), B: c.C * math.Sin(h), Alpha: c.Alpha}
+}
+
+// https://bottosson.github.io/posts/oklab/
+func (c OKLab) RGB() Color {
+	l := c.L + 0.3963377774*c.A + 0.2158037573*c.B
+	m := c.L - 0.1055613458*c.A - 0.0638541728*c.B
+	s := c.L - 0.0894841775*c.A - 1.2914855480*c.B
+	l, m, s = l*l*l, m*m*m, s*s*s
+
+	return linearToColor([3]float64{
+		4.0767416621*l - 3.3077115913*m + 0.2309699292*s,
+		-1.2684380046*l + 2.6097574011*m - 0.3413193965*s,
+		-0.0041960863*l - 0.7034186147*m + 1.7076147010*s,
+	}, c.Alpha)
+}
+
+func (c OKLCH) RGB() Color {
+	return c.OKLab().RGB()
+}
+
+func (c OKLCH) OKLab() OKLab {
+	h := c.H * math.Pi / 180
+	return OKLab{L: c.L, A: c.C * math.Cos(h), B: c.C * math.Sin(h), Alpha: c.Alpha}
+}
+
+// https://www.w3.org/TR/css-color-4/#rgb-to-hsl
+func (c Color) HSL() HSL {
+	max := math.Max(c.R, math.Max(c.G, c.B))
+	min := math.Min(c.R, math.Min(c.G, c.B))
+	hsl := HSL{L: (max + min) / 2, Alpha: c.A}
+	// Colors converted from other spaces can be off by rounding errors, so
+	// almost equal components count as a gray.
+	d := max - min
+	if d < 1e-9 {
+		return hsl
+	}
+
+	if hsl.L != 0 && hsl.L != 1 {
+		hsl.S = (max - hsl.L) / math.Min(hsl.L, 1-hsl.L)
+	}
+
+	switch max {
+	case c.R:
+		hsl.H = (c.G - c.B) / d
+		if c.G < c.B {
+			hsl.H += 6
+		}
+	case c.G:
+		hsl.H = (c.B-c.R)/d + 2
+	default:
+		hsl.H = (c.R-c.G)/d + 4
+	}
+	hsl.H *= 60
+
+	return hsl
+}
+
+// https://www.w3.org/TR/css-color-4/#rgb-to-hwb
+func (c Color) HWB() HWB {
+	white := math.Min(c.R, math.Min(c.G, c.B))
+	black := 1 - math.Max(c.R, math.Max(c.G, c.B))
+	return HWB{H: c.HSL().H, W: white, B: black, Alpha: c.A}
+}
+
+func (c Color) Lab() Lab {
+	xyz := multiply(d65ToD50, multiply(linearSRGBToXYZ, c.linear()))
+	f := func(t float64) float64 {
+		if t > labEpsilon {
+			return math.Cbrt(t)
+		}
+
+		return (labKappa*t + 16) / 116
+	}
+
+	fx, fy, fz := f(xyz[0]/d50[0]), f(xyz[1]/d50[1]), f(xyz[2]/d50[2])
+	return Lab{L: 116*fy - 16, A: 500 * (fx - fy), B: 200 * (fy - fz), Alpha: c.A}
+}
+
+func (c Color) LCH() LCH {
+	lab := c.Lab()
+	chroma, hue := polar(lab.A, lab.B)
+	return LCH{L: lab.L, C: chroma, H: hue, Alpha: c.A}
+}
+
+func (c Color) OKLab() OKLab {
+	rgb := c.linear()
+	l := math.Cbrt(0.4122214708*rgb[0] + 0.5363325363*rgb[1] + 0.0514459929*rgb[2])
+	m := math.Cbrt(0.2119034982*rgb[0] + 0.6806995451*rgb[1] + 0.1073969566*rgb[2])
+	s := math.Cbrt(0.0883024619*rgb[0] + 0.2817188376*rgb[1] + 0.6299787005*rgb[2])
+
+	return OKLab{
+		L:     0.2104542553*l + 0.7936177850*m - 0.0040720468*s,
+		A:     1.9779984951*l - 2.4285922050*m + 0.4505937099*s,
+		B:     0.0259040371*l + 0.7827717662*m - 0.8086757660*s,
+		Alpha: c.A,
+	}
+}
+
+func (c Color) OKLCH() OKLCH {
+	lab := c.OKLab()
+	chroma, hue := polar(lab.A, lab.B)
+	return OKLCH{L: lab.L, C: chroma, H: hue, Alpha: c.A}
+}
+
+// Luminance returns the relative luminance of the color, ignoring its alpha.
+// https://www.w3.org/TR/WCAG21/#dfn-relative-luminance
+func (c Color) Luminance() float64 {
+	rgb := c.Clamp().linear()
+	return 0.2126*rgb[0] + 0.7152*rgb[1] + 0.0722*rgb[2]
+}
+
+// Contrast returns the contrast ratio of two colors, from 1 to 21. WCAG asks
+// for at least 4.5 for normal text and 3 for large text.
+// https://www.w3.org/TR/WCAG21/#dfn-contrast-ratio
+func Contrast(a, b Color) float64 {
+	l1, l2 := a.Luminance(), b.Luminance()
+	if l1 < l2 {
+		l1, l2 = l2, l1
+	}
+
+	return (l1 + 0.05) / (l2 + 0.05)
+}
+
+func (c Color) linear() [3]float64 {
+	f := func(v float64) float64 {
+		if a := math.Abs(v); a > 0.04045 {
+			return math.Copysign(math.Pow((a+0.055)/1.055, 2.4), v)
+		}
+
+		return v / 12.92
+	}
+
+	return [3]float64{f(c.R), f(c.G), f(c.B)}
+}
+
+func linearToColor(rgb [3]float64, alpha float64) Color {
+	f := func(v float64) float64 {
+		if a := math.Abs(v); a > 0.0031308 {
+			return math.Copysign(1.055*math.Pow(a, 1/2.4)-0.055, v)
+		}
+
+		return 12.92 * v
+	}
+
+	return Color{R: f(rgb[0]), G: f(rgb[1]), B: f(rgb[2]), A: alpha}
+}
+
+func polar(a, b float64) (chroma, hue float64) {
+	chroma = math.Hypot(a, b)
+	if chroma < 1e-9 {
+		return 0, 0
+	}
+
+	return chroma, normalizeHue(math.Atan2(b, a) * 180 / math.Pi)
+}
+
+func normalizeHue(h float64) float64 {
+	h = math.Mod(h, 360)
+	if h < 0 {
+		h += 360
+	}
+
+	return h
+}
+
+type matrix [3][3]float64
+
+func multiply(m matrix, v [3]float64) [3]float64 {
+	return [3]float64{
+		m[0][0]*v[0] + m[0][1]*v[1] + m[0][2]*v[2],
+		m[1][0]*v[0] + m[1][1]*v[1] + m[1][2]*v[2],
+		m[2][0]*v[0] + m[2][1]*v[1] + m[2][2]*v[2],
+	}
+}
+
+// The matrices of the sample code of CSS Color 4.
+var (
+	linearSRGBToXYZ = matrix{
+		{0.41239079926595934, 0.357584339383878, 0.1804807884018343},
+		{0.21263900587151027, 0.715168678767756, 0.07219231536073371},
+		{0.01933081871559182, 0.11919477979462598, 0.9505321522496607},
+	}
+	xyzToLinearSRGB = matrix{
+		{3.2409699419045226, -1.537383177570094, -0.4986107602930034},
+		{-0.9692436362808796, 1.8759675015077202, 0.04155505740717559},
+		{0.05563007969699366, -0.20397695888897652, 1.0569715142428786},
+	}
+	d65ToD50 = matrix{
+		{1.0479298208405488, 0.022946793341019088, -0.05019222954313557},
+		{0.029627815688159344, 0.990434484573249, -0.01707382502938514},
+		{-0.009243058152591178, 0.015055144896577895, 0.7518742899580008},
+	}
+	d50ToD65 = matrix{
+		{0.955473421488075, -0.02309845494876471, 0.06325924320057072},
+		{-0.0283697093338637, 1.0099953980813041, 0.021041441191917323},
+		{0.012314014864481998, -0.020507649298898964, 1.330365926242124},
+	}
+)
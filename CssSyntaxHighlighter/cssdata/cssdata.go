@@ -0,0 +1,59 @@
+// Package cssdata holds the tables of CSS names that the minifier, the
+// formatter, the linter and the highlighter share. The names are lower case.
+package cssdata
+
+// GroupRules are the at-rules whose blocks contain rules instead of
+// declarations, including the keyframes rules.
+var GroupRules = map[string]bool{
+	"media":             true,
+	"supports":          true,
+	"document":          true,
+	"layer":             true,
+	"container":         true,
+	"scope":             true,
+	"keyframes":         true,
+	"-webkit-keyframes": true,
+	"-moz-keyframes":    true,
+}
+
+// KeyframesRules are the group rules whose rules are keyframes, with
+// percentages instead of selectors in their preludes.
+var KeyframesRules = map[string]bool{
+	"keyframes":         true,
+	"-webkit-keyframes": true,
+	"-moz-keyframes":    true,
+}
+
+// MathFunctions are the functions whose arguments are math expressions.
+// https://www.w3.org/TR/css-values-4/#math
+var MathFunctions = map[string]bool{
+	"calc":         true,
+	"-webkit-calc": true,
+	"-moz-calc":    true,
+	"min":          true,
+	"max":          true,
+	"clamp":        true,
+}
+
+// SelectorFunctions are the functional pseudo-classes that take selectors.
+var SelectorFunctions = map[string]bool{
+	"is":           true,
+	"not":          true,
+	"where":        true,
+	"has":          true,
+	"matches":      true,
+	"-webkit-any":  true,
+	"-moz-any":     true,
+	"host":         true,
+	"host-context": true,
+	"slotted":      true,
+}
+
+// LengthUnits are the units of the lengths, which may be left out of a zero
+// length outside of math functions.
+// https://www.w3.org/TR/css-values-4/#lengths
+var LengthUnits = map[string]bool{
+	"px": true, "em": true, "rem": true, "ex": true, "ch": true, "cap": true, "ic": true, "lh": true, "rlh": true,
+	"vw": true, "vh": true, "vi": true, "vb": true, "vmin": true, "vmax": true,
+	"cm": true, "mm": true, "q": true, "in": true, "pt": true, "pc": true,
+}
@@ -0,0 +1,274 @@
+package deps
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/QuickOrBeDead/GoLangLearning/lexer"
+	"github.com/QuickOrBeDead/GoLangLearning/parser"
+	"github.com/QuickOrBeDead/GoLangLearning/sourcemap"
+)
+
+// Bundle writes the entry stylesheet to w with its imports replaced by the
+// stylesheets they import, as one file at the path output. Every stylesheet
+// is included once, where it is first imported, and the relative URLs in it
+// are rewritten to point to the same files from the directory of output.
+//
+// Imports with layer(), supports() or a media query are wrapped in the
+// matching @layer, @supports and @media rules. Imports of files that are not
+// in the graph, like external or missing ones, are kept and moved to the
+// start of the bundle, since @import rules must come before the other rules.
+func (g *Graph) Bundle(w io.Writer, output string) error {
+	return g.BundleWithSourceMap(w, output, nil)
+}
+
+// BundleWithSourceMap bundles like Bundle and maps every token of the bundle
+// to its position in the stylesheet it comes from. The sources are named by
+// their paths in the file system of the graph.
+func (g *Graph) BundleWithSourceMap(w io.Writer, output string, sourceMap *sourcemap.Generator) error {
+	output = path.Clean(output)
+	if !fs.ValidPath(output) {
+		return fmt.Errorf("the bundle %s is outside of the root directory", output)
+	}
+
+	b := &bundler{g: g, dir: path.Dir(output), included: make(map[string]bool)}
+	body := b.stylesheet(g.stylesheets[g.Entry])
+	bundle := &chunk{}
+	bundle.append(&b.charset)
+	for _, c := range b.imports {
+		bundle.append(c)
+	}
+	bundle.append(body)
+
+	if sourceMap != nil {
+		sourceMap.Append(&bundle.sourceMap)
+	}
+
+	_, err := io.WriteString(w, bundle.sb.String())
+	return err
+}
+
+type bundler struct {
+	g   *Graph
+	dir string
+	// charset and imports are the @charset rule of the entry and the kept
+	// imports, which are written before the rest.
+	charset  chunk
+	imports  []*chunk
+	included map[string]bool
+}
+
+// chunk is a part of the bundle and its mappings.
+type chunk struct {
+	sb        strings.Builder
+	sourceMap sourcemap.Generator
+	// sources are the paths of the stylesheets whose text the source map has.
+	sources map[string]bool
+}
+
+func (c *chunk) write(s string) {
+	c.sb.WriteString(s)
+	c.sourceMap.WriteString(s)
+}
+
+func (c *chunk) append(other *chunk) {
+	c.sb.WriteString(other.sb.String())
+	c.sourceMap.Append(&other.sourceMap)
+}
+
+// add maps the next write to the position line:col of s. The source map gets
+// the text of s first, to count the column in UTF-16 code units.
+func (c *chunk) add(s *Stylesheet, line, col int) {
+	if !c.sources[s.Path] {
+		if c.sources == nil {
+			c.sources = make(map[string]bool)
+		}
+		c.sources[s.Path] = true
+		c.sourceMap.WriteSource(s.Path, string(s.Text))
+	}
+
+	c.sourceMap.Add(s.Path, line, col)
+}
+
+// copy writes the text of s from start to stop and maps the tokens in it.
+func (c *chunk) copy(s *Stylesheet, start, stop int) {
+	i := sort.Search(len(s.tokens), func(i int) bool {
+		return s.tokens[i].Start >= start
+	})
+
+	for ; i < len(s.tokens) && s.tokens[i].Start < stop; i++ {
+		t := s.tokens[i]
+		c.write(string(s.Text[start:t.Start]))
+		c.add(s, t.Line, t.Col)
+		start = t.Start
+	}
+
+	c.write(string(s.Text[start:stop]))
+}
+
+// stylesheet returns the text of s with its imports inlined and its URLs
+// rewritten.
+func (b *bundler) stylesheet(s *Stylesheet) *chunk {
+	b.included[s.Path] = true
+
+	c := &chunk{}
+	pos := 0
+	skip := func(span parser.Span) {
+		c.copy(s, pos, span.Start)
+		pos = span.Stop
+	}
+
+	// https://www.w3.org/TR/css-syntax-3/#charset-rule
+	for _, rule := range s.Sheet.Rules {
+		if r, ok := rule.(*parser.AtRule); ok && strings.EqualFold(r.Name(), "charset") {
+			if s.Path == b.g.Entry {
+				b.charset.copy(s, r.Start, r.Stop)
+				b.charset.write("\n")
+			}
+
+			skip(r.Span)
+		}
+
+		if _, ok := rule.(*parser.Comment); !ok {
+			break
+		}
+	}
+
+	for _, r := range s.References {
+		if r.Kind == URL {
+			skip(r.Span)
+			b.url(c, s, r)
+			continue
+		}
+
+		switch imported := b.g.stylesheets[r.Path]; {
+		case imported != nil && b.included[r.Path]:
+			skip(r.rule.Span)
+		case imported != nil:
+			skip(r.rule.Span)
+			wrap(c, s, r, b.stylesheet(imported))
+		default:
+			skip(r.rule.Span)
+			rule := &chunk{}
+			rule.copy(s, r.rule.Start, r.Start)
+			b.url(rule, s, r)
+			rule.copy(s, r.Stop, r.rule.Stop)
+			if !r.rule.Semicolon {
+				rule.write(";")
+			}
+			rule.write("\n")
+			b.imports = append(b.imports, rule)
+		}
+	}
+
+	c.copy(s, pos, len(s.Text))
+	return c
+}
+
+// url writes the URL of r, rewritten when it is relative and the bundle is
+// in another directory.
+func (b *bundler) url(c *chunk, s *Stylesheet, r Reference) {
+	if r.Path == "" || strings.HasPrefix(r.URL, "/") {
+		c.copy(s, r.Start, r.Stop)
+		return
+	}
+
+	_, suffix := splitURL(r.URL)
+	u := relative(b.dir, r.Path) + suffix
+	if u == r.URL {
+		c.copy(s, r.Start, r.Stop)
+		return
+	}
+
+	c.add(s, r.Line, r.Col)
+	switch {
+	case r.quoted:
+		c.write(quote(u))
+	case strings.ContainsAny(u, " \t\n\"'()\\"):
+		c.write("url(" + quote(u) + ")")
+	default:
+		c.write("url(" + u + ")")
+	}
+}
+
+// wrap writes an imported stylesheet in the rules that apply the conditions
+// of its @import rule r, which the rules are mapped to.
+func wrap(c *chunk, s *Stylesheet, r Reference, imported *chunk) {
+	conditions := r.conditions
+	var layer, supports string
+	if len(conditions) > 0 {
+		switch v := conditions[0].(type) {
+		case *parser.Token:
+			if v.Type == lexer.IdentToken && strings.EqualFold(string(v.Value), "layer") {
+				layer = "@layer"
+				conditions = trimValues(conditions[1:])
+			}
+		case *parser.Function:
+			if strings.EqualFold(v.Name(), "layer") {
+				layer = "@layer " + source(s, v.Values)
+				conditions = trimValues(conditions[1:])
+			}
+		}
+	}
+
+	if len(conditions) > 0 {
+		if f, ok := conditions[0].(*parser.Function); ok && strings.EqualFold(f.Name(), "supports") {
+			supports = "@supports (" + source(s, f.Values) + ")"
+			conditions = trimValues(conditions[1:])
+		}
+	}
+
+	var media string
+	if len(conditions) > 0 {
+		media = "@media " + source(s, conditions)
+	}
+
+	depth := 0
+	for _, at := range []string{layer, supports, media} {
+		if at != "" {
+			c.add(s, r.rule.Line, r.rule.Col)
+			c.write(at + " {\n")
+			depth++
+		}
+	}
+
+	c.append(imported)
+	for ; depth > 0; depth-- {
+		c.write("\n}")
+	}
+}
+
+// source returns the text of values without the whitespace around them.
+func source(s *Stylesheet, values []parser.ComponentValue) string {
+	values = trimValues(values)
+	if len(values) == 0 {
+		return ""
+	}
+
+	return string(s.Text[values[0].Position().Start:values[len(values)-1].Position().Stop])
+}
+
+// quote returns s as a CSS string.
+// https://www.w3.org/TR/cssom-1/#serialize-a-string
+func quote(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&sb, "\\%x ", r)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	sb.WriteByte('"')
+
+	return sb.String()
+}
@@ -0,0 +1,408 @@
+// Package deps follows the @import rules and url() references of a stylesheet
+// to the files they point to. It reports missing files, import cycles and
+// unused files, and bundles the imports into one stylesheet.
+package deps
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/QuickOrBeDead/GoLangLearning/lexer"
+	"github.com/QuickOrBeDead/GoLangLearning/parser"
+)
+
+type Kind uint8
+
+const (
+	Import Kind = iota
+	URL
+)
+
+func (k Kind) String() string {
+	if k == Import {
+		return "import"
+	}
+
+	return "url"
+}
+
+// Reference is an @import rule or a url() in a stylesheet. Its span is the
+// url token or the string of the URL.
+type Reference struct {
+	parser.Span
+	Kind Kind
+	// URL is the URL as written, without the quotes and escapes.
+	URL string
+	// Path is the slash-separated path of the file the URL points to in the
+	// file system of the graph. It is "" for URLs that are not files, like
+	// https: and data: URLs or #fragments, and for invalid ones.
+	Path string
+	// quoted is set when the span is a string instead of a url token.
+	quoted bool
+	// err is why a URL could not be resolved to a path.
+	err error
+	// rule and conditions are the @import rule of an import and what follows
+	// its URL: layer(), supports() and a media query.
+	rule       *parser.AtRule
+	conditions []parser.ComponentValue
+}
+
+type Stylesheet struct {
+	Path       string
+	Text       []rune
+	Sheet      *parser.Stylesheet
+	References []Reference
+	// tokens are the tokens of Text other than whitespace, which the bundle
+	// maps to.
+	tokens []lexer.Token
+}
+
+type Problem struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Col     int    `json:"col"`
+	Message string `json:"message"`
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s", p.File, p.Line, p.Col, p.Message)
+}
+
+// Graph is the entry stylesheet and the files it depends on.
+type Graph struct {
+	FS    fs.FS
+	Entry string
+	// Stylesheets are the entry and the stylesheets it imports directly or
+	// not, in the order they were found.
+	Stylesheets []*Stylesheet
+	// Assets are the existing files that url() references point to, in the
+	// order they were found.
+	Assets []string
+	// Cycles are the import cycles, each starting and ending with the same
+	// stylesheet.
+	Cycles   [][]string
+	Problems []Problem
+
+	stylesheets map[string]*Stylesheet
+	assets      map[string]bool
+	// importing are the stylesheets whose imports are being loaded.
+	importing []string
+}
+
+// Build loads the stylesheet entry and the files it references from fsys.
+// Paths are slash-separated and relative to the root of fsys, which is also
+// where URLs starting with a slash start from. Only an unreadable entry is
+// an error, the other files are reported as problems.
+func Build(fsys fs.FS, entry string) (*Graph, error) {
+	entry = path.Clean(entry)
+	src, err := fs.ReadFile(fsys, entry)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &Graph{FS: fsys, Entry: entry, stylesheets: make(map[string]*Stylesheet), assets: make(map[string]bool)}
+	g.load(entry, src)
+	return g, nil
+}
+
+// Stylesheet returns the stylesheet of the graph at the path name, nil if
+// there is none.
+func (g *Graph) Stylesheet(name string) *Stylesheet {
+	return g.stylesheets[name]
+}
+
+func (g *Graph) load(name string, src []byte) {
+	s := parse(name, src)
+	g.Stylesheets = append(g.Stylesheets, s)
+	g.stylesheets[name] = s
+	g.importing = append(g.importing, name)
+	defer func() {
+		g.importing = g.importing[:len(g.importing)-1]
+	}()
+
+	for _, r := range s.References {
+		if r.err != nil {
+			g.problem(s, r, r.err.Error())
+		}
+
+		if r.Path == "" {
+			continue
+		}
+
+		if r.Kind == URL {
+			if g.assets[r.Path] {
+				continue
+			}
+
+			if _, err := fs.Stat(g.FS, r.Path); err != nil {
+				g.problem(s, r, missing("file", r.Path, err))
+				continue
+			}
+
+			g.assets[r.Path] = true
+			g.Assets = append(g.Assets, r.Path)
+			continue
+		}
+
+		if i := indexOf(g.importing, r.Path); i >= 0 {
+			cycle := append(append([]string{}, g.importing[i:]...), r.Path)
+			g.Cycles = append(g.Cycles, cycle)
+			g.problem(s, r, "import cycle "+strings.Join(cycle, " -> "))
+			continue
+		}
+
+		if g.stylesheets[r.Path] != nil {
+			continue
+		}
+
+		src, err := fs.ReadFile(g.FS, r.Path)
+		if err != nil {
+			g.problem(s, r, missing("stylesheet", r.Path, err))
+			continue
+		}
+
+		g.load(r.Path, src)
+	}
+}
+
+func (g *Graph) problem(s *Stylesheet, r Reference, msg string) {
+	g.Problems = append(g.Problems, Problem{File: s.Path, Line: r.Line, Col: r.Col, Message: msg})
+}
+
+func missing(what, name string, err error) string {
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Sprintf("missing %s %s", what, name)
+	}
+
+	return err.Error()
+}
+
+func indexOf(list []string, s string) int {
+	for i, v := range list {
+		if v == s {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// unusedExtensions are the extensions of the files Unused reports:
+// stylesheets, images and fonts.
+var unusedExtensions = map[string]bool{
+	".css": true, ".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".svg": true, ".webp": true,
+	".avif": true, ".ico": true, ".cur": true, ".bmp": true, ".woff": true, ".woff2": true, ".ttf": true,
+	".otf": true, ".eot": true,
+}
+
+// Unused returns the stylesheets, images and fonts under the directory dir
+// that the graph does not reach. Other files, like the sources or documents
+// next to them, are not reported.
+func (g *Graph) Unused(dir string) ([]string, error) {
+	unused := []string{}
+	err := fs.WalkDir(g.FS, path.Clean(dir), func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.IsDir() && unusedExtensions[strings.ToLower(path.Ext(name))] && g.stylesheets[name] == nil && !g.assets[name] {
+			unused = append(unused, name)
+		}
+
+		return nil
+	})
+
+	return unused, err
+}
+
+func parse(name string, src []byte) *Stylesheet {
+	s := &Stylesheet{Path: name, Text: []rune(string(src))}
+	s.Sheet = parser.New(&lexer.Lexer{Text: s.Text, Dialect: lexer.DialectOf(name)}).ParseStylesheet()
+	lex := &lexer.Lexer{Text: s.Text, Dialect: lexer.DialectOf(name)}
+	for t := lex.NextToken(); t.Type != lexer.EOF; t = lex.NextToken() {
+		if t.Type != lexer.WhitespaceToken {
+			s.tokens = append(s.tokens, t)
+		}
+	}
+	for _, rule := range s.Sheet.Rules {
+		switch r := rule.(type) {
+		case *parser.AtRule:
+			if strings.EqualFold(r.Name(), "import") {
+				if ref, ok := importReference(r); ok {
+					s.References = append(s.References, ref)
+					continue
+				}
+			}
+
+			s.References = urls(s.References, r.Prelude)
+			if r.Block != nil {
+				s.References = urls(s.References, r.Block.Values)
+			}
+		case *parser.QualifiedRule:
+			s.References = urls(s.References, r.Prelude)
+			s.References = urls(s.References, r.Block.Values)
+		}
+	}
+
+	for i := range s.References {
+		s.References[i].Path, s.References[i].err = resolve(name, s.References[i].URL)
+	}
+
+	return s
+}
+
+// https://www.w3.org/TR/css-cascade-5/#at-import
+func importReference(r *parser.AtRule) (Reference, bool) {
+	for i, v := range r.Prelude {
+		if isTrivia(v) {
+			continue
+		}
+
+		ref, ok := urlReference(v, true)
+		if !ok {
+			return Reference{}, false
+		}
+
+		ref.Kind = Import
+		ref.rule = r
+		ref.conditions = trimValues(r.Prelude[i+1:])
+		return ref, true
+	}
+
+	return Reference{}, false
+}
+
+// urls appends the url() references in values to refs.
+func urls(refs []Reference, values []parser.ComponentValue) []Reference {
+	for _, v := range values {
+		if ref, ok := urlReference(v, false); ok {
+			refs = append(refs, ref)
+			continue
+		}
+
+		switch v := v.(type) {
+		case *parser.Function:
+			refs = urls(refs, v.Values)
+		case *parser.SimpleBlock:
+			refs = urls(refs, v.Values)
+		}
+	}
+
+	return refs
+}
+
+// urlReference returns the reference of a url token or a url() function
+// with a string. Strings are URLs too when str is set, as they are in
+// @import rules.
+func urlReference(v parser.ComponentValue, str bool) (Reference, bool) {
+	switch v := v.(type) {
+	case *parser.Token:
+		switch {
+		case v.Type == lexer.UrlToken:
+			return Reference{Span: v.Position(), Kind: URL, URL: string(v.Value)}, true
+		case v.Type == lexer.StringToken && str:
+			return Reference{Span: v.Position(), Kind: URL, URL: string(v.Value), quoted: true}, true
+		}
+	case *parser.Function:
+		values := trimValues(v.Values)
+		if !strings.EqualFold(v.Name(), "url") || len(values) != 1 {
+			return Reference{}, false
+		}
+
+		if t, ok := values[0].(*parser.Token); ok && t.Type == lexer.StringToken {
+			return Reference{Span: t.Position(), Kind: URL, URL: string(t.Value), quoted: true}, true
+		}
+	}
+
+	return Reference{}, false
+}
+
+func isTrivia(v parser.ComponentValue) bool {
+	t, ok := v.(*parser.Token)
+	return ok && t.Type.IsTrivia()
+}
+
+// trimValues drops the whitespace and comments at both ends of values.
+func trimValues(values []parser.ComponentValue) []parser.ComponentValue {
+	for len(values) > 0 && isTrivia(values[0]) {
+		values = values[1:]
+	}
+
+	for len(values) > 0 && isTrivia(values[len(values)-1]) {
+		values = values[:len(values)-1]
+	}
+
+	return values
+}
+
+var schemePattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*:`)
+
+// resolve returns the path of the file that a URL in the stylesheet base
+// points to, "" for URLs that are not files: ones with a scheme or a host,
+// and ones with only a query or a fragment, like the #id of an SVG filter.
+// https://url.spec.whatwg.org/#concept-basic-url-parser
+func resolve(base, ref string) (string, error) {
+	if schemePattern.MatchString(ref) || strings.HasPrefix(ref, "//") {
+		return "", nil
+	}
+
+	p, _ := splitURL(ref)
+	if p == "" {
+		return "", nil
+	}
+
+	p, err := url.PathUnescape(p)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL %s", ref)
+	}
+
+	if strings.HasPrefix(p, "/") {
+		p = path.Clean(p[1:])
+	} else {
+		p = path.Join(path.Dir(base), p)
+	}
+
+	if !fs.ValidPath(p) {
+		return "", fmt.Errorf("%s is outside of the root directory", ref)
+	}
+
+	return p, nil
+}
+
+// splitURL splits a URL into its path and the query and fragment after it.
+func splitURL(ref string) (p, suffix string) {
+	if i := strings.IndexAny(ref, "?#"); i >= 0 {
+		return ref[:i], ref[i:]
+	}
+
+	return ref, ""
+}
+
+// relative returns the path of the file to relative to the directory dir,
+// both slash-separated paths in the same file system.
+func relative(dir, to string) string {
+	split := func(p string) []string {
+		if p == "." {
+			return nil
+		}
+
+		return strings.Split(p, "/")
+	}
+
+	from, target := split(dir), split(to)
+	for len(from) > 0 && len(target) > 1 && from[0] == target[0] {
+		from, target = from[1:], target[1:]
+	}
+
+	parts := make([]string, 0, len(from)+len(target))
+	for range from {
+		parts = append(parts, "..")
+	}
+
+	return strings.Join(append(parts, target...), "/")
+}
@@ -0,0 +1,281 @@
+package deps
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/QuickOrBeDead/GoLangLearning/sourcemap"
+)
+
+func testFS() fstest.MapFS {
+	files := map[string]string{
+		"site/main.css": `@charset "utf-8";
+@import "base.css";
+@import url(theme/dark.css) screen and (prefers-color-scheme: dark);
+@import url("https://fonts.example.com/css?family=Inter");
+@import "missing.css";
+body { background: url(img/bg.png) }
+.logo { background-image: url('/site/img/logo.svg#icon'); filter: url(#blur) }
+.x { content: "a.png"; background: url(data:image/png;base64,AAAA) }
+`,
+		"site/base.css": `@import "theme/vars.css" layer(base) supports(display: grid);
+a { cursor: url(img/hand.cur), pointer }
+`,
+		"site/theme/dark.css": `@import "../base.css";
+body { background: url("../img/bg dark.png") }
+`,
+		"site/theme/vars.css": `:root { --x: url(../fonts/missing.woff2) }
+@font-face { src: url(../../../outside.woff) }
+`,
+		"site/img/bg.png":      "",
+		"site/img/bg dark.png": "",
+		"site/img/logo.svg":    "",
+		"site/img/hand.cur":    "",
+		"site/img/unused.gif":  "",
+		"site/old.css":         "",
+		"site/README.md":       "",
+		"site/a.css":           `@import "b.css";`,
+		"site/b.css":           `@import url(c.css);`,
+		"site/c.css":           `@import "a.css";`,
+	}
+
+	fsys := fstest.MapFS{}
+	for name, text := range files {
+		fsys[name] = &fstest.MapFile{Data: []byte(text)}
+	}
+
+	return fsys
+}
+
+func TestBuild(t *testing.T) {
+	g, err := Build(testFS(), "site/main.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stylesheets := []string{}
+	for _, s := range g.Stylesheets {
+		stylesheets = append(stylesheets, s.Path)
+	}
+
+	if expected := []string{"site/main.css", "site/base.css", "site/theme/vars.css", "site/theme/dark.css"}; !reflect.DeepEqual(stylesheets, expected) {
+		t.Fatalf("stylesheets (expected) %v != %v (actual)", expected, stylesheets)
+	}
+
+	if expected := []string{"site/img/hand.cur", "site/img/bg dark.png", "site/img/bg.png", "site/img/logo.svg"}; !reflect.DeepEqual(g.Assets, expected) {
+		t.Fatalf("assets (expected) %v != %v (actual)", expected, g.Assets)
+	}
+
+	problems := []string{}
+	for _, p := range g.Problems {
+		problems = append(problems, p.String())
+	}
+
+	expected := []string{
+		"site/theme/vars.css:1:14: missing file site/fonts/missing.woff2",
+		"site/theme/vars.css:2:19: ../../../outside.woff is outside of the root directory",
+		"site/main.css:5:9: missing stylesheet site/missing.css",
+	}
+	if !reflect.DeepEqual(problems, expected) {
+		t.Fatalf("problems (expected) %q != %q (actual)", expected, problems)
+	}
+
+	refs := []string{}
+	for _, r := range g.Stylesheet("site/main.css").References {
+		refs = append(refs, r.Kind.String()+" "+r.URL+" "+r.Path)
+	}
+
+	expectedRefs := []string{
+		"import base.css site/base.css",
+		"import theme/dark.css site/theme/dark.css",
+		"import https://fonts.example.com/css?family=Inter ",
+		"import missing.css site/missing.css",
+		"url img/bg.png site/img/bg.png",
+		"url /site/img/logo.svg#icon site/img/logo.svg",
+		"url #blur ",
+		"url data:image/png;base64,AAAA ",
+	}
+	if !reflect.DeepEqual(refs, expectedRefs) {
+		t.Fatalf("references (expected) %q != %q (actual)", expectedRefs, refs)
+	}
+}
+
+func TestCycles(t *testing.T) {
+	g, err := Build(testFS(), "site/a.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := [][]string{{"site/a.css", "site/b.css", "site/c.css", "site/a.css"}}; !reflect.DeepEqual(g.Cycles, expected) {
+		t.Fatalf("cycles (expected) %v != %v (actual)", expected, g.Cycles)
+	}
+
+	if len(g.Problems) != 1 || g.Problems[0].String() != "site/c.css:1:9: import cycle site/a.css -> site/b.css -> site/c.css -> site/a.css" {
+		t.Fatalf("problems %v", g.Problems)
+	}
+
+	var sb strings.Builder
+	if err := g.Bundle(&sb, "site/a.css"); err != nil {
+		t.Fatal(err)
+	}
+
+	if output := sb.String(); output != "" {
+		t.Fatalf("bundle (expected) %q != %q (actual)", "", output)
+	}
+}
+
+func TestUnused(t *testing.T) {
+	if _, err := Build(testFS(), "site/none.css"); err == nil {
+		t.Fatalf("missing entry without error")
+	}
+
+	g, err := Build(testFS(), "site/main.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unused, err := g.Unused("site")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []string{"site/a.css", "site/b.css", "site/c.css", "site/img/unused.gif", "site/old.css"}; !reflect.DeepEqual(unused, expected) {
+		t.Fatalf("unused (expected) %v != %v (actual)", expected, unused)
+	}
+}
+
+func TestBundle(t *testing.T) {
+	g, err := Build(testFS(), "site/main.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := []struct {
+		output string
+		bundle string
+	}{
+		{"site/main.css", `@charset "utf-8";
+@import url("https://fonts.example.com/css?family=Inter");
+@import "missing.css";
+
+@layer base {
+@supports (display: grid) {
+:root { --x: url(fonts/missing.woff2) }
+@font-face { src: url(../../../outside.woff) }
+
+}
+}
+a { cursor: url(img/hand.cur), pointer }
+
+@media screen and (prefers-color-scheme: dark) {
+
+body { background: url("img/bg dark.png") }
+
+}
+
+
+body { background: url(img/bg.png) }
+.logo { background-image: url('/site/img/logo.svg#icon'); filter: url(#blur) }
+.x { content: "a.png"; background: url(data:image/png;base64,AAAA) }
+`},
+		{"dist/bundle.css", `@charset "utf-8";
+@import url("https://fonts.example.com/css?family=Inter");
+@import "../site/missing.css";
+
+@layer base {
+@supports (display: grid) {
+:root { --x: url(../site/fonts/missing.woff2) }
+@font-face { src: url(../../../outside.woff) }
+
+}
+}
+a { cursor: url(../site/img/hand.cur), pointer }
+
+@media screen and (prefers-color-scheme: dark) {
+
+body { background: url("../site/img/bg dark.png") }
+
+}
+
+
+body { background: url(../site/img/bg.png) }
+.logo { background-image: url('/site/img/logo.svg#icon'); filter: url(#blur) }
+.x { content: "a.png"; background: url(data:image/png;base64,AAAA) }
+`},
+	}
+
+	for _, v := range values {
+		var sb strings.Builder
+		if err := g.Bundle(&sb, v.output); err != nil {
+			t.Fatal(err)
+		}
+
+		if bundle := sb.String(); bundle != v.bundle {
+			t.Fatalf("%s (expected) %q != %q (actual)", v.output, v.bundle, bundle)
+		}
+	}
+
+	if err := g.Bundle(&strings.Builder{}, "../bundle.css"); err == nil {
+		t.Fatalf("bundle outside of the root without error")
+	}
+}
+
+func TestBundleWithSourceMap(t *testing.T) {
+	fsys := testFS()
+	g, err := Build(fsys, "site/main.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sb strings.Builder
+	var sourceMap sourcemap.Generator
+	if err := g.BundleWithSourceMap(&sb, "dist/bundle.css", &sourceMap); err != nil {
+		t.Fatal(err)
+	}
+
+	m := sourceMap.Map("bundle.css")
+	if expected := []string{"site/main.css", "site/base.css", "site/theme/vars.css", "site/theme/dark.css"}; !reflect.DeepEqual(m.Sources, expected) {
+		t.Fatalf("sources (expected) %v != %v (actual)", expected, m.Sources)
+	}
+
+	mappings, err := sourcemap.Decode(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every mapped token starts with the same rune as its source, rewritten
+	// URLs and the rules around imports included.
+	lines := strings.Split(sb.String(), "\n")
+	for _, mapping := range mappings {
+		source := strings.Split(string(fsys[mapping.Source].Data), "\n")[mapping.OriginalLine][mapping.OriginalCol:]
+		output := lines[mapping.GeneratedLine][mapping.GeneratedCol:]
+		if source[0] != output[0] {
+			t.Fatalf("%+v maps %q to %q", mapping, output, source)
+		}
+	}
+
+	if m, _ := sourcemap.Lookup(mappings, 7, 0); m.Source != "site/theme/vars.css" || m.OriginalLine != 1 || m.OriginalCol != 0 {
+		t.Fatalf("@font-face is mapped to %+v", m)
+	}
+}
+
+func TestRelative(t *testing.T) {
+	values := []struct {
+		dir, to, output string
+	}{
+		{".", "a.png", "a.png"},
+		{".", "img/a.png", "img/a.png"},
+		{"css", "css/a.png", "a.png"},
+		{"css", "img/a.png", "../img/a.png"},
+		{"a/b", "a/c/d.png", "../c/d.png"},
+		{"a/b", "a", "../../a"},
+	}
+
+	for _, v := range values {
+		if output := relative(v.dir, v.to); output != v.output {
+			t.Fatalf("%s %s (expected) %q != %q (actual)", v.dir, v.to, v.output, output)
+		}
+	}
+}
@@ -0,0 +1,370 @@
+package format
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	"github.com/QuickOrBeDead/GoLangLearning/cssdata"
+	"github.com/QuickOrBeDead/GoLangLearning/lexer"
+	"github.com/QuickOrBeDead/GoLangLearning/parser"
+	"github.com/QuickOrBeDead/GoLangLearning/sourcemap"
+)
+
+type BraceStyle int
+
+const (
+	// SameLine puts the opening brace at the end of the selector or prelude.
+	SameLine BraceStyle = iota
+	// NextLine puts the opening brace on a line of its own.
+	NextLine
+)
+
+type Options struct {
+	// IndentWidth is the number of spaces of one indentation level. It is
+	// ignored when UseTabs is set.
+	IndentWidth int
+	UseTabs     bool
+	BraceStyle  BraceStyle
+}
+
+var DefaultOptions = Options{IndentWidth: 4}
+
+type context int
+
+const (
+	selectorContext context = iota
+	preludeContext
+	valueContext
+)
+
+type formatter struct {
+	w      *bufio.Writer
+	opts   Options
+	indent string
+	err    error
+	// sourceMap is nil when no source map is written, source is the name of
+	// the input in it.
+	sourceMap *sourcemap.Generator
+	source    string
+}
+
+// Format writes the stylesheet read by lex to w with one rule or declaration
+// per line. Stylesheets with syntax errors are not formatted, the first error
+// is returned instead.
+func Format(w io.Writer, lex *lexer.Lexer, opts Options) error {
+	return FormatWithSourceMap(w, lex, opts, nil, "")
+}
+
+// FormatWithSourceMap formats like Format and maps the lines of rules,
+// declarations and comments to their position in the input, which is named
+// source in the map.
+func FormatWithSourceMap(w io.Writer, lex *lexer.Lexer, opts Options, sourceMap *sourcemap.Generator, source string) error {
+	p := parser.New(lex)
+	s := p.ParseStylesheet()
+	if err := lex.Err(); err != nil {
+		return err
+	}
+
+	if len(p.Errors) > 0 {
+		return p.Errors[0]
+	}
+
+	if sourceMap != nil {
+		sourceMap.WriteSource(source, s.String())
+	}
+
+	f := &formatter{w: bufio.NewWriter(w), opts: opts, indent: indentation(opts), sourceMap: sourceMap, source: source}
+	f.rules(s.Rules, 0)
+	if f.err != nil {
+		return f.err
+	}
+
+	return f.w.Flush()
+}
+
+// String formats css with the given options.
+func String(css string, opts Options) (string, error) {
+	var sb strings.Builder
+	err := Format(&sb, &lexer.Lexer{Text: []rune(css)}, opts)
+	return sb.String(), err
+}
+
+func indentation(opts Options) string {
+	if opts.UseTabs {
+		return "\t"
+	}
+
+	return strings.Repeat(" ", opts.IndentWidth)
+}
+
+func (f *formatter) line(depth int, s string) {
+	f.lineAt(depth, s, parser.Span{})
+}
+
+// lineAt writes a line that comes from the node at span. Spans without a
+// line are not mapped.
+func (f *formatter) lineAt(depth int, s string, span parser.Span) {
+	for i := 0; i < depth; i++ {
+		f.write(f.indent)
+	}
+
+	if f.sourceMap != nil && span.Line > 0 {
+		f.sourceMap.Add(f.source, span.Line, span.Col)
+	}
+	f.write(s)
+	f.write("\n")
+}
+
+func (f *formatter) write(s string) {
+	f.w.WriteString(s)
+	if f.sourceMap != nil {
+		f.sourceMap.WriteString(s)
+	}
+}
+
+func (f *formatter) rules(rules []parser.Rule, depth int) {
+	for i, r := range rules {
+		if i > 0 && separated(rules[i-1], r) {
+			f.write("\n")
+		}
+
+		switch r := r.(type) {
+		case *parser.QualifiedRule:
+			f.block(formatValues(r.Prelude, selectorContext, false), r.Span, r.Block, false, depth)
+		case *parser.AtRule:
+			f.atRule(r, false, depth)
+		case *parser.Comment:
+			f.lineAt(depth, r.String(), r.Span)
+		}
+	}
+}
+
+// separated reports whether a blank line goes between two rules, which is
+// the case around rules with blocks unless a comment comes first.
+func separated(prev parser.Rule, r parser.Rule) bool {
+	if _, ok := prev.(*parser.Comment); ok {
+		return false
+	}
+
+	return hasBlock(prev) || hasBlock(r)
+}
+
+func hasBlock(r parser.Rule) bool {
+	switch r := r.(type) {
+	case *parser.QualifiedRule:
+		return r.Block != nil
+	case *parser.AtRule:
+		return r.Block != nil
+	default:
+		return false
+	}
+}
+
+// atRule writes an at-rule. The blocks of group rules nested in a
+// declaration block hold declarations and nested rules like it.
+func (f *formatter) atRule(r *parser.AtRule, nested bool, depth int) {
+	head := string(r.Token.Val)
+	if prelude := formatValues(r.Prelude, preludeContext, false); prelude != "" {
+		head += " " + prelude
+	}
+
+	if r.Block == nil {
+		f.lineAt(depth, head+";", r.Span)
+		return
+	}
+
+	f.block(head, r.Span, r.Block, cssdata.GroupRules[strings.ToLower(r.Name())] && !nested, depth)
+}
+
+func (f *formatter) block(head string, span parser.Span, b *parser.SimpleBlock, rules bool, depth int) {
+	if f.opts.BraceStyle == NextLine {
+		f.lineAt(depth, head, span)
+		f.line(depth, "{")
+	} else {
+		f.lineAt(depth, head+" {", span)
+	}
+
+	p := parser.NewFromValues(b.Values)
+	if rules {
+		f.rules(p.ParseRuleList(), depth+1)
+	} else {
+		f.declarations(p.ParseDeclarationList(), depth+1)
+	}
+
+	if len(p.Errors) > 0 && f.err == nil {
+		f.err = p.Errors[0]
+	}
+
+	f.line(depth, "}")
+}
+
+func (f *formatter) declarations(items []parser.BlockItem, depth int) {
+	for _, item := range items {
+		switch item := item.(type) {
+		case *parser.Declaration:
+			f.lineAt(depth, formatDeclaration(item), item.Span)
+		case *parser.QualifiedRule:
+			f.block(formatValues(item.Prelude, selectorContext, false), item.Span, item.Block, false, depth)
+		case *parser.AtRule:
+			f.atRule(item, true, depth)
+		case *parser.Comment:
+			f.lineAt(depth, item.String(), item.Span)
+		}
+	}
+}
+
+// formatDeclaration lowercases the property name, except for custom
+// properties whose names are case-sensitive and whose values are kept as
+// they are written.
+func formatDeclaration(d *parser.Declaration) string {
+	var sb strings.Builder
+	name := string(d.Token.Val)
+	custom := strings.HasPrefix(name, "--")
+	if custom {
+		sb.WriteString(name)
+		sb.WriteString(":")
+		var value strings.Builder
+		for _, v := range d.Value {
+			value.WriteString(v.String())
+		}
+
+		if v := strings.TrimSpace(value.String()); v != "" {
+			sb.WriteString(" ")
+			sb.WriteString(v)
+		}
+	} else {
+		sb.WriteString(strings.ToLower(name))
+		sb.WriteString(": ")
+		sb.WriteString(formatValues(d.Value, valueContext, true))
+	}
+
+	if d.Important {
+		sb.WriteString(" !important")
+	}
+	sb.WriteString(";")
+
+	return sb.String()
+}
+
+// valueWriter writes component values with whitespace collapsed to single
+// spaces, none inside brackets and one after commas.
+type valueWriter struct {
+	sb      strings.Builder
+	ctx     context
+	colors  bool
+	pending bool
+	// open is set after an opening bracket.
+	open bool
+	// parens is the number of open () blocks.
+	parens int
+}
+
+func formatValues(values []parser.ComponentValue, ctx context, colors bool) string {
+	vw := &valueWriter{ctx: ctx, colors: colors}
+	vw.values(values)
+	return vw.sb.String()
+}
+
+func (vw *valueWriter) values(values []parser.ComponentValue) {
+	for _, v := range values {
+		switch v := v.(type) {
+		case *parser.Token:
+			vw.token(v.Token)
+		case *parser.Function:
+			vw.write(string(v.Token.Val))
+			vw.open = true
+			vw.values(v.Values)
+			if v.Closed {
+				vw.close(")")
+			}
+		case *parser.SimpleBlock:
+			vw.write(string(v.Open.Val))
+			vw.open = true
+			if v.Open.Type == lexer.LeftParenthesisToken {
+				vw.parens++
+			}
+			vw.values(v.Values)
+			if v.Open.Type == lexer.LeftParenthesisToken {
+				vw.parens--
+			}
+			if v.Closed {
+				vw.close(closing(v))
+			}
+		}
+	}
+}
+
+func (vw *valueWriter) token(t lexer.Token) {
+	switch {
+	case t.Type == lexer.WhitespaceToken:
+		vw.pending = true
+	case t.Type == lexer.CommaToken:
+		vw.close(",")
+		vw.pending = true
+	case t.Type == lexer.ColonToken:
+		if vw.ctx == selectorContext || (vw.ctx == preludeContext && vw.parens == 0) {
+			vw.write(":")
+		} else {
+			vw.close(":")
+			vw.pending = true
+		}
+	case vw.ctx == selectorContext && !vw.open && isCombinator(t):
+		vw.pending = true
+		vw.write(string(t.Val))
+		vw.pending = true
+	case vw.colors && t.Type == lexer.HashToken:
+		vw.write(lowerHexColor(t))
+	default:
+		vw.write(string(t.Val))
+	}
+}
+
+func (vw *valueWriter) write(s string) {
+	if vw.pending && !vw.open && vw.sb.Len() > 0 {
+		vw.sb.WriteByte(' ')
+	}
+	vw.sb.WriteString(s)
+	vw.pending = false
+	vw.open = false
+}
+
+// close writes s without the whitespace before it.
+func (vw *valueWriter) close(s string) {
+	vw.pending = false
+	vw.write(s)
+}
+
+func closing(b *parser.SimpleBlock) string {
+	switch b.Close() {
+	case lexer.RightBraceToken:
+		return "}"
+	case lexer.RightBracketToken:
+		return "]"
+	default:
+		return ")"
+	}
+}
+
+func isCombinator(t lexer.Token) bool {
+	if t.Type == lexer.ColumnToken {
+		return true
+	}
+
+	return t.Type == lexer.DelimToken && len(t.Val) == 1 && strings.ContainsRune(">+~", t.Val[0])
+}
+
+func lowerHexColor(t lexer.Token) string {
+	v := string(t.Value)
+	if string(t.Val) != "#"+v || (len(v) != 3 && len(v) != 4 && len(v) != 6 && len(v) != 8) {
+		return string(t.Val)
+	}
+
+	for _, r := range v {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return string(t.Val)
+		}
+	}
+
+	return strings.ToLower(string(t.Val))
+}
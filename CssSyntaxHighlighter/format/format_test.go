@@ -0,0 +1,170 @@
+package format
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/QuickOrBeDead/GoLangLearning/lexer"
+	"github.com/QuickOrBeDead/GoLangLearning/sourcemap"
+)
+
+func TestString(t *testing.T) {
+	css := `@charset "utf-8";
+@import url(a.css)   screen;
+/* header */
+A,B>C+D   ~e{COLOR:#FFF;margin : 0 auto!important;font:12px/1.5 "A" , serif}
+li:nth-child( 2n + 1 ):not( .x , #Y ){--Main-Color :  #ABC ;background:URL( x.png ) RGB( 1,2 , 3 )}
+@media screen and (min-width:10px){.a{top:0}/* c */
+.b[ data-x = "y" ]{}}
+@font-face{font-family:x;src:local( x )}
+@page :first{margin:1in;@top-left{content:"x"}}`
+
+	expected := `@charset "utf-8";
+@import url(a.css) screen;
+/* header */
+A, B > C + D ~ e {
+    color: #fff;
+    margin: 0 auto !important;
+    font: 12px/1.5 "A", serif;
+}
+
+li:nth-child(2n + 1):not(.x, #Y) {
+    --Main-Color: #ABC;
+    background: URL( x.png ) RGB(1, 2, 3);
+}
+
+@media screen and (min-width: 10px) {
+    .a {
+        top: 0;
+    }
+
+    /* c */
+    .b[data-x = "y"] {
+    }
+}
+
+@font-face {
+    font-family: x;
+    src: local(x);
+}
+
+@page :first {
+    margin: 1in;
+    @top-left {
+        content: "x";
+    }
+}
+`
+
+	output, err := String(css, DefaultOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if output != expected {
+		t.Fatalf("(expected) %q != %q (actual)", expected, output)
+	}
+
+	again, err := String(output, DefaultOptions)
+	if err != nil || again != output {
+		t.Fatalf("formatting is not idempotent, %q, %v", again, err)
+	}
+}
+
+func TestStringOptions(t *testing.T) {
+	values := []struct {
+		opts   Options
+		output string
+	}{
+		{Options{IndentWidth: 2}, "@media print {\n  a {\n    b: c;\n  }\n}\n"},
+		{Options{UseTabs: true}, "@media print {\n\ta {\n\t\tb: c;\n\t}\n}\n"},
+		{Options{IndentWidth: 2, BraceStyle: NextLine}, "@media print\n{\n  a\n  {\n    b: c;\n  }\n}\n"},
+	}
+
+	for _, v := range values {
+		output, err := String("@media print{a{b:c}}", v.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if output != v.output {
+			t.Fatalf("%+v (expected) %q != %q (actual)", v.opts, v.output, output)
+		}
+	}
+}
+
+func TestStringNestedRules(t *testing.T) {
+	css := `a{color:red;.x   .y{top:0}&:hover>b{b:c}@media print{d:e;&.z{f:g}}}`
+	expected := `a {
+    color: red;
+    .x .y {
+        top: 0;
+    }
+    &:hover > b {
+        b: c;
+    }
+    @media print {
+        d: e;
+        &.z {
+            f: g;
+        }
+    }
+}
+`
+
+	output, err := String(css, DefaultOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if output != expected {
+		t.Fatalf("(expected) %q != %q (actual)", expected, output)
+	}
+}
+
+func TestStringErrors(t *testing.T) {
+	values := []string{
+		"a { color: red",
+		"a { 12px; color: red }",
+		"@media print { a { b: c }",
+		"a { b: c } d",
+	}
+
+	for _, v := range values {
+		if output, err := String(v, DefaultOptions); err == nil {
+			t.Fatalf("%q expected an error, got %q", v, output)
+		}
+	}
+}
+
+func TestFormatWithSourceMap(t *testing.T) {
+	css := "/* a */ a,b{color:red;\n  top : 0}@media print{c{d:e}}"
+	var sb strings.Builder
+	var g sourcemap.Generator
+	if err := FormatWithSourceMap(&sb, &lexer.Lexer{Text: []rune(css)}, DefaultOptions, &g, "a.css"); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "/* a */\na, b {\n    color: red;\n    top: 0;\n}\n\n@media print {\n    c {\n        d: e;\n    }\n}\n"; sb.String() != expected {
+		t.Fatalf("(expected) %q != %q (actual)", expected, sb.String())
+	}
+
+	mappings, err := sourcemap.Decode(g.Map("a.formatted.css"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []sourcemap.Mapping{
+		{GeneratedLine: 0, GeneratedCol: 0, Source: "a.css", OriginalLine: 0, OriginalCol: 0},
+		{GeneratedLine: 1, GeneratedCol: 0, Source: "a.css", OriginalLine: 0, OriginalCol: 8},
+		{GeneratedLine: 2, GeneratedCol: 4, Source: "a.css", OriginalLine: 0, OriginalCol: 12},
+		{GeneratedLine: 3, GeneratedCol: 4, Source: "a.css", OriginalLine: 1, OriginalCol: 2},
+		{GeneratedLine: 6, GeneratedCol: 0, Source: "a.css", OriginalLine: 1, OriginalCol: 10},
+		{GeneratedLine: 7, GeneratedCol: 4, Source: "a.css", OriginalLine: 1, OriginalCol: 23},
+		{GeneratedLine: 8, GeneratedCol: 8, Source: "a.css", OriginalLine: 1, OriginalCol: 25},
+	}
+	if !reflect.DeepEqual(mappings, expected) {
+		t.Fatalf("(expected) %v != %v (actual)", expected, mappings)
+	}
+}
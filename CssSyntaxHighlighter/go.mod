@@ -1,3 +1,5 @@
 module github.com/QuickOrBeDead/GoLangLearning
 
 go 1.19
+
+require golang.org/x/net v0.2.0
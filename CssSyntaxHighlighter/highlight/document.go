@@ -0,0 +1,146 @@
+package highlight
+
+import (
+	"bufio"
+	"bytes"
+	"html"
+	"io"
+	"strings"
+
+	nethtml "golang.org/x/net/html"
+)
+
+// Document writes the HTML document read from r to w as escaped source, with
+// the CSS of <style> elements and style attributes highlighted in place. The
+// CSS is lexed as written, character references are not decoded.
+func Document(w io.Writer, r io.Reader, opts Options) error {
+	bw := bufio.NewWriter(w)
+	z := nethtml.NewTokenizer(r)
+	inStyle := false
+	for {
+		tt := z.Next()
+		if tt == nethtml.ErrorToken {
+			if err := z.Err(); err != io.EOF {
+				return err
+			}
+
+			return bw.Flush()
+		}
+
+		raw := z.Raw()
+		switch tt {
+		case nethtml.TextToken:
+			if inStyle {
+				HTML(bw, string(raw), opts)
+			} else {
+				bw.WriteString(html.EscapeString(string(raw)))
+			}
+		case nethtml.StartTagToken, nethtml.SelfClosingTagToken:
+			name, _ := z.TagName()
+			inStyle = tt == nethtml.StartTagToken && string(name) == "style"
+			writeTag(bw, raw, opts)
+		case nethtml.EndTagToken:
+			inStyle = false
+			writeHTMLSpan(bw, "html-tag", raw)
+		case nethtml.CommentToken:
+			writeHTMLSpan(bw, "html-comment", raw)
+		case nethtml.DoctypeToken:
+			writeHTMLSpan(bw, "html-doctype", raw)
+		}
+	}
+}
+
+// DocumentString returns the highlighted HTML of an HTML document.
+func DocumentString(document string, opts Options) string {
+	var sb strings.Builder
+	Document(&sb, strings.NewReader(document), opts)
+	return sb.String()
+}
+
+func writeHTMLSpan(w *bufio.Writer, class string, raw []byte) {
+	w.WriteString(`<span class="`)
+	w.WriteString(class)
+	w.WriteString(`">`)
+	w.WriteString(html.EscapeString(string(raw)))
+	w.WriteString("</span>")
+}
+
+// writeTag writes a start tag with the values of its style attributes
+// highlighted as declaration lists.
+func writeTag(w *bufio.Writer, raw []byte, opts Options) {
+	start := 0
+	for _, v := range styleValues(raw) {
+		writeHTMLSpan(w, "html-tag", raw[start:v[0]])
+		HTML(w, string(raw[v[0]:v[1]]), Options{Properties: opts.Properties, Colors: opts.Colors, DeclarationList: true})
+		start = v[1]
+	}
+	writeHTMLSpan(w, "html-tag", raw[start:])
+}
+
+// styleValues returns the offsets of the style attribute values in the raw
+// text of a start tag, without the quotes.
+// https://html.spec.whatwg.org/multipage/parsing.html#before-attribute-name-state
+func styleValues(raw []byte) [][2]int {
+	isSpace := func(c byte) bool {
+		return c == ' ' || c == '\t' || c == '\n' || c == '\f' || c == '\r'
+	}
+
+	values := [][2]int{}
+	i := 1
+	for i < len(raw) && !isSpace(raw[i]) && raw[i] != '/' && raw[i] != '>' {
+		i++
+	}
+
+	for i < len(raw) {
+		for i < len(raw) && (isSpace(raw[i]) || raw[i] == '/') {
+			i++
+		}
+
+		if i == len(raw) || raw[i] == '>' {
+			break
+		}
+
+		nameStart := i
+		i++
+		for i < len(raw) && !isSpace(raw[i]) && raw[i] != '/' && raw[i] != '>' && raw[i] != '=' {
+			i++
+		}
+		name := raw[nameStart:i]
+
+		j := i
+		for j < len(raw) && isSpace(raw[j]) {
+			j++
+		}
+
+		if j == len(raw) || raw[j] != '=' {
+			continue
+		}
+
+		i = j + 1
+		for i < len(raw) && isSpace(raw[i]) {
+			i++
+		}
+
+		var value [2]int
+		if i < len(raw) && (raw[i] == '"' || raw[i] == '\'') {
+			end := bytes.IndexByte(raw[i+1:], raw[i])
+			if end < 0 {
+				end = len(raw) - i - 1
+			}
+			value = [2]int{i + 1, i + 1 + end}
+			i = value[1] + 1
+		} else {
+			value[0] = i
+			for i < len(raw) && !isSpace(raw[i]) && raw[i] != '>' {
+				i++
+			}
+			value[1] = i
+		}
+
+		if bytes.EqualFold(name, []byte("style")) {
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
@@ -0,0 +1,27 @@
+package highlight
+
+import (
+	"testing"
+)
+
+func TestDocumentString(t *testing.T) {
+	values := []struct {
+		document string
+		output   string
+	}{
+		{"<!DOCTYPE html><p>a &lt; b</p><!-- x -->",
+			`<span class="html-doctype">&lt;!DOCTYPE html&gt;</span><span class="html-tag">&lt;p&gt;</span>a &amp;lt; b<span class="html-tag">&lt;/p&gt;</span><span class="html-comment">&lt;!-- x --&gt;</span>`},
+		{"<style>a>b{}</style>",
+			`<span class="html-tag">&lt;style&gt;</span><span class="tok-ident cat-name">a</span><span class="tok-delim cat-punctuation">&gt;</span><span class="tok-ident cat-name">b</span><span class="tok-left-brace cat-punctuation">{</span><span class="tok-right-brace cat-punctuation">}</span><span class="html-tag">&lt;/style&gt;</span>`},
+		{`<p class=x STYLE = 'b:c' style=d:e>`,
+			`<span class="html-tag">&lt;p class=x STYLE = &#39;</span><span class="tok-ident cat-name tok-property">b</span><span class="tok-colon cat-punctuation">:</span><span class="tok-ident cat-name">c</span><span class="html-tag">&#39; style=</span><span class="tok-ident cat-name tok-property">d</span><span class="tok-colon cat-punctuation">:</span><span class="tok-ident cat-name">e</span><span class="html-tag">&gt;</span>`},
+		{`<img data-style="a:b" src=x />`,
+			`<span class="html-tag">&lt;img data-style=&#34;a:b&#34; src=x /&gt;</span>`},
+	}
+
+	for _, v := range values {
+		if output := DocumentString(v.document, Options{Properties: true}); output != v.output {
+			t.Fatalf("%s (expected) %q != %q (actual)", v.document, v.output, output)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package highlight
+
+import (
+	"bufio"
+	"embed"
+	"html"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/QuickOrBeDead/GoLangLearning/color"
+	"github.com/QuickOrBeDead/GoLangLearning/cssdata"
+	"github.com/QuickOrBeDead/GoLangLearning/lexer"
+	"github.com/QuickOrBeDead/GoLangLearning/parser"
+	"github.com/QuickOrBeDead/GoLangLearning/selector"
+)
+
+//go:embed themes/*.css
+var themes embed.FS
+
+const DefaultTheme = "light"
+
+type Options struct {
+	// Specificity wraps the selectors of style rules in spans with their
+	// specificity as title.
+	Specificity bool
+	// Properties adds the tok-property class to the names of declarations.
+	Properties bool
+	// Colors wraps the colors in declaration values in spans with a swatch
+	// and their hex, rgb and hsl forms as title.
+	Colors bool
+	// Dialect is the language of the CSS, like SCSS or Less.
+	Dialect lexer.Dialect
+	// DeclarationList is set when the CSS is a list of declarations without
+	// braces, like the value of a style attribute, rather than a stylesheet.
+	DeclarationList bool
+}
+
+// ClassName returns the class of the spans of a token type, like tok-ident or
+// tok-bad-url.
+func ClassName(t lexer.TokenType) string {
+	var sb strings.Builder
+	sb.WriteString("tok-")
+	prev := ' '
+	for _, r := range t.String() {
+		if unicode.IsUpper(r) && unicode.IsLower(prev) {
+			sb.WriteRune('-')
+		}
+		sb.WriteRune(unicode.ToLower(r))
+		prev = r
+	}
+
+	return sb.String()
+}
+
+// CategoryClassName returns the class of the spans of a token category, like
+// cat-literal. Themes style the categories and override the colors of some
+// token types.
+func CategoryClassName(c lexer.Category) string {
+	return "cat-" + c.String()
+}
+
+// spanClass returns the classes of the span of a token type.
+func spanClass(t lexer.TokenType) string {
+	return ClassName(t) + " " + CategoryClassName(t.Category())
+}
+
+// HTML writes css to w with every token except whitespace in a span with its
+// class name and the class name of its category. The text is escaped, so the
+// result can be put in a <pre>.
+func HTML(w io.Writer, css string, opts Options) error {
+	var o outline
+	if opts.Specificity || opts.Properties || opts.Colors {
+		o = outlineOf(css, opts)
+	}
+
+	bw := bufio.NewWriter(w)
+	wrapEnd := -1
+	l := lexer.Lexer{Text: []rune(css), Dialect: opts.Dialect}
+	for v := l.NextToken(); v.Type != lexer.EOF; v = l.NextToken() {
+		if s, ok := o.wraps[v.Start]; ok && wrapEnd < 0 {
+			bw.WriteString(s.open)
+			wrapEnd = s.end
+		}
+
+		if o.properties[v.Start] {
+			bw.WriteString(`<span class="tok-ident cat-name tok-property">`)
+			bw.WriteString(html.EscapeString(string(v.Val)))
+			bw.WriteString("</span>")
+		} else {
+			writeSpan(bw, v)
+		}
+
+		if v.End == wrapEnd {
+			bw.WriteString("</span>")
+			wrapEnd = -1
+		}
+	}
+
+	return bw.Flush()
+}
+
+func writeSpan(w *bufio.Writer, t lexer.Token) {
+	if t.Type == lexer.WhitespaceToken {
+		w.WriteString(html.EscapeString(string(t.Val)))
+		return
+	}
+
+	w.WriteString(`<span class="`)
+	w.WriteString(spanClass(t.Type))
+	w.WriteString(`">`)
+	w.WriteString(html.EscapeString(string(t.Val)))
+	w.WriteString("</span>")
+}
+
+// String returns the highlighted HTML of css.
+func String(css string, opts Options) string {
+	var sb strings.Builder
+	HTML(&sb, css, opts)
+	return sb.String()
+}
+
+// Themes returns the names of the theme stylesheets.
+func Themes() []string {
+	entries, _ := fs.ReadDir(themes, "themes")
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), ".css"))
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Theme returns the stylesheet of the named theme. The stylesheets style the
+// token spans inside an element with the highlight class.
+func Theme(name string) ([]byte, bool) {
+	if strings.ContainsAny(name, "/.") {
+		return nil, false
+	}
+
+	b, err := themes.ReadFile("themes/" + name + ".css")
+	return b, err == nil
+}
+
+// wrapSpan is a span around the tokens of a selector or a color. open is
+// the start tag of the span, end the offset after its last token.
+type wrapSpan struct {
+	end  int
+	open string
+}
+
+// outline holds the selectors, the colors and the declaration names of a
+// stylesheet by their start offset.
+type outline struct {
+	wraps      map[int]wrapSpan
+	properties map[int]bool
+}
+
+func outlineOf(css string, opts Options) outline {
+	o := outline{wraps: make(map[int]wrapSpan), properties: make(map[int]bool)}
+	var colors func(values []parser.ComponentValue)
+	colors = func(values []parser.ComponentValue) {
+		for _, v := range values {
+			if c, ok := color.FromValue(v); ok {
+				p := v.Position()
+				o.wraps[p.Start] = wrapSpan{end: p.Stop, open: colorOpen(c)}
+			} else if f, ok := v.(*parser.Function); ok {
+				colors(f.Values)
+			}
+		}
+	}
+
+	declarations := func(items []parser.BlockItem) {
+		for _, item := range items {
+			d, ok := item.(*parser.Declaration)
+			if !ok {
+				continue
+			}
+
+			if opts.Properties && d.Token.Type == lexer.IdentToken {
+				o.properties[d.Token.Start] = true
+			}
+
+			if opts.Colors {
+				colors(d.Value)
+			}
+		}
+	}
+
+	var walk func(rules []parser.Rule)
+	walk = func(rules []parser.Rule) {
+		for _, r := range rules {
+			switch r := r.(type) {
+			case *parser.QualifiedRule:
+				declarations(r.Block.Declarations())
+				if !opts.Specificity {
+					continue
+				}
+
+				list, err := selector.Parse(r.Prelude)
+				if err != nil {
+					continue
+				}
+
+				for _, c := range list {
+					o.wraps[c.Start] = wrapSpan{end: c.Stop, open: `<span class="selector" title="` + html.EscapeString("specificity "+c.Specificity().String()) + `">`}
+				}
+			case *parser.AtRule:
+				if r.Block == nil {
+					continue
+				}
+
+				name := strings.ToLower(r.Name())
+				switch {
+				case cssdata.KeyframesRules[name]:
+					for _, k := range r.Block.Rules() {
+						if k, ok := k.(*parser.QualifiedRule); ok {
+							declarations(k.Block.Declarations())
+						}
+					}
+				case cssdata.GroupRules[name]:
+					walk(r.Block.Rules())
+				default:
+					declarations(r.Block.Declarations())
+				}
+			}
+		}
+	}
+
+	p := parser.New(&lexer.Lexer{Text: []rune(css), Dialect: opts.Dialect})
+	if opts.DeclarationList {
+		declarations(p.ParseDeclarationList())
+	} else {
+		walk(p.ParseStylesheet().Rules)
+	}
+
+	return o
+}
+
+// colorOpen returns the start tag of the span around a color, with a swatch
+// and its hex, rgb and hsl forms as title.
+func colorOpen(c color.Color) string {
+	title := c.Hex() + "\n" + c.RGBString() + "\n" + c.HSLString()
+	return `<span class="color" title="` + html.EscapeString(title) + `"><span class="swatch" style="background-color: ` + c.Hex() + `"></span>`
+}
@@ -0,0 +1,116 @@
+package highlight
+
+import (
+	"testing"
+
+	"github.com/QuickOrBeDead/GoLangLearning/lexer"
+)
+
+func TestClassName(t *testing.T) {
+	values := []struct {
+		tokenType lexer.TokenType
+		className string
+	}{
+		{lexer.IdentToken, "tok-ident"},
+		{lexer.AtKeywordToken, "tok-at-keyword"},
+		{lexer.BadUrlToken, "tok-bad-url"},
+		{lexer.CDOToken, "tok-cdo"},
+		{lexer.LeftParenthesisToken, "tok-left-parenthesis"},
+	}
+
+	for _, v := range values {
+		if c := ClassName(v.tokenType); c != v.className {
+			t.Fatalf("%v (expected) %q != %q (actual)", v.tokenType, v.className, c)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	values := []struct {
+		css    string
+		opts   Options
+		output string
+	}{
+		{"a { color: red }", Options{},
+			`<span class="tok-ident cat-name">a</span> <span class="tok-left-brace cat-punctuation">{</span> <span class="tok-ident cat-name">color</span><span class="tok-colon cat-punctuation">:</span> <span class="tok-ident cat-name">red</span> <span class="tok-right-brace cat-punctuation">}</span>`},
+		{"</pre><script>", Options{},
+			`<span class="tok-delim cat-punctuation">&lt;</span><span class="tok-delim cat-punctuation">/</span><span class="tok-ident cat-name">pre</span><span class="tok-delim cat-punctuation">&gt;</span><span class="tok-delim cat-punctuation">&lt;</span><span class="tok-ident cat-name">script</span><span class="tok-delim cat-punctuation">&gt;</span>`},
+		{`a[x="<&>"]`, Options{},
+			`<span class="tok-ident cat-name">a</span><span class="tok-left-bracket cat-punctuation">[</span><span class="tok-ident cat-name">x</span><span class="tok-delim cat-punctuation">=</span><span class="tok-string cat-literal">&#34;&lt;&amp;&gt;&#34;</span><span class="tok-right-bracket cat-punctuation">]</span>`},
+		{"a b {}", Options{Specificity: true},
+			`<span class="selector" title="specificity (0,0,2)"><span class="tok-ident cat-name">a</span> <span class="tok-ident cat-name">b</span></span> <span class="tok-left-brace cat-punctuation">{</span><span class="tok-right-brace cat-punctuation">}</span>`},
+	}
+
+	for _, v := range values {
+		if output := String(v.css, v.opts); output != v.output {
+			t.Fatalf("%s (expected) %q != %q (actual)", v.css, v.output, output)
+		}
+	}
+}
+
+func TestThemes(t *testing.T) {
+	themes := Themes()
+	if len(themes) != 3 || themes[0] != "dark" || themes[1] != "high-contrast" || themes[2] != "light" {
+		t.Fatalf("unexpected themes %v", themes)
+	}
+
+	if _, ok := Theme(DefaultTheme); !ok {
+		t.Fatalf("missing default theme %s", DefaultTheme)
+	}
+
+	for _, name := range []string{"", "missing", "../highlight", "dark.css"} {
+		if _, ok := Theme(name); ok {
+			t.Fatalf("unexpected theme %q", name)
+		}
+	}
+}
+
+func TestStringProperties(t *testing.T) {
+	values := []struct {
+		css    string
+		opts   Options
+		output string
+	}{
+		{"a { b: c }", Options{Properties: true},
+			`<span class="tok-ident cat-name">a</span> <span class="tok-left-brace cat-punctuation">{</span> <span class="tok-ident cat-name tok-property">b</span><span class="tok-colon cat-punctuation">:</span> <span class="tok-ident cat-name">c</span> <span class="tok-right-brace cat-punctuation">}</span>`},
+		{"b: c; d", Options{Properties: true, DeclarationList: true},
+			`<span class="tok-ident cat-name tok-property">b</span><span class="tok-colon cat-punctuation">:</span> <span class="tok-ident cat-name">c</span><span class="tok-semicolon cat-punctuation">;</span> <span class="tok-ident cat-name">d</span>`},
+		{"b: c", Options{Properties: true},
+			`<span class="tok-ident cat-name">b</span><span class="tok-colon cat-punctuation">:</span> <span class="tok-ident cat-name">c</span>`},
+	}
+
+	for _, v := range values {
+		if output := String(v.css, v.opts); output != v.output {
+			t.Fatalf("%s (expected) %q != %q (actual)", v.css, v.output, output)
+		}
+	}
+}
+
+func TestStringColors(t *testing.T) {
+	values := []struct {
+		css    string
+		opts   Options
+		output string
+	}{
+		{"a{b:red}", Options{Colors: true},
+			`<span class="tok-ident cat-name">a</span><span class="tok-left-brace cat-punctuation">{</span><span class="tok-ident cat-name">b</span><span class="tok-colon cat-punctuation">:</span>` +
+				`<span class="color" title="#ff0000` + "\n" + `rgb(255 0 0)` + "\n" + `hsl(0 100% 50%)"><span class="swatch" style="background-color: #ff0000"></span><span class="tok-ident cat-name">red</span></span>` +
+				`<span class="tok-right-brace cat-punctuation">}</span>`},
+		{"b: rgb(0 0 255 / 50%)", Options{Colors: true, DeclarationList: true},
+			`<span class="tok-ident cat-name">b</span><span class="tok-colon cat-punctuation">:</span> ` +
+				`<span class="color" title="#0000ff80` + "\n" + `rgb(0 0 255 / 0.5)` + "\n" + `hsl(240 100% 50% / 0.5)"><span class="swatch" style="background-color: #0000ff80"></span>` +
+				`<span class="tok-function cat-name">rgb(</span><span class="tok-number cat-literal">0</span> <span class="tok-number cat-literal">0</span> <span class="tok-number cat-literal">255</span> <span class="tok-delim cat-punctuation">/</span> <span class="tok-percentage cat-literal">50%</span><span class="tok-right-parenthesis cat-punctuation">)</span></span>`},
+		{"b: linear-gradient(#fff, x)", Options{Colors: true, DeclarationList: true},
+			`<span class="tok-ident cat-name">b</span><span class="tok-colon cat-punctuation">:</span> <span class="tok-function cat-name">linear-gradient(</span>` +
+				`<span class="color" title="#ffffff` + "\n" + `rgb(255 255 255)` + "\n" + `hsl(0 0% 100%)"><span class="swatch" style="background-color: #ffffff"></span><span class="tok-hash cat-name">#fff</span></span>` +
+				`<span class="tok-comma cat-punctuation">,</span> <span class="tok-ident cat-name">x</span><span class="tok-right-parenthesis cat-punctuation">)</span>`},
+		{"red{b:c}", Options{Colors: true},
+			`<span class="tok-ident cat-name">red</span><span class="tok-left-brace cat-punctuation">{</span><span class="tok-ident cat-name">b</span><span class="tok-colon cat-punctuation">:</span><span class="tok-ident cat-name">c</span><span class="tok-right-brace cat-punctuation">}</span>`},
+	}
+
+	for _, v := range values {
+		if output := String(v.css, v.opts); output != v.output {
+			t.Fatalf("%s (expected) %q != %q (actual)", v.css, v.output, output)
+		}
+	}
+}
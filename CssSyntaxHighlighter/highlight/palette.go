@@ -0,0 +1,77 @@
+package highlight
+
+import (
+	"fmt"
+
+	"github.com/QuickOrBeDead/GoLangLearning/lexer"
+)
+
+type Color struct {
+	R, G, B uint8
+}
+
+func (c Color) Hex() string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// Palette holds the colors of a theme for the renderers that cannot use the
+// theme stylesheets. Tokens without a color use the color of their category
+// and then Foreground.
+type Palette struct {
+	Background, Foreground Color
+	Categories             map[lexer.Category]Color
+	Tokens                 map[lexer.TokenType]Color
+}
+
+// Color returns the color of a token type.
+func (p Palette) Color(t lexer.TokenType) Color {
+	if c, ok := p.Tokens[t]; ok {
+		return c
+	}
+
+	if c, ok := p.Categories[t.Category()]; ok {
+		return c
+	}
+
+	return p.Foreground
+}
+
+// tokenGroups are the token types that the themes color apart from their
+// category. The colors passed to newPalette are those of the categories in the
+// order of lexer.Categories followed by those of the groups.
+var tokenGroups = [][]lexer.TokenType{
+	{lexer.UrlToken, lexer.LeftParenthesisToken, lexer.RightParenthesisToken},
+	{lexer.AtKeywordToken, lexer.AtToken, lexer.CDOToken, lexer.CDCToken, lexer.VariableToken},
+	{lexer.HashToken},
+	{lexer.NumberToken, lexer.DimensionToken, lexer.PercentageToken},
+	{lexer.LeftBraceToken, lexer.RightBraceToken, lexer.LeftBracketToken, lexer.RightBracketToken},
+}
+
+func newPalette(background, foreground Color, colors ...Color) Palette {
+	p := Palette{Background: background, Foreground: foreground, Categories: make(map[lexer.Category]Color), Tokens: make(map[lexer.TokenType]Color)}
+	categories := lexer.Categories()
+	for i, c := range categories {
+		p.Categories[c] = colors[i]
+	}
+
+	for i, group := range tokenGroups {
+		for _, t := range group {
+			p.Tokens[t] = colors[len(categories)+i]
+		}
+	}
+
+	return p
+}
+
+// Palettes has the colors of the theme stylesheets by theme name.
+var Palettes = map[string]Palette{
+	"dark": newPalette(Color{0x1e, 0x1e, 0x1e}, Color{0xd4, 0xd4, 0xd4},
+		Color{0xce, 0x91, 0x78}, Color{0xdc, 0xdc, 0xaa}, Color{0x9c, 0xdc, 0xfe}, Color{0x6a, 0x99, 0x55}, Color{0xf4, 0x47, 0x47},
+		Color{0x9c, 0xdc, 0xfe}, Color{0xc5, 0x86, 0xc0}, Color{0xf4, 0x87, 0x71}, Color{0xb5, 0xce, 0xa8}, Color{0xff, 0xa6, 0x57}),
+	"light": newPalette(Color{0xff, 0xff, 0xff}, Color{0x24, 0x29, 0x2f},
+		Color{0x0a, 0x30, 0x69}, Color{0x57, 0x60, 0x6a}, Color{0x05, 0x50, 0xae}, Color{0x6e, 0x77, 0x81}, Color{0x82, 0x07, 0x1e},
+		Color{0x05, 0x50, 0xae}, Color{0x82, 0x50, 0xdf}, Color{0xcf, 0x22, 0x2e}, Color{0x11, 0x63, 0x29}, Color{0x95, 0x38, 0x00}),
+	"high-contrast": newPalette(Color{0x00, 0x00, 0x00}, Color{0xff, 0xff, 0xff},
+		Color{0x80, 0xff, 0x80}, Color{0xff, 0xff, 0xff}, Color{0x00, 0xff, 0xff}, Color{0xc0, 0xc0, 0xc0}, Color{0xff, 0xff, 0x00},
+		Color{0x00, 0xff, 0xff}, Color{0xff, 0x80, 0xff}, Color{0xff, 0x80, 0x80}, Color{0xff, 0xff, 0x00}, Color{0xff, 0xff, 0xff}),
+}
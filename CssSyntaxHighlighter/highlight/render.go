@@ -0,0 +1,282 @@
+package highlight
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+
+	"github.com/QuickOrBeDead/GoLangLearning/lexer"
+)
+
+// Renderer writes the tokens of a lexer in some highlighted form.
+type Renderer interface {
+	Render(w io.Writer, lex *lexer.Lexer) error
+}
+
+// HTMLRenderer writes the token spans of HTML. Standalone wraps them in a
+// document with the stylesheet of Theme.
+type HTMLRenderer struct {
+	Theme      string
+	Standalone bool
+}
+
+func (r HTMLRenderer) Render(w io.Writer, lex *lexer.Lexer) error {
+	bw := bufio.NewWriter(w)
+	if r.Standalone {
+		if err := HTMLHeader(bw, r.Theme); err != nil {
+			return err
+		}
+		bw.WriteString("<pre class=\"highlight\">")
+	}
+
+	for v := lex.NextToken(); v.Type != lexer.EOF; v = lex.NextToken() {
+		writeSpan(bw, v)
+	}
+
+	if r.Standalone {
+		bw.WriteString("</pre>\n")
+		HTMLFooter(bw)
+	}
+
+	if err := lex.Err(); err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+// HTMLHeader writes the start of an HTML document with the stylesheet of
+// theme, up to the opening body tag. The highlighted tokens go in <pre
+// class="highlight"> elements after it.
+func HTMLHeader(w io.Writer, theme string) error {
+	css, ok := Theme(theme)
+	if !ok {
+		return fmt.Errorf("unknown theme %q", theme)
+	}
+
+	io.WriteString(w, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<style>\n")
+	w.Write(css)
+	_, err := io.WriteString(w, "</style>\n</head>\n<body>\n")
+	return err
+}
+
+// HTMLFooter writes the end of a document started by HTMLHeader.
+func HTMLFooter(w io.Writer) error {
+	_, err := io.WriteString(w, "</body>\n</html>\n")
+	return err
+}
+
+// ANSIRenderer colors tokens with the escapes of 256-color terminals, or with
+// 24-bit colors when TrueColor is set.
+type ANSIRenderer struct {
+	Palette   Palette
+	TrueColor bool
+}
+
+func (r ANSIRenderer) Render(w io.Writer, lex *lexer.Lexer) error {
+	bw := bufio.NewWriter(w)
+	for v := lex.NextToken(); v.Type != lexer.EOF; v = lex.NextToken() {
+		if v.Type == lexer.WhitespaceToken {
+			bw.WriteString(string(v.Val))
+			continue
+		}
+
+		c := r.Palette.Color(v.Type)
+		if r.TrueColor {
+			fmt.Fprintf(bw, "\x1b[38;2;%d;%d;%dm", c.R, c.G, c.B)
+		} else {
+			fmt.Fprintf(bw, "\x1b[38;5;%dm", ansi256(c))
+		}
+		bw.WriteString(string(v.Val))
+		bw.WriteString("\x1b[0m")
+	}
+
+	if err := lex.Err(); err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+// ansi256 returns the closest color of the 6x6x6 cube or the gray ramp of
+// the 256-color palette.
+func ansi256(c Color) int {
+	if c.R == c.G && c.G == c.B {
+		switch {
+		case c.R < 8:
+			return 16
+		case c.R > 248:
+			return 231
+		case c.R >= 238:
+			return 255
+		default:
+			return 232 + (int(c.R)-8)/10
+		}
+	}
+
+	cube := func(v uint8) int {
+		if v < 48 {
+			return 0
+		}
+		if v < 115 {
+			return 1
+		}
+		return (int(v) - 35) / 40
+	}
+
+	return 16 + 36*cube(c.R) + 6*cube(c.G) + cube(c.B)
+}
+
+// SVGRenderer writes a standalone SVG image of the highlighted text in a
+// monospace font.
+type SVGRenderer struct {
+	Palette  Palette
+	FontSize int
+}
+
+const (
+	svgPadding  = 10
+	svgTabWidth = 4
+)
+
+func (r SVGRenderer) Render(w io.Writer, lex *lexer.Lexer) error {
+	fontSize := r.FontSize
+	if fontSize <= 0 {
+		fontSize = 14
+	}
+	charWidth, lineHeight := float64(fontSize)*0.6, fontSize*4/3
+
+	// Tokens can span lines, so they are split into the lines first to know
+	// the size of the image.
+	type piece struct {
+		text  string
+		color Color
+		plain bool
+	}
+	lines := [][]piece{{}}
+	widths := []int{0}
+	for v := lex.NextToken(); v.Type != lexer.EOF; v = lex.NextToken() {
+		for i, text := range strings.Split(string(v.Val), "\n") {
+			if i > 0 {
+				lines = append(lines, []piece{})
+				widths = append(widths, 0)
+			}
+
+			if text == "" {
+				continue
+			}
+
+			text = strings.ReplaceAll(text, "\t", strings.Repeat(" ", svgTabWidth))
+			n := len(lines) - 1
+			lines[n] = append(lines[n], piece{text, r.Palette.Color(v.Type), v.Type == lexer.WhitespaceToken})
+			widths[n] += len([]rune(text))
+		}
+	}
+
+	if err := lex.Err(); err != nil {
+		return err
+	}
+
+	for len(lines) > 1 && len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+
+	columns := 0
+	for _, width := range widths {
+		if width > columns {
+			columns = width
+		}
+	}
+	width := int(float64(columns)*charWidth) + 2*svgPadding
+	height := len(lines)*lineHeight + 2*svgPadding
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n", width, height, width, height)
+	fmt.Fprintf(bw, `<rect width="100%%" height="100%%" fill="%s"/>`+"\n", r.Palette.Background.Hex())
+	fmt.Fprintf(bw, `<text font-family="monospace" font-size="%d" fill="%s" xml:space="preserve">`+"\n", fontSize, r.Palette.Foreground.Hex())
+	for i, line := range lines {
+		fmt.Fprintf(bw, `<tspan x="%d" y="%d">`, svgPadding, svgPadding+(i+1)*lineHeight-lineHeight/4)
+		for _, p := range line {
+			if p.plain {
+				bw.WriteString(html.EscapeString(p.text))
+				continue
+			}
+
+			fmt.Fprintf(bw, `<tspan fill="%s">%s</tspan>`, p.color.Hex(), html.EscapeString(p.text))
+		}
+		bw.WriteString("</tspan>\n")
+	}
+	bw.WriteString("</text>\n</svg>\n")
+
+	return bw.Flush()
+}
+
+// JSONToken is the JSON form of a token. Value is the source text of the
+// token and Start and End are rune offsets. File is the name of the input
+// when there are several.
+type JSONToken struct {
+	File     string `json:"file,omitempty"`
+	Type     string `json:"type"`
+	Category string `json:"category"`
+	Value    string `json:"value"`
+	Start    int    `json:"start"`
+	End      int    `json:"end"`
+	Line     int    `json:"line"`
+	Col      int    `json:"col"`
+}
+
+func NewJSONToken(t lexer.Token) JSONToken {
+	return JSONToken{Type: t.Type.String(), Category: t.Type.Category().String(), Value: string(t.Val), Start: t.Start, End: t.End, Line: t.Line, Col: t.Col}
+}
+
+// JSONRenderer writes the tokens as a JSON array of JSONToken objects, with
+// File as their file when it is set.
+type JSONRenderer struct {
+	Indent bool
+	File   string
+}
+
+func (r JSONRenderer) Render(w io.Writer, lex *lexer.Lexer) error {
+	tokens := []JSONToken{}
+	for v := lex.NextToken(); v.Type != lexer.EOF; v = lex.NextToken() {
+		t := NewJSONToken(v)
+		t.File = r.File
+		tokens = append(tokens, t)
+	}
+
+	if err := lex.Err(); err != nil {
+		return err
+	}
+
+	e := json.NewEncoder(w)
+	if r.Indent {
+		e.SetIndent("", "  ")
+	}
+
+	return e.Encode(tokens)
+}
+
+// TokensRenderer writes one token per line with its position, type and
+// quoted text. The positions start with File when it is set.
+type TokensRenderer struct {
+	File string
+}
+
+func (r TokensRenderer) Render(w io.Writer, lex *lexer.Lexer) error {
+	bw := bufio.NewWriter(w)
+	for v := lex.NextToken(); v.Type != lexer.EOF; v = lex.NextToken() {
+		if r.File != "" {
+			bw.WriteString(r.File + ":")
+		}
+		fmt.Fprintf(bw, "%d:%d\t%s\t%q\n", v.Line, v.Col, v.Type, string(v.Val))
+	}
+
+	if err := lex.Err(); err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
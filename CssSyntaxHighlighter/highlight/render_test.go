@@ -0,0 +1,99 @@
+package highlight
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/QuickOrBeDead/GoLangLearning/lexer"
+)
+
+func render(r Renderer, css string) string {
+	var sb strings.Builder
+	if err := r.Render(&sb, &lexer.Lexer{Text: []rune(css)}); err != nil {
+		return "error: " + err.Error()
+	}
+
+	return sb.String()
+}
+
+func TestRenderers(t *testing.T) {
+	palette := Palettes["dark"]
+	values := []struct {
+		renderer Renderer
+		css      string
+		output   string
+	}{
+		{HTMLRenderer{}, "a<b", `<span class="tok-ident cat-name">a</span><span class="tok-delim cat-punctuation">&lt;</span><span class="tok-ident cat-name">b</span>`},
+		{ANSIRenderer{Palette: palette}, "a {", "\x1b[38;5;153ma\x1b[0m \x1b[38;5;215m{\x1b[0m"},
+		{ANSIRenderer{Palette: palette, TrueColor: true}, "#fff", "\x1b[38;2;244;135;113m#fff\x1b[0m"},
+		{JSONRenderer{}, "a 1", `[{"type":"Ident","category":"name","value":"a","start":0,"end":1,"line":1,"col":1},{"type":"Whitespace","category":"trivia","value":" ","start":1,"end":2,"line":1,"col":2},{"type":"Number","category":"literal","value":"1","start":2,"end":3,"line":1,"col":3}]` + "\n"},
+		{JSONRenderer{}, "", "[]\n"},
+		{TokensRenderer{}, "a\n\"<\"", "1:1\tIdent\t\"a\"\n1:2\tWhitespace\t\"\\n\"\n2:1\tString\t\"\\\"<\\\"\"\n"},
+		{TokensRenderer{File: "a.css"}, "a", "a.css:1:1\tIdent\t\"a\"\n"},
+		{JSONRenderer{File: "a.css"}, "a", `[{"file":"a.css","type":"Ident","category":"name","value":"a","start":0,"end":1,"line":1,"col":1}]` + "\n"},
+		{SVGRenderer{Palette: palette, FontSize: 10}, "a{\n\t}\n",
+			`<svg xmlns="http://www.w3.org/2000/svg" width="50" height="46" viewBox="0 0 50 46">` + "\n" +
+				`<rect width="100%" height="100%" fill="#1e1e1e"/>` + "\n" +
+				`<text font-family="monospace" font-size="10" fill="#d4d4d4" xml:space="preserve">` + "\n" +
+				`<tspan x="10" y="20"><tspan fill="#9cdcfe">a</tspan><tspan fill="#ffa657">{</tspan></tspan>` + "\n" +
+				`<tspan x="10" y="33">    <tspan fill="#ffa657">}</tspan></tspan>` + "\n" +
+				"</text>\n</svg>\n"},
+	}
+
+	for _, v := range values {
+		if output := render(v.renderer, v.css); output != v.output {
+			t.Fatalf("%T %q (expected) %q != %q (actual)", v.renderer, v.css, v.output, output)
+		}
+	}
+}
+
+func TestHTMLRendererStandalone(t *testing.T) {
+	output := render(HTMLRenderer{Theme: "dark", Standalone: true}, "a")
+	if !strings.HasPrefix(output, "<!DOCTYPE html>") || !strings.Contains(output, ".highlight .cat-name") || !strings.Contains(output, `<pre class="highlight"><span class="tok-ident cat-name">a</span></pre>`) {
+		t.Fatalf("unexpected document %q", output)
+	}
+
+	if output := render(HTMLRenderer{Theme: "missing", Standalone: true}, "a"); !strings.HasPrefix(output, "error: ") {
+		t.Fatalf("expected an error for a missing theme, got %q", output)
+	}
+}
+
+func TestANSI256(t *testing.T) {
+	values := []struct {
+		color Color
+		index int
+	}{
+		{Color{0, 0, 0}, 16},
+		{Color{255, 255, 255}, 231},
+		{Color{255, 0, 0}, 196},
+		{Color{0x80, 0x80, 0x80}, 244},
+		{Color{0x1e, 0x1e, 0x1e}, 234},
+	}
+
+	for _, v := range values {
+		if i := ansi256(v.color); i != v.index {
+			t.Fatalf("%v (expected) %d != %d (actual)", v.color, v.index, i)
+		}
+	}
+}
+
+func TestPalettesMatchThemes(t *testing.T) {
+	for _, name := range Themes() {
+		css, _ := Theme(name)
+		p, ok := Palettes[name]
+		if !ok {
+			t.Fatalf("missing palette for theme %s", name)
+		}
+
+		colors := []Color{p.Background}
+		for _, tokenType := range lexer.TokenTypes() {
+			colors = append(colors, p.Color(tokenType))
+		}
+
+		for _, c := range colors {
+			if !strings.Contains(string(css), c.Hex()) {
+				t.Fatalf("theme %s does not use color %s", name, c.Hex())
+			}
+		}
+	}
+}
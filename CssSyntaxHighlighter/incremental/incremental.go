@@ -0,0 +1,166 @@
+package incremental
+
+import (
+	"fmt"
+
+	"github.com/QuickOrBeDead/GoLangLearning/lexer"
+)
+
+// lookahead is the number of runes after the end of a token the lexer may
+// look at to decide where the token ends, like the "+5" of "1e+5".
+const lookahead = 3
+
+// Edit replaces Delete runes at Offset with Insert. Offsets count runes, not
+// bytes.
+type Edit struct {
+	Offset int    `json:"offset"`
+	Delete int    `json:"delete"`
+	Insert string `json:"insert"`
+}
+
+// Change is the effect of an edit on the token list: Delete tokens starting
+// at From are replaced with Tokens. The tokens after them keep their types
+// and text, only their positions move.
+type Change struct {
+	From, Delete int
+	Tokens       []lexer.Token
+}
+
+// Document is a text and its tokens, which are kept up to date as the text
+// is edited by lexing only the part around each edit again.
+type Document struct {
+	text    []rune
+	tokens  []lexer.Token
+	dialect lexer.Dialect
+}
+
+func New(text string) *Document {
+	return NewWithDialect(text, lexer.CSS)
+}
+
+// NewWithDialect returns a document whose text is lexed in dialect.
+func NewWithDialect(text string, dialect lexer.Dialect) *Document {
+	d := &Document{text: []rune(text), dialect: dialect}
+	l := lexer.Lexer{Text: d.text, Dialect: dialect}
+	for t := l.NextToken(); t.Type != lexer.EOF; t = l.NextToken() {
+		d.tokens = append(d.tokens, t)
+	}
+
+	return d
+}
+
+func (d *Document) Text() string {
+	return string(d.text)
+}
+
+// Tokens returns the tokens of the text without the EOF token.
+func (d *Document) Tokens() []lexer.Token {
+	return d.tokens
+}
+
+// Apply edits the text and lexes it again from the last token the edit can
+// change, until the new tokens line up with the old ones after the edit.
+func (d *Document) Apply(e Edit) (Change, error) {
+	if e.Offset < 0 || e.Delete < 0 || e.Offset+e.Delete > len(d.text) {
+		return Change{}, fmt.Errorf("edit %d+%d is out of range of the text of length %d", e.Offset, e.Delete, len(d.text))
+	}
+
+	insert := []rune(e.Insert)
+	text := make([]rune, 0, len(d.text)-e.Delete+len(insert))
+	text = append(text, d.text[:e.Offset]...)
+	text = append(text, insert...)
+	text = append(text, d.text[e.Offset+e.Delete:]...)
+
+	oldEnd, newEnd := e.Offset+e.Delete, e.Offset+len(insert)
+	delta := newEnd - oldEnd
+
+	from := d.restartIndex(e.Offset)
+	state := lexer.State{Line: 1, Col: 1}
+	if from < len(d.tokens) {
+		t := d.tokens[from]
+		state = lexer.State{Offset: t.Start, Line: t.Line, Col: t.Col, AfterCR: t.Start > 0 && text[t.Start-1] == '\r'}
+	}
+
+	l := lexer.Lexer{Text: text, Dialect: d.dialect}
+	l.Resume(state)
+
+	// to is the first old token that may line up with the new tokens.
+	to := from
+	tokens := []lexer.Token{}
+	for {
+		next := l.State()
+		if next.Offset >= newEnd {
+			for to < len(d.tokens) && (d.tokens[to].Start < oldEnd || d.tokens[to].Start+delta < next.Offset) {
+				to++
+			}
+
+			if to < len(d.tokens) && d.tokens[to].Start+delta == next.Offset {
+				d.shift(to, delta, next)
+				break
+			}
+		}
+
+		t := l.NextToken()
+		if t.Type == lexer.EOF {
+			to = len(d.tokens)
+			break
+		}
+		tokens = append(tokens, t)
+	}
+
+	c := Change{From: from, Delete: to - from, Tokens: tokens}
+	rest := d.tokens[to:]
+	d.tokens = append(append(append(make([]lexer.Token, 0, from+len(tokens)+len(rest)), d.tokens[:from]...), tokens...), rest...)
+	d.text = text
+
+	return c, nil
+}
+
+// restartIndex returns the index of the first token that an edit at offset
+// can change.
+func (d *Document) restartIndex(offset int) int {
+	i := 0
+	for i < len(d.tokens) && d.tokens[i].End+lookahead <= offset {
+		i++
+	}
+
+	// The whitespace after "url(" is a token of its own only when a quote
+	// follows it, so it depends on what comes after.
+	if i > 0 && i < len(d.tokens) && d.tokens[i].Type == lexer.WhitespaceToken && isURLFunction(d.tokens[i-1]) {
+		i--
+	}
+
+	// In Less an at-rule name is a variable when a colon follows it, so it
+	// depends on what comes after the whitespace after it.
+	if d.dialect == lexer.Less && i > 0 && i < len(d.tokens) && d.tokens[i].Type == lexer.WhitespaceToken &&
+		(d.tokens[i-1].Type == lexer.AtKeywordToken || d.tokens[i-1].Type == lexer.VariableToken) {
+		i--
+	}
+
+	if i == len(d.tokens) && i > 0 {
+		i--
+	}
+
+	return i
+}
+
+func isURLFunction(t lexer.Token) bool {
+	v := string(t.Value)
+	return t.Type == lexer.FunctionToken && len(v) == 3 && (v[0]|0x20) == 'u' && (v[1]|0x20) == 'r' && (v[2]|0x20) == 'l'
+}
+
+// shift moves the tokens from index i on by delta runes, where the token at
+// i now starts at the position of s.
+func (d *Document) shift(i int, delta int, s lexer.State) {
+	first := d.tokens[i]
+	lines, cols := s.Line-first.Line, s.Col-first.Col
+	for j := i; j < len(d.tokens); j++ {
+		t := &d.tokens[j]
+		if t.Line == first.Line {
+			t.Col += cols
+		}
+		t.Line += lines
+		t.Start += delta
+		t.End += delta
+	}
+}
@@ -0,0 +1,130 @@
+package incremental
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/QuickOrBeDead/GoLangLearning/lexer"
+)
+
+func checkTokens(t *testing.T, d *Document, step string) {
+	l := lexer.Lexer{Text: []rune(d.Text()), Dialect: d.dialect}
+	i := 0
+	for v := l.NextToken(); v.Type != lexer.EOF; v = l.NextToken() {
+		if i >= len(d.tokens) {
+			t.Fatalf("%s: missing token %d %+v in %q", step, i, v, d.Text())
+		}
+
+		o := d.tokens[i]
+		if o.Type != v.Type || string(o.Val) != string(v.Val) || o.Start != v.Start || o.End != v.End || o.Line != v.Line || o.Col != v.Col {
+			t.Fatalf("%s: %d. token (expected) %+v != %+v (actual) in %q", step, i, v, o, d.Text())
+		}
+		i++
+	}
+
+	if i != len(d.tokens) {
+		t.Fatalf("%s: len(tokens) (expected) %d != %d (actual) in %q", step, i, len(d.tokens), d.Text())
+	}
+}
+
+func TestApply(t *testing.T) {
+	values := []struct {
+		text   string
+		edit   Edit
+		output string
+		from   int
+		delete int
+		tokens int
+	}{
+		{"a { b: c }", Edit{1, 0, "bc"}, "abc { b: c }", 0, 1, 1},
+		{"a { b: c }\nd { e: f }", Edit{7, 1, "red"}, "a { b: red }\nd { e: f }", 4, 4, 4},
+		{"a { b: c }", Edit{0, 0, "\n\n"}, "\n\na { b: c }", 0, 0, 1},
+		{"a { b: 1 }", Edit{8, 0, "e+5"}, "a { b: 1e+5 }", 5, 3, 3},
+		{"a { b: c } /* x */", Edit{11, 0, "\"/*"}, "a { b: c } \"/*/* x */", 8, 4, 4},
+		{"a { b: url( x) }", Edit{12, 0, "'"}, "a { b: url( 'x) }", 7, 3, 3},
+		{"a { b: url(     'x') }", Edit{16, 1, ""}, "a { b: url(     x') }", 7, 4, 1},
+		{"", Edit{0, 0, "a"}, "a", 0, 0, 1},
+		{"a b", Edit{0, 3, ""}, "", 0, 3, 0},
+	}
+
+	for _, v := range values {
+		d := New(v.text)
+		c, err := d.Apply(v.edit)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if d.Text() != v.output {
+			t.Fatalf("%q %+v text (expected) %q != %q (actual)", v.text, v.edit, v.output, d.Text())
+		}
+
+		if c.From != v.from || c.Delete != v.delete || len(c.Tokens) != v.tokens {
+			t.Fatalf("%q %+v change (expected) %d %d %d != %d %d %d (actual)", v.text, v.edit, v.from, v.delete, v.tokens, c.From, c.Delete, len(c.Tokens))
+		}
+
+		checkTokens(t, d, v.text)
+	}
+
+	if _, err := New("ab").Apply(Edit{1, 2, ""}); err == nil {
+		t.Fatal("expected an error for an edit out of range")
+	}
+}
+
+func TestApplyDialects(t *testing.T) {
+	values := []struct {
+		dialect lexer.Dialect
+		text    string
+		edit    Edit
+		output  string
+	}{
+		{lexer.SCSS, "a { b: c }", Edit{7, 0, "$"}, "a { b: $c }"},
+		{lexer.SCSS, "a / b", Edit{3, 0, "/"}, "a // b"},
+		{lexer.Less, "@media     x", Edit{11, 0, ":"}, "@media     :x"},
+		{lexer.Less, "@a     : x", Edit{7, 1, ""}, "@a      x"},
+	}
+
+	for _, v := range values {
+		d := NewWithDialect(v.text, v.dialect)
+		if _, err := d.Apply(v.edit); err != nil {
+			t.Fatal(err)
+		}
+
+		if d.Text() != v.output {
+			t.Fatalf("%q %+v text (expected) %q != %q (actual)", v.text, v.edit, v.output, d.Text())
+		}
+
+		checkTokens(t, d, v.dialect.String()+" "+v.text)
+	}
+}
+
+func TestApplyRandomEdits(t *testing.T) {
+	fragments := []string{"a", "-", "1", ".", "e", "+", " ", "\n", "\r\n", "\"", "'", "\\", "/*", "*/", "{", "}", "(", ")", ":", ";", "url(", "#", "@", "<!--", "-->", "é", "%", "//", "$", "&", "media"}
+	for _, dialect := range []lexer.Dialect{lexer.CSS, lexer.SCSS, lexer.Less} {
+		r := rand.New(rand.NewSource(1))
+		d := NewWithDialect("@media print {\n  a.b, #c > d::before { color: #fff; width: calc(100% - 1e3px); background: url( x.png ) }\n}\n/* end */", dialect)
+		for i := 0; i < 5000; i++ {
+			n := len(d.text)
+			e := Edit{Offset: r.Intn(n + 1)}
+			if e.Offset < n && r.Intn(3) == 0 {
+				e.Delete = r.Intn(min(n-e.Offset, 4) + 1)
+			}
+			if r.Intn(4) != 0 {
+				e.Insert = fragments[r.Intn(len(fragments))]
+			}
+
+			if _, err := d.Apply(e); err != nil {
+				t.Fatal(err)
+			}
+
+			checkTokens(t, d, dialect.String()+" random edit")
+		}
+	}
+}
+
+func min(a int, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,66 @@
+package lexer
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// benchmarkStylesheets returns the stylesheets of testdata and a generated
+// one in the style of a hand-written stylesheet. font-awesome.css and
+// normalize.css are MIT licensed, see their headers.
+func benchmarkStylesheets(b *testing.B) map[string][]byte {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.css"))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	sheets := make(map[string][]byte)
+	for _, file := range files {
+		css, err := os.ReadFile(file)
+		if err != nil {
+			b.Fatal(err)
+		}
+		sheets[strings.TrimSuffix(filepath.Base(file), ".css")] = css
+	}
+
+	var sb strings.Builder
+	for i := 0; i < 2000; i++ {
+		fmt.Fprintf(&sb, "/* section %d */\n.nav-%d > li:nth-child(2n+1) a[href^=\"https://\"],\n#main .item-%d::before {\n", i, i, i)
+		fmt.Fprintf(&sb, "    margin: 0 %dpx -1.5em;\n    color: #%06x;\n    background: url(images/bg-%d.png) no-repeat, linear-gradient(45deg, rgba(0, 0, 0, .5) 0%%, transparent 100%%);\n", i%16, i*2654435761%0xffffff, i)
+		fmt.Fprintf(&sb, "    font: italic 400 1.2rem/1.5 \"Helvetica Neue\", sans-serif !important;\n}\n\n@media (min-width: %dpx) {\n    .col-%d { width: calc(100%% / %d - 2px); }\n}\n\n", 320+i, i, i%12+1)
+	}
+	sheets["generated"] = []byte(sb.String())
+
+	return sheets
+}
+
+func BenchmarkNextToken(b *testing.B) {
+	for name, css := range benchmarkStylesheets(b) {
+		text := []rune(string(css))
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(int64(len(css)))
+			for i := 0; i < b.N; i++ {
+				l := Lexer{Text: text}
+				for l.NextToken().Type != EOF {
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkNewLexer(b *testing.B) {
+	for name, css := range benchmarkStylesheets(b) {
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(int64(len(css)))
+			for i := 0; i < b.N; i++ {
+				l := NewLexer(bytes.NewReader(css))
+				for l.NextToken().Type != EOF {
+				}
+			}
+		})
+	}
+}
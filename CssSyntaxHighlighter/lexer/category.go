@@ -0,0 +1,102 @@
+package lexer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Category groups token types that look alike, so a theme or a tool can
+// treat a new token type like the others of its category.
+type Category uint8
+
+const (
+	// NoCategory is the category of EOF.
+	NoCategory Category = iota
+	LiteralCategory
+	PunctuationCategory
+	NameCategory
+	TriviaCategory
+	ErrorCategory
+)
+
+var categoryNames = [...]string{"", "literal", "punctuation", "name", "trivia", "error"}
+
+// Categories returns the categories of the token types other than EOF.
+func Categories() []Category {
+	return []Category{LiteralCategory, PunctuationCategory, NameCategory, TriviaCategory, ErrorCategory}
+}
+
+func (c Category) String() string {
+	if int(c) < len(categoryNames) {
+		return categoryNames[c]
+	}
+
+	return ""
+}
+
+// ParseCategory returns the category with the name String returns.
+func ParseCategory(s string) (Category, error) {
+	for _, c := range Categories() {
+		if strings.EqualFold(s, c.String()) {
+			return c, nil
+		}
+	}
+
+	return NoCategory, fmt.Errorf("unknown token category %q", s)
+}
+
+type tokenTypeInfo struct {
+	name     string
+	category Category
+	bracket  bool
+}
+
+func (t TokenType) String() string {
+	if int(t) < len(tokenTypes) {
+		return tokenTypes[t].name
+	}
+
+	return ""
+}
+
+func (t TokenType) Category() Category {
+	if int(t) < len(tokenTypes) {
+		return tokenTypes[t].category
+	}
+
+	return NoCategory
+}
+
+// IsTrivia reports whether the token is whitespace or a comment.
+func (t TokenType) IsTrivia() bool {
+	return t.Category() == TriviaCategory
+}
+
+// IsBracket reports whether the token opens or closes a block: a
+// parenthesis, a brace or a square bracket. Function tokens are not
+// brackets even though they end with a parenthesis.
+func (t TokenType) IsBracket() bool {
+	return int(t) < len(tokenTypes) && tokenTypes[t].bracket
+}
+
+// TokenTypes returns every token type, EOF last.
+func TokenTypes() []TokenType {
+	types := make([]TokenType, len(tokenTypes))
+	for i := range types {
+		types[i] = TokenType(i)
+	}
+
+	return types
+}
+
+// ParseTokenType returns the token type with the name String returns, like
+// "Ident" or "BadUrl". The case of s is ignored.
+func ParseTokenType(s string) (TokenType, error) {
+	for i, info := range tokenTypes {
+		if strings.EqualFold(s, info.name) {
+			return TokenType(i), nil
+		}
+	}
+
+	return ErrorToken, fmt.Errorf("unknown token type %q", s)
+}
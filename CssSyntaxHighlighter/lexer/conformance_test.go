@@ -0,0 +1,174 @@
+package lexer_test
+
+//go:generate sh -c "mkdir -p testdata/css-parsing-tests && cd testdata/css-parsing-tests && curl -fsSLO https://raw.githubusercontent.com/SimonSapin/css-parsing-tests/master/component_value_list.json && curl -fsSLO https://raw.githubusercontent.com/SimonSapin/css-parsing-tests/master/README.rst"
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/QuickOrBeDead/GoLangLearning/lexer"
+	"github.com/QuickOrBeDead/GoLangLearning/parser"
+)
+
+// TestComponentValueList runs our own cases in the format of the
+// css-parsing-tests corpus, see testdata/README.md.
+func TestComponentValueList(t *testing.T) {
+	runComponentValueList(t, filepath.Join("testdata", "component_value_list.json"))
+}
+
+// TestCSSParsingTests runs component_value_list.json of the upstream corpus,
+// which go generate downloads into testdata/css-parsing-tests.
+// https://github.com/SimonSapin/css-parsing-tests
+func TestCSSParsingTests(t *testing.T) {
+	file := filepath.Join("testdata", "css-parsing-tests", "component_value_list.json")
+	if _, err := os.Stat(file); errors.Is(err, fs.ErrNotExist) {
+		t.Skip(file + " is missing, run go generate ./lexer to download it")
+	}
+
+	runComponentValueList(t, file)
+}
+
+// runComponentValueList lexes each input of file with the streaming lexer,
+// parses it into component values and compares them with the result of the
+// case.
+func runComponentValueList(t *testing.T, file string) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cases []interface{}
+	if err := json.Unmarshal(b, &cases); err != nil {
+		t.Fatalf("%s: %v", file, err)
+	}
+
+	if len(cases)%2 != 0 {
+		t.Fatalf("%s: expected pairs of input and result, got %d values", file, len(cases))
+	}
+
+	for i := 0; i < len(cases); i += 2 {
+		css, ok := cases[i].(string)
+		if !ok {
+			t.Fatalf("%s: %d. input is not a string", file, i/2)
+		}
+
+		p := parser.New(lexer.NewLexer(strings.NewReader(css)))
+		actual := conformanceValues(p.ParseComponentValueList())
+		if !conformanceEqual(cases[i+1], actual) {
+			e, _ := json.Marshal(cases[i+1])
+			a, _ := json.Marshal(actual)
+			t.Errorf("%s: %q (expected) %s != %s (actual)", file, css, e, a)
+		}
+	}
+}
+
+var numberRepr = regexp.MustCompile(`^[+-]?[0-9]*\.?[0-9]+([eE][+-]?[0-9]+)?`)
+
+// conformanceValues converts component values to the JSON representation of
+// css-parsing-tests. Comments are dropped.
+func conformanceValues(values []parser.ComponentValue) []interface{} {
+	list := []interface{}{}
+	for _, v := range values {
+		if t, ok := v.(*parser.Token); ok && t.Type == lexer.CommentToken {
+			continue
+		}
+
+		list = append(list, conformanceValue(v))
+	}
+
+	return list
+}
+
+func conformanceValue(v parser.ComponentValue) interface{} {
+	switch v := v.(type) {
+	case *parser.Function:
+		return append([]interface{}{"function", v.Name()}, conformanceValues(v.Values)...)
+	case *parser.SimpleBlock:
+		name := "()"
+		switch v.Open.Type {
+		case lexer.LeftBraceToken:
+			name = "{}"
+		case lexer.LeftBracketToken:
+			name = "[]"
+		}
+
+		return append([]interface{}{name}, conformanceValues(v.Values)...)
+	}
+
+	t := v.(*parser.Token).Token
+	switch t.Type {
+	case lexer.WhitespaceToken:
+		return " "
+	case lexer.IdentToken:
+		return []interface{}{"ident", string(t.Value)}
+	case lexer.AtKeywordToken:
+		return []interface{}{"at-keyword", string(t.Value)}
+	case lexer.HashToken:
+		flag := "unrestricted"
+		if t.Flag == lexer.IDFlag {
+			flag = "id"
+		}
+
+		return []interface{}{"hash", string(t.Value), flag}
+	case lexer.StringToken:
+		return []interface{}{"string", string(t.Value)}
+	case lexer.UrlToken:
+		return []interface{}{"url", string(t.Value)}
+	case lexer.BadStringToken:
+		return []interface{}{"error", "bad-string"}
+	case lexer.BadUrlToken:
+		return []interface{}{"error", "bad-url"}
+	case lexer.RightParenthesisToken, lexer.RightBracketToken, lexer.RightBraceToken:
+		return []interface{}{"error", string(t.Val)}
+	case lexer.NumberToken, lexer.PercentageToken, lexer.DimensionToken:
+		flag := "number"
+		if t.Flag == lexer.IntegerFlag {
+			flag = "integer"
+		}
+
+		n := []interface{}{"number", numberRepr.FindString(string(t.Val)), t.Number, flag}
+		switch t.Type {
+		case lexer.PercentageToken:
+			n[0] = "percentage"
+		case lexer.DimensionToken:
+			n[0] = "dimension"
+			n = append(n, string(t.Unit))
+		}
+
+		return n
+	default:
+		return string(t.Val)
+	}
+}
+
+// conformanceEqual compares decoded JSON values, numbers with a relative
+// tolerance.
+func conformanceEqual(expected, actual interface{}) bool {
+	switch e := expected.(type) {
+	case []interface{}:
+		a, ok := actual.([]interface{})
+		if !ok || len(a) != len(e) {
+			return false
+		}
+
+		for i := range e {
+			if !conformanceEqual(e[i], a[i]) {
+				return false
+			}
+		}
+
+		return true
+	case float64:
+		a, ok := actual.(float64)
+		return ok && math.Abs(e-a) <= 1e-9*math.Max(1, math.Abs(e))
+	default:
+		return expected == actual
+	}
+}
@@ -0,0 +1,125 @@
+package lexer
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Dialect is the language of the input. CSS is tokenized as CSS Syntax
+// Level 3 describes, the preprocessor dialects add their own tokens:
+//
+//	SCSS: // comments, $variables, #{} interpolation and & parent selectors
+//	Less: // comments, @variables, @{} interpolation, & parent selectors and
+//	      .mixin() calls
+//
+// Directives like @mixin, @include or @if are at-keywords in every dialect.
+type Dialect uint8
+
+const (
+	CSS Dialect = iota
+	SCSS
+	Less
+)
+
+var dialectNames = [...]string{"css", "scss", "less"}
+
+func (d Dialect) String() string {
+	if int(d) < len(dialectNames) {
+		return dialectNames[d]
+	}
+
+	return ""
+}
+
+// ParseDialect returns the dialect with the name String returns.
+func ParseDialect(s string) (Dialect, error) {
+	for i, name := range dialectNames {
+		if strings.EqualFold(s, name) {
+			return Dialect(i), nil
+		}
+	}
+
+	return CSS, fmt.Errorf("unknown dialect %q", s)
+}
+
+// DialectOf returns the dialect of a file by its extension: SCSS for .scss,
+// Less for .less and CSS for the other files.
+func DialectOf(path string) Dialect {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".scss":
+		return SCSS
+	case ".less":
+		return Less
+	default:
+		return CSS
+	}
+}
+
+// lessAtRules are the at-rules of CSS and Less. Other at-keywords are Less
+// variables.
+var lessAtRules = map[string]bool{
+	"charset": true, "import": true, "namespace": true, "media": true, "supports": true, "document": true,
+	"page": true, "font-face": true, "keyframes": true, "viewport": true, "counter-style": true,
+	"font-feature-values": true, "property": true, "layer": true, "container": true, "scope": true,
+	"starting-style": true, "plugin": true,
+}
+
+// scanDialectToken scans the tokens that the dialect of the lexer adds to
+// CSS. It reports false and consumes nothing when the input does not start
+// with one of them.
+func (lex *Lexer) scanDialectToken() (Token, bool) {
+	r := lex.peek(0)
+	switch {
+	case r == '/' && lex.peek(1) == '/':
+		for r := lex.peek(0); r >= 0 && !isNewline(r); r = lex.peek(0) {
+			lex.next()
+		}
+
+		return Token{Type: CommentToken, Val: lex.shift()}, true
+	case r == '&':
+		lex.next()
+		v := lex.scanName()
+		return Token{Type: ParentSelectorToken, Val: lex.shift(), Value: v}, true
+	case lex.Dialect == SCSS && r == '$' && lex.startsIdent(1):
+		lex.next()
+		v := lex.scanName()
+		return Token{Type: VariableToken, Val: lex.shift(), Value: v}, true
+	case lex.Dialect == SCSS && r == '#' && lex.peek(1) == '{',
+		lex.Dialect == Less && r == '@' && lex.peek(1) == '{':
+		lex.setPos(lex.pos + 2)
+		return Token{Type: InterpolationToken, Val: lex.shift()}, true
+	case lex.Dialect == Less && r == '@' && lex.startsIdent(1):
+		lex.next()
+		v := lex.scanName()
+		if name := strings.ToLower(string(v)); !lex.followedByColon() && (lessAtRules[name] || strings.HasPrefix(name, "-")) {
+			return Token{Type: AtKeywordToken, Val: lex.shift(), Value: v}, true
+		}
+
+		return Token{Type: VariableToken, Val: lex.shift(), Value: v}, true
+	case lex.Dialect == Less && r == '.' && lex.startsIdent(1):
+		start := lex.pos
+		lex.next()
+		v := lex.scanName()
+		if lex.peek(0) != '(' {
+			lex.setPos(start)
+			return Token{}, false
+		}
+
+		lex.next()
+		return Token{Type: MixinToken, Val: lex.shift(), Value: v}, true
+	}
+
+	return Token{}, false
+}
+
+// followedByColon reports whether the next rune other than whitespace is a
+// colon.
+func (lex *Lexer) followedByColon() bool {
+	i := 0
+	for isWhitespace(lex.peek(i)) {
+		i++
+	}
+
+	return lex.peek(i) == ':'
+}
@@ -0,0 +1,108 @@
+package lexer
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+// FuzzNextToken checks that lexing terminates and that the tokens cover the
+// input without gaps in every dialect, so their text adds up to the input. A
+// lexer stuck inside a token is reported by the fuzzer as a hang. The same is
+// checked for a streaming lexer that reads the input in chunks of 1 to 16
+// bytes, against the preprocessed input.
+//
+//	go test ./lexer -fuzz FuzzNextToken
+func FuzzNextToken(f *testing.F) {
+	for _, seed := range []string{
+		"",
+		"a { color: red; }",
+		"@media (min-width: 10px) { .x { width: calc(100% - 2px) } }",
+		"url( a.png ) url(a b) url('c')",
+		"'a\\\nb' \"c\n",
+		"1e3 -.5e-2px +10% 1.",
+		"#-\\31 x \\ @\\",
+		"<!-- --> |= ~= ^= $= *= ||",
+		"/* unclosed",
+		"a\r\nb\fc\x00\xff",
+		"€ 😀 \xe2\x82",
+		"$a: #{&-b} // c",
+		"@a: @{b}; .c(@d) &e",
+	} {
+		for d := range dialectNames {
+			f.Add(seed, uint8(d), uint8(d))
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, css string, dialect uint8, chunk uint8) {
+		d := Dialect(dialect % uint8(len(dialectNames)))
+		checkTokens(t, css, &Lexer{Text: []rune(css), Dialect: d}, []rune(css))
+
+		l := NewLexer(&chunkReader{s: css, n: int(chunk%16) + 1})
+		l.Dialect = d
+		checkTokens(t, css, l, []rune(preprocess.Replace(css)))
+		if err := l.Err(); err != nil {
+			t.Fatalf("%q: %v", css, err)
+		}
+	})
+}
+
+// preprocess does the input preprocessing of a streaming lexer on a string.
+// Invalid UTF-8 becomes U+FFFD as the string is converted to runes.
+var preprocess = strings.NewReplacer("\r\n", "\n", "\r", "\n", "\f", "\n", "\x00", "�")
+
+// checkTokens checks that the tokens of l cover text, the input css as the
+// lexer sees it, without gaps.
+func checkTokens(t *testing.T, css string, l *Lexer, text []rune) {
+	var sb []rune
+	end := 0
+	for i := 0; ; i++ {
+		if i > len(text) {
+			t.Fatalf("%q: more than %d tokens", css, len(text))
+		}
+
+		v := l.NextToken()
+		if v.Start != end {
+			t.Fatalf("%q: %d. token start (expected) %d != %d (actual)", css, i, end, v.Start)
+		}
+
+		if v.Type == EOF {
+			if v.End != len(text) {
+				t.Fatalf("%q: EOF end (expected) %d != %d (actual)", css, len(text), v.End)
+			}
+			break
+		}
+
+		if v.End <= v.Start || v.End-v.Start != len(v.Val) {
+			t.Fatalf("%q: %d. token %v has range %d-%d and %d runes", css, i, v.Type.String(), v.Start, v.End, len(v.Val))
+		}
+
+		sb = append(sb, v.Val...)
+		end = v.End
+	}
+
+	if string(sb) != string(text) {
+		t.Fatalf("tokens (expected) %q != %q (actual)", string(text), string(sb))
+	}
+}
+
+// chunkReader returns at most n bytes of s per read, so that runes and tokens
+// are split across reads.
+type chunkReader struct {
+	s string
+	n int
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	if r.s == "" {
+		return 0, io.EOF
+	}
+
+	if len(p) > r.n {
+		p = p[:r.n]
+	}
+
+	n := copy(p, r.s)
+	r.s = r.s[n:]
+	return n, nil
+}
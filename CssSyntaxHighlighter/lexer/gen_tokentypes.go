@@ -0,0 +1,94 @@
+//go:build ignore
+
+// gen_tokentypes writes tokentype_string.go with the names and categories of
+// the token types from the comments of the TokenType constants in lexer.go.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"strings"
+)
+
+func main() {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "lexer.go", nil, parser.ParseComments)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by gen_tokentypes.go; DO NOT EDIT.\n\npackage lexer\n\nvar tokenTypes = [...]tokenTypeInfo{\n")
+	n := 0
+	ast.Inspect(f, func(node ast.Node) bool {
+		d, ok := node.(*ast.GenDecl)
+		if !ok || d.Tok != token.CONST || !isTokenTypeBlock(d) {
+			return true
+		}
+
+		for _, spec := range d.Specs {
+			v := spec.(*ast.ValueSpec)
+			name := v.Names[0].Name
+			if v.Comment == nil {
+				log.Fatalf("%s: missing category comment", fset.Position(v.Pos()))
+			}
+
+			category, bracket := "", false
+			for _, word := range strings.Split(strings.TrimSpace(v.Comment.Text()), ",") {
+				switch word = strings.TrimSpace(word); word {
+				case "bracket":
+					bracket = true
+				case "none":
+					category = "NoCategory"
+				case "literal", "punctuation", "name", "trivia", "error":
+					category = strings.ToUpper(word[:1]) + word[1:] + "Category"
+				default:
+					log.Fatalf("%s: unknown category %q", fset.Position(v.Pos()), word)
+				}
+			}
+
+			if category == "" {
+				log.Fatalf("%s: missing category", fset.Position(v.Pos()))
+			}
+
+			fmt.Fprintf(&buf, "%s: {%q, %s, %t},\n", name, strings.TrimSuffix(name, "Token"), category, bracket)
+			n++
+		}
+
+		return false
+	})
+	buf.WriteString("}\n")
+
+	if n == 0 {
+		log.Fatal("no TokenType constants in lexer.go")
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := os.WriteFile("tokentype_string.go", src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func isTokenTypeBlock(d *ast.GenDecl) bool {
+	if len(d.Specs) == 0 {
+		return false
+	}
+
+	v, ok := d.Specs[0].(*ast.ValueSpec)
+	if !ok {
+		return false
+	}
+
+	t, ok := v.Type.(*ast.Ident)
+	return ok && t.Name == "TokenType"
+}
@@ -1,110 +1,128 @@
 package lexer
 
 import (
+	"io"
+	"strconv"
 	"unicode"
 	"unicode/utf8"
 )
 
+// Lexer tokenizes Text, or the input of a reader when it is created with
+// NewLexer. pos and start are offsets in the whole input, buf holds the runes
+// from offset base on.
 type Lexer struct {
-	Text  []rune
-	pos   int
-	start int
+	Text []rune
+	// Dialect is the language of the input, CSS by default.
+	Dialect Dialect
+	pos     int
+	start   int
+	buf     []rune
+	base    int
+	src     io.RuneScanner
+	err     error
+	// line and col are the zero-based position of start, afterCR is set
+	// when the last shifted rune was a carriage return.
+	line    int
+	col     int
+	afterCR bool
 }
 
 type TokenType uint32
 
+// The comment after each token type is its category, and "bracket" for the
+// tokens that open or close a block. tokentype_string.go is generated from them.
+//
+//go:generate go run gen_tokentypes.go
 const (
-	ErrorToken TokenType = iota
-	IdentToken
-	FunctionToken
-	AtKeywordToken
-	HashToken
-	StringToken
-	BadStringToken
-	UrlToken
-	NumberToken
-	DimensionToken
-	PercentageToken
-	WhitespaceToken
-	LeftParenthesisToken
-	RightParenthesisToken
-	LeftBraceToken
-	RightBraceToken
-	ColonToken
-	SemicolonToken
-	CommaToken
-	CommentToken
-	AtToken
-	CDOToken
-	CDCToken
-	UnmatchedToken
-	EOF
+	ErrorToken            TokenType = iota // error
+	IdentToken                             // name
+	FunctionToken                          // name
+	AtKeywordToken                         // name
+	HashToken                              // name
+	StringToken                            // literal
+	BadStringToken                         // error
+	UrlToken                               // literal
+	BadUrlToken                            // error
+	NumberToken                            // literal
+	DimensionToken                         // literal
+	PercentageToken                        // literal
+	WhitespaceToken                        // trivia
+	LeftParenthesisToken                   // punctuation, bracket
+	RightParenthesisToken                  // punctuation, bracket
+	LeftBraceToken                         // punctuation, bracket
+	RightBraceToken                        // punctuation, bracket
+	ColonToken                             // punctuation
+	SemicolonToken                         // punctuation
+	CommaToken                             // punctuation
+	CommentToken                           // trivia
+	AtToken                                // punctuation
+	CDOToken                               // punctuation
+	CDCToken                               // punctuation
+	DelimToken                             // punctuation
+	LeftBracketToken                       // punctuation, bracket
+	RightBracketToken                      // punctuation, bracket
+	IncludeMatchToken                      // punctuation
+	DashMatchToken                         // punctuation
+	PrefixMatchToken                       // punctuation
+	SuffixMatchToken                       // punctuation
+	SubstringMatchToken                    // punctuation
+	ColumnToken                            // punctuation
+	VariableToken                          // name
+	InterpolationToken                     // punctuation, bracket
+	ParentSelectorToken                    // name
+	MixinToken                             // name
+	EOF                                    // none
 )
 
+var matchTokens = map[rune]TokenType{
+	'~': IncludeMatchToken,
+	'|': DashMatchToken,
+	'^': PrefixMatchToken,
+	'$': SuffixMatchToken,
+	'*': SubstringMatchToken,
+}
+
 type Token struct {
 	Type TokenType
 	Val  []rune
+	// Value is the decoded value of ident, function, at-keyword, hash, string
+	// and url tokens: escapes are resolved and the quotes, '#', '@', '(' and
+	// "url(...)" around the value are stripped. It is the name of variable and
+	// mixin tokens and the suffix of parent selector tokens. It is nil for the
+	// other token types.
+	Value []rune
+
+	// Number, Flag and Sign describe the numeric value of number, percentage
+	// and dimension tokens, Unit is the unit of a dimension token. Flag is
+	// also set on hash tokens.
+	Number float64
+	Flag   TypeFlag
+	Sign   rune
+	Unit   []rune
+
+	// Start and End are the rune offsets of the token in the input, Line and
+	// Col are the 1-based position of its first rune. CRLF counts as a single
+	// line break and a form feed starts a new line.
+	Start int
+	End   int
+	Line  int
+	Col   int
 }
 
-func (t TokenType) String() string {
-	switch t {
-	case ErrorToken:
-		return "Error"
-	case IdentToken:
-		return "Ident"
-	case FunctionToken:
-		return "Function"
-	case AtKeywordToken:
-		return "AtKeyword"
-	case HashToken:
-		return "Hash"
-	case StringToken:
-		return "String"
-	case BadStringToken:
-		return "BadString"
-	case UrlToken:
-		return "Url"
-	case NumberToken:
-		return "Number"
-	case DimensionToken:
-		return "Dimension"
-	case PercentageToken:
-		return "Percentage"
-	case WhitespaceToken:
-		return "Whitespace"
-	case LeftParenthesisToken:
-		return "LeftParenthesis"
-	case RightParenthesisToken:
-		return "RightParenthesis"
-	case LeftBraceToken:
-		return "LeftBrace"
-	case RightBraceToken:
-		return "RightBrace"
-	case ColonToken:
-		return "Colon"
-	case SemicolonToken:
-		return "Semicolon"
-	case CommaToken:
-		return "Comma"
-	case CommentToken:
-		return "Comment"
-	case AtToken:
-		return "At"
-	case CDOToken:
-		return "CDO"
-	case CDCToken:
-		return "CDC"
-	case UnmatchedToken:
-		return "Unmatched"
-	case EOF:
-		return "EOF"
-	default:
-		return ""
-	}
-}
+// TypeFlag tells whether a numeric token was written as an integer and
+// whether the name of a hash token is a valid identifier.
+type TypeFlag uint8
+
+const (
+	NoFlag TypeFlag = iota
+	IntegerFlag
+	NumberFlag
+	IDFlag
+	UnrestrictedFlag
+)
 
 func (lex *Lexer) next() {
-	if lex.pos >= len(lex.Text) {
+	if lex.peek(0) < 0 {
 		return
 	}
 
@@ -116,29 +134,86 @@ func (lex *Lexer) setPos(p int) {
 }
 
 func (lex *Lexer) peek(c int) rune {
-	pos := lex.pos + c
-	if pos >= len(lex.Text) {
+	i := lex.pos + c - lex.base
+	if i >= len(lex.buf) && !lex.fill(i) {
 		return -1
 	}
-	return lex.Text[pos]
+	return lex.buf[lex.pos+c-lex.base]
+}
+
+// slice returns the runes between the offsets from and to, which must not be
+// before start.
+func (lex *Lexer) slice(from int, to int) []rune {
+	return lex.buf[from-lex.base : to-lex.base : to-lex.base]
 }
 
 func (lex *Lexer) shift() []rune {
-	r := lex.Text[lex.start:lex.pos:lex.pos]
+	r := lex.slice(lex.start, lex.pos)
+	for _, c := range r {
+		switch {
+		case c == '\n' && lex.afterCR:
+		case isNewline(c):
+			lex.line++
+			lex.col = 0
+		default:
+			lex.col++
+		}
+
+		lex.afterCR = c == '\r'
+	}
+
 	lex.start = lex.pos
 	return r
 }
 
 func (lex *Lexer) NextToken() Token {
+	start, line, col := lex.start, lex.line, lex.col
+	t := lex.scanToken()
+	t.Start, t.End, t.Line, t.Col = start, lex.start, line+1, col+1
+	return t
+}
+
+// State is the position of a lexer between two tokens. A token does not
+// depend on the tokens before it, so lexing can resume from the start of any
+// token with its offset, line and column.
+type State struct {
+	Offset, Line, Col int
+	// AfterCR is set when the rune before Offset is a carriage return.
+	AfterCR bool
+}
+
+// State returns the position of the next token.
+func (lex *Lexer) State() State {
+	return State{Offset: lex.start, Line: lex.line + 1, Col: lex.col + 1, AfterCR: lex.afterCR}
+}
+
+// Resume continues lexing from s. It is only supported by lexers over Text,
+// a streaming lexer cannot go back in its input.
+func (lex *Lexer) Resume(s State) {
+	if lex.src != nil {
+		panic("lexer: Resume called on a streaming lexer")
+	}
+
+	lex.pos, lex.start = s.Offset, s.Offset
+	lex.line, lex.col, lex.afterCR = s.Line-1, s.Col-1, s.AfterCR
+}
+
+func (lex *Lexer) scanToken() Token {
+	if lex.Dialect != CSS {
+		if t, ok := lex.scanDialectToken(); ok {
+			return t
+		}
+	}
+
 	var r rune
 	switch r = lex.peek(0); {
-	case r <= 0:
+	case r < 0:
 		return Token{Type: EOF, Val: []rune{}}
 	case lex.scanWhitespace():
 		return Token{Type: WhitespaceToken, Val: lex.shift()}
 	case r == '"', r == '\'':
 		t, v := lex.scanString(r)
-		return Token{Type: t, Val: v}
+		return Token{Type: t, Val: lex.shift(), Value: v}
 	case r == '{':
 		lex.next()
 		return Token{Type: LeftBraceToken, Val: lex.shift()}
@@ -160,100 +235,267 @@ func (lex *Lexer) NextToken() Token {
 	case r == ',':
 		lex.next()
 		return Token{Type: CommaToken, Val: lex.shift()}
+	case r == '[':
+		lex.next()
+		return Token{Type: LeftBracketToken, Val: lex.shift()}
+	case r == ']':
+		lex.next()
+		return Token{Type: RightBracketToken, Val: lex.shift()}
+	case r == '/' && lex.peek(1) == '*':
+		lex.scanComment()
+		return Token{Type: CommentToken, Val: lex.shift()}
+	case r == '<' && lex.peek(1) == '!' && lex.peek(2) == '-' && lex.peek(3) == '-':
+		lex.setPos(lex.pos + 4)
+		return Token{Type: CDOToken, Val: lex.shift()}
+	case r == '-' && lex.peek(1) == '-' && lex.peek(2) == '>':
+		lex.setPos(lex.pos + 3)
+		return Token{Type: CDCToken, Val: lex.shift()}
+	case r == '|' && lex.peek(1) == '|':
+		lex.setPos(lex.pos + 2)
+		return Token{Type: ColumnToken, Val: lex.shift()}
+	case lex.peek(1) == '=' && matchTokens[r] != 0:
+		lex.setPos(lex.pos + 2)
+		return Token{Type: matchTokens[r], Val: lex.shift()}
 	case r == '#':
-		if isIdentStart(lex.peek(1)) {
+		if isIdent(lex.peek(1)) || lex.isValidEscape(1) {
+			flag := UnrestrictedFlag
+			if lex.startsIdent(1) {
+				flag = IDFlag
+			}
+
 			lex.next()
-			_ = lex.scanIdent()
-			return Token{Type: HashToken, Val: lex.shift()}
+			v := lex.scanName()
+			return Token{Type: HashToken, Val: lex.shift(), Value: v, Flag: flag}
 		}
 	case r == '@':
-		if isIdentStart(lex.peek(1)) {
+		if lex.startsIdent(1) {
 			lex.next()
-			_ = lex.scanIdent()
-			return Token{Type: AtKeywordToken, Val: lex.shift()}
+			v := lex.scanName()
+			return Token{Type: AtKeywordToken, Val: lex.shift(), Value: v}
 		}
-	case r == '.':
-		t := lex.scanNumericToken()
-		if t != ErrorToken {
-			return Token{Type: t, Val: lex.shift()}
-		}
-	case isIdentStart(r):
-		_ = lex.scanIdent()
-		val := lex.shift()
-		if lex.peek(1) == '(' {
-			if len(val) == 3 && matchASCIICaseInsensitive(val[0], 'u') && matchASCIICaseInsensitive(val[1], 'r') && matchASCIICaseInsensitive(val[2], 'l') {
-				return Token{Type: UrlToken, Val: val}
-			} else {
-				return Token{Type: FunctionToken, Val: val}
-			}
-		} else {
-			return Token{Type: IdentToken, Val: val}
+
+		lex.next()
+		return Token{Type: AtToken, Val: lex.shift()}
+	case lex.startsNumber(0):
+		return lex.scanNumericToken()
+	case lex.startsIdent(0):
+		return lex.scanIdentLike()
+	}
+
+	lex.next()
+	return Token{Type: DelimToken, Val: lex.shift()}
+}
+
+// https://www.w3.org/TR/css-syntax-3/#consume-comment
+func (lex *Lexer) scanComment() {
+	lex.setPos(lex.pos + 2)
+	for {
+		r := lex.peek(0)
+		if r < 0 {
+			return
 		}
-	case unicode.IsDigit(r):
-		t := lex.scanNumericToken()
-		if t != ErrorToken {
-			return Token{Type: t, Val: lex.shift()}
+
+		lex.next()
+		if r == '*' && lex.peek(0) == '/' {
+			lex.next()
+			return
 		}
 	}
+}
+
+// https://www.w3.org/TR/css-syntax-3/#consume-ident-like-token
+func (lex *Lexer) scanIdentLike() Token {
+	v := lex.scanName()
+	if lex.peek(0) != '(' {
+		return Token{Type: IdentToken, Val: lex.shift(), Value: v}
+	}
 
 	lex.next()
-	return Token{Type: UnmatchedToken, Val: lex.shift()}
+	if len(v) == 3 && matchASCIICaseInsensitive(v[0], 'u') && matchASCIICaseInsensitive(v[1], 'r') && matchASCIICaseInsensitive(v[2], 'l') {
+		for isWhitespace(lex.peek(0)) && isWhitespace(lex.peek(1)) {
+			lex.next()
+		}
+
+		r := lex.peek(0)
+		if isWhitespace(r) {
+			r = lex.peek(1)
+		}
+
+		if r != '"' && r != '\'' {
+			t, u := lex.scanUrl()
+			return Token{Type: t, Val: lex.shift(), Value: u}
+		}
+	}
+
+	return Token{Type: FunctionToken, Val: lex.shift(), Value: v}
 }
 
-func (lex *Lexer) scanIdent() bool {
-	r := false
-	for isIdent(lex.peek(0)) {
-		lex.next()
-		r = true
+// https://www.w3.org/TR/css-syntax-3/#consume-url-token
+// It expects "url(" to be consumed already.
+func (lex *Lexer) scanUrl() (TokenType, []rune) {
+	lex.scanWhitespace()
+	var val []rune
+	for {
+		r := lex.peek(0)
+		switch {
+		case r == ')':
+			lex.next()
+			return UrlToken, val
+		case r < 0:
+			return UrlToken, val
+		case isWhitespace(r):
+			lex.scanWhitespace()
+			if r = lex.peek(0); r == ')' || r < 0 {
+				lex.next()
+				return UrlToken, val
+			}
+
+			lex.scanBadUrlRemnants()
+			return BadUrlToken, nil
+		case r == '"', r == '\'', r == '(', isNonPrintable(r):
+			lex.scanBadUrlRemnants()
+			return BadUrlToken, nil
+		case r == '\\':
+			if !lex.isValidEscape(0) {
+				lex.scanBadUrlRemnants()
+				return BadUrlToken, nil
+			}
+
+			lex.next()
+			val = append(val, lex.scanEscapedChars())
+		default:
+			lex.next()
+			val = append(val, r)
+		}
 	}
+}
 
-	return r
+// https://www.w3.org/TR/css-syntax-3/#consume-remnants-of-bad-url
+func (lex *Lexer) scanBadUrlRemnants() {
+	for {
+		if lex.isValidEscape(0) {
+			lex.next()
+			lex.scanEscapedChars()
+			continue
+		}
+
+		r := lex.peek(0)
+		lex.next()
+		if r == ')' || r < 0 {
+			return
+		}
+	}
 }
 
-func (lex *Lexer) scanNumericToken() TokenType {
-	if lex.scanNumber() {
-		if lex.peek(0) == '%' {
+// https://www.w3.org/TR/css-syntax-3/#consume-name
+func (lex *Lexer) scanName() []rune {
+	startPos := lex.pos
+	var val []rune
+	for {
+		r := lex.peek(0)
+		if isIdent(r) {
 			lex.next()
-			return PercentageToken
-		} else if lex.scanIdent() {
-			return DimensionToken
+			if val != nil {
+				val = append(val, r)
+			}
+		} else if lex.isValidEscape(0) {
+			if val == nil {
+				val = append([]rune{}, lex.slice(startPos, lex.pos)...)
+			}
+			lex.next()
+			val = append(val, lex.scanEscapedChars())
+		} else {
+			break
 		}
+	}
 
-		return NumberToken
+	if val == nil {
+		return lex.slice(startPos, lex.pos)
 	}
 
-	return ErrorToken
+	return val
 }
 
-func (lex *Lexer) scanNumber() bool {
-	startPos := lex.pos
-	r := lex.peek(0)
-	isFirstDigit := unicode.IsDigit(r)
-	if isFirstDigit {
+// https://www.w3.org/TR/css-syntax-3/#consume-numeric-token
+func (lex *Lexer) scanNumericToken() Token {
+	n, flag, sign := lex.scanNumber()
+	if lex.startsIdent(0) {
+		unit := lex.scanName()
+		return Token{Type: DimensionToken, Val: lex.shift(), Number: n, Flag: flag, Sign: sign, Unit: unit}
+	}
+
+	if lex.peek(0) == '%' {
 		lex.next()
+		return Token{Type: PercentageToken, Val: lex.shift(), Number: n, Flag: flag, Sign: sign}
 	}
 
-	for r = lex.peek(0); unicode.IsDigit(r); r = lex.peek(0) {
+	return Token{Type: NumberToken, Val: lex.shift(), Number: n, Flag: flag, Sign: sign}
+}
+
+// https://www.w3.org/TR/css-syntax-3/#consume-number
+func (lex *Lexer) scanNumber() (float64, TypeFlag, rune) {
+	startPos := lex.pos
+	flag := IntegerFlag
+	var sign rune
+	if r := lex.peek(0); r == '+' || r == '-' {
+		sign = r
 		lex.next()
 	}
 
-	if r == '.' {
+	lex.scanDigits()
+
+	if lex.peek(0) == '.' && isDigit(lex.peek(1)) {
 		lex.next()
+		lex.scanDigits()
+		flag = NumberFlag
+	}
 
-		for unicode.IsDigit(lex.peek(0)) {
-			lex.next()
+	if r := lex.peek(0); r == 'e' || r == 'E' {
+		c := 1
+		if r1 := lex.peek(1); r1 == '+' || r1 == '-' {
+			c++
+		}
+
+		if isDigit(lex.peek(c)) {
+			lex.setPos(lex.pos + c)
+			lex.scanDigits()
+			flag = NumberFlag
+		}
+	}
+
+	n, err := strconv.ParseFloat(string(lex.slice(startPos, lex.pos)), 64)
+	if err != nil {
+		// ParseFloat still returns ±Inf or 0 for values out of range.
+		if numErr, ok := err.(*strconv.NumError); !ok || numErr.Err != strconv.ErrRange {
+			n = 0
 		}
-	} else if !isFirstDigit {
-		lex.setPos(startPos)
-		return false
 	}
 
-	return true
+	return n, flag, sign
+}
+
+func (lex *Lexer) scanDigits() {
+	for isDigit(lex.peek(0)) {
+		lex.next()
+	}
+}
+
+// https://www.w3.org/TR/css-syntax-3/#starts-with-a-number
+func (lex *Lexer) startsNumber(c int) bool {
+	switch r := lex.peek(c); {
+	case r == '+', r == '-':
+		r1 := lex.peek(c + 1)
+		return isDigit(r1) || (r1 == '.' && isDigit(lex.peek(c+2)))
+	case r == '.':
+		return isDigit(lex.peek(c + 1))
+	default:
+		return isDigit(r)
+	}
 }
 
 func (lex *Lexer) scanWhitespace() bool {
 	r := false
-	for unicode.IsSpace(lex.peek(0)) {
+	for isWhitespace(lex.peek(0)) {
 		lex.next()
 		r = true
 	}
@@ -261,38 +503,107 @@ func (lex *Lexer) scanWhitespace() bool {
 	return r
 }
 
-func (lex *Lexer) scanEscapedChars() {
+// https://www.w3.org/TR/css-syntax-3/#consume-escaped-code-point
+// It expects the reverse solidus to be consumed already.
+func (lex *Lexer) scanEscapedChars() rune {
+	r := lex.peek(0)
+	if r < 0 {
+		return unicode.ReplacementChar
+	}
+
+	if !isHexDigit(r) {
+		lex.next()
+		return r
+	}
+
+	v := 0
+	for i := 0; i < 6 && isHexDigit(lex.peek(0)); i++ {
+		v = v*16 + hexValue(lex.peek(0))
+		lex.next()
+	}
 
+	if isWhitespace(lex.peek(0)) {
+		lex.scanNewline()
+	}
+
+	if v == 0 || (v >= 0xD800 && v <= 0xDFFF) || v > unicode.MaxRune {
+		return unicode.ReplacementChar
+	}
+
+	return rune(v)
+}
+
+// https://www.w3.org/TR/css-syntax-3/#check-if-two-code-points-are-a-valid-escape
+func (lex *Lexer) isValidEscape(c int) bool {
+	return lex.peek(c) == '\\' && !isNewline(lex.peek(c+1))
 }
 
+// https://www.w3.org/TR/css-syntax-3/#check-if-three-code-points-would-start-an-ident-sequence
+func (lex *Lexer) startsIdent(c int) bool {
+	switch r := lex.peek(c); {
+	case r == '-':
+		r1 := lex.peek(c + 1)
+		return isIdentStart(r1) || r1 == '-' || lex.isValidEscape(c+1)
+	case r == '\\':
+		return lex.isValidEscape(c)
+	default:
+		return isIdentStart(r)
+	}
+}
+
+// scanNewline consumes a single whitespace code point, treating CRLF as one.
+func (lex *Lexer) scanNewline() {
+	if lex.peek(0) == '\r' && lex.peek(1) == '\n' {
+		lex.next()
+	}
+
+	lex.next()
+}
+
+// https://www.w3.org/TR/css-syntax-3/#consume-string-token
 func (lex *Lexer) scanString(endRune rune) (TokenType, []rune) {
+	lex.next()
 	startPos := lex.pos
+	var val []rune
+	value := func() []rune {
+		if val == nil {
+			return lex.slice(startPos, lex.pos)
+		}
+
+		return val
+	}
+
 	for {
 		r := lex.peek(0)
-		if r == endRune {
+		switch {
+		case r == endRune:
+			v := value()
 			lex.next()
-			break
-		} else if r <= 0 {
-			break
-		} else if isNewline(r) {
+			return StringToken, v
+		case r < 0:
+			return StringToken, value()
+		case isNewline(r):
+			return BadStringToken, nil
+		case r == '\\':
+			if val == nil {
+				val = append([]rune{}, lex.slice(startPos, lex.pos)...)
+			}
+
 			lex.next()
-			return BadStringToken, lex.Text[startPos:lex.pos]
-		} else if r == '\\' {
-			r1 := lex.peek(0)
-			if r1 <= 0 {
-				break
-			} else if isNewline(r1) {
-				lex.next()
+			if r1 := lex.peek(0); r1 < 0 {
 				continue
+			} else if isNewline(r1) {
+				lex.scanNewline()
 			} else {
-				lex.scanEscapedChars()
+				val = append(val, lex.scanEscapedChars())
 			}
-		} else {
+		default:
 			lex.next()
+			if val != nil {
+				val = append(val, r)
+			}
 		}
 	}
-
-	return StringToken, lex.Text[startPos:lex.pos]
 }
 
 func readRune(text string, pos int) (r rune, size int) {
@@ -329,6 +640,29 @@ func isIdentStart(r rune) bool {
 	return isLetter(r) || isNonASCII(r) || r == '_'
 }
 
+func isHexDigit(r rune) bool {
+	return isDigit(r) || (r >= 'a' && r <= 'f') || (r >= 'A' && r <= 'F')
+}
+
+func hexValue(r rune) int {
+	switch {
+	case r >= 'a':
+		return int(r-'a') + 10
+	case r >= 'A':
+		return int(r-'A') + 10
+	default:
+		return int(r - '0')
+	}
+}
+
+func isNonPrintable(r rune) bool {
+	return (r >= 0 && r <= 0x08) || r == 0x0B || (r >= 0x0E && r <= 0x1F) || r == 0x7F
+}
+
+func isWhitespace(r rune) bool {
+	return isNewline(r) || r == '\t' || r == ' '
+}
+
 func isNewline(r rune) bool {
 	return r == '\n' || r == '\r' || r == '\f'
 }
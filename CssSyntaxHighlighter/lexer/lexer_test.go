@@ -1,6 +1,7 @@
 package lexer
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -30,6 +31,26 @@ func TestNextToken(t *testing.T) {
 		{"2rem", []TestDataToken{{DimensionToken, "2rem"}}},
 		{".2rem", []TestDataToken{{DimensionToken, ".2rem"}}},
 		{"2px .25rem 1.25rem", []TestDataToken{{DimensionToken, "2px"}, {WhitespaceToken, " "}, {DimensionToken, ".25rem"}, {WhitespaceToken, " "}, {DimensionToken, "1.25rem"}}},
+		{"-10px", []TestDataToken{{DimensionToken, "-10px"}}},
+		{"1e3", []TestDataToken{{NumberToken, "1e3"}}},
+		{"1.", []TestDataToken{{NumberToken, "1"}, {DelimToken, "."}}},
+		{"a-1", []TestDataToken{{IdentToken, "a-1"}}},
+		{"1-2", []TestDataToken{{NumberToken, "1"}, {NumberToken, "-2"}}},
+		{"1+2", []TestDataToken{{NumberToken, "1"}, {NumberToken, "+2"}}},
+		{"/* a */", []TestDataToken{{CommentToken, "/* a */"}}},
+		{"/**/a/***/", []TestDataToken{{CommentToken, "/**/"}, {IdentToken, "a"}, {CommentToken, "/***/"}}},
+		{"/* a", []TestDataToken{{CommentToken, "/* a"}}},
+		{"/*/", []TestDataToken{{CommentToken, "/*/"}}},
+		{"<!-- a -->", []TestDataToken{{CDOToken, "<!--"}, {WhitespaceToken, " "}, {IdentToken, "a"}, {WhitespaceToken, " "}, {CDCToken, "-->"}}},
+		{"<!-", []TestDataToken{{DelimToken, "<"}, {DelimToken, "!"}, {DelimToken, "-"}}},
+		{"--> --x", []TestDataToken{{CDCToken, "-->"}, {WhitespaceToken, " "}, {IdentToken, "--x"}}},
+		{"@ @1 @a", []TestDataToken{{AtToken, "@"}, {WhitespaceToken, " "}, {AtToken, "@"}, {NumberToken, "1"}, {WhitespaceToken, " "}, {AtKeywordToken, "@a"}}},
+		{"a > b ~ c + d", []TestDataToken{{IdentToken, "a"}, {WhitespaceToken, " "}, {DelimToken, ">"}, {WhitespaceToken, " "}, {IdentToken, "b"}, {WhitespaceToken, " "}, {DelimToken, "~"}, {WhitespaceToken, " "}, {IdentToken, "c"}, {WhitespaceToken, " "}, {DelimToken, "+"}, {WhitespaceToken, " "}, {IdentToken, "d"}}},
+		{"*.a/!=^$|-", []TestDataToken{{DelimToken, "*"}, {DelimToken, "."}, {IdentToken, "a"}, {DelimToken, "/"}, {DelimToken, "!"}, {DelimToken, "="}, {DelimToken, "^"}, {DelimToken, "$"}, {DelimToken, "|"}, {DelimToken, "-"}}},
+		{"#", []TestDataToken{{DelimToken, "#"}}},
+		{"\\\n", []TestDataToken{{DelimToken, "\\"}, {WhitespaceToken, "\n"}}},
+		{"[a~=b]", []TestDataToken{{LeftBracketToken, "["}, {IdentToken, "a"}, {IncludeMatchToken, "~="}, {IdentToken, "b"}, {RightBracketToken, "]"}}},
+		{"|=^=$=*=||", []TestDataToken{{DashMatchToken, "|="}, {PrefixMatchToken, "^="}, {SuffixMatchToken, "$="}, {SubstringMatchToken, "*="}, {ColumnToken, "||"}}},
 		{"color: red;", []TestDataToken{{IdentToken, "color"}, {ColonToken, ":"}, {WhitespaceToken, " "}, {IdentToken, "red"}, {SemicolonToken, ";"}}},
 	}
 
@@ -44,7 +65,13 @@ func TestNextToken(t *testing.T) {
 			t.Fatalf("len(tokens) - %v != %v - len(v.tokens)", len(tokens), len(v.tokens))
 		}
 
+		offset := 0
 		for i, token := range tokens {
+			if token.Start != offset || token.End != offset+len(token.Val) {
+				t.Fatalf("%s %d. token offsets (expected) %d-%d != %d-%d (actual)", v.css, i, offset, offset+len(token.Val), token.Start, token.End)
+			}
+			offset = token.End
+
 			actualTokenType := token.Type
 			expectedTokenType := v.tokens[i].tokenType
 			actualTokenValue := string(token.Val)
@@ -60,3 +87,353 @@ func TestNextToken(t *testing.T) {
 		}
 	}
 }
+
+type TestDataValueToken struct {
+	tokenType TokenType
+	val       string
+	value     string
+}
+
+func TestNextTokenEscapes(t *testing.T) {
+	values := []struct {
+		css    string
+		tokens []TestDataValueToken
+	}{
+		{`"\f101"`, []TestDataValueToken{{StringToken, `"\f101"`, "\uf101"}}},
+		{`'a\'b'`, []TestDataValueToken{{StringToken, `'a\'b'`, "a'b"}}},
+		{`"a\"`, []TestDataValueToken{{StringToken, `"a\"`, `a"`}}},
+		{`"a\`, []TestDataValueToken{{StringToken, `"a\`, "a"}}},
+		{"\"a\\\nb\"", []TestDataValueToken{{StringToken, "\"a\\\nb\"", "ab"}}},
+		{"\"a\\\r\nb\"", []TestDataValueToken{{StringToken, "\"a\\\r\nb\"", "ab"}}},
+		{"\"a\nb", []TestDataValueToken{{BadStringToken, "\"a", ""}, {WhitespaceToken, "\n", ""}, {IdentToken, "b", "b"}}},
+		{`"\0 \110000 \d800"`, []TestDataValueToken{{StringToken, `"\0 \110000 \d800"`, "\ufffd\ufffd\ufffd"}}},
+		{`"\41 \42  x"`, []TestDataValueToken{{StringToken, `"\41 \42  x"`, "AB x"}}},
+		{`\31 0px`, []TestDataValueToken{{IdentToken, `\31 0px`, "10px"}}},
+		{`a\:b`, []TestDataValueToken{{IdentToken, `a\:b`, "a:b"}}},
+		{`\`, []TestDataValueToken{{IdentToken, `\`, "\ufffd"}}},
+		{`-moz-box`, []TestDataValueToken{{IdentToken, `-moz-box`, "-moz-box"}}},
+		{`--main-color`, []TestDataValueToken{{IdentToken, `--main-color`, "--main-color"}}},
+		{`#\31 23`, []TestDataValueToken{{HashToken, `#\31 23`, "123"}}},
+		{`#fff`, []TestDataValueToken{{HashToken, `#fff`, "fff"}}},
+		{`@\6d edia`, []TestDataValueToken{{AtKeywordToken, `@\6d edia`, "media"}}},
+		{`r\67 b(`, []TestDataValueToken{{FunctionToken, `r\67 b(`, "rgb"}}},
+		{`url(a.png)`, []TestDataValueToken{{UrlToken, `url(a.png)`, "a.png"}}},
+		{`URL(  a.png  )`, []TestDataValueToken{{UrlToken, `URL(  a.png  )`, "a.png"}}},
+		{`url(data:image/png;base64,iVBORw0K=)`, []TestDataValueToken{{UrlToken, `url(data:image/png;base64,iVBORw0K=)`, "data:image/png;base64,iVBORw0K="}}},
+		{`url(a\)b.png)`, []TestDataValueToken{{UrlToken, `url(a\)b.png)`, "a)b.png"}}},
+		{`url()`, []TestDataValueToken{{UrlToken, `url()`, ""}}},
+		{`url(a.png`, []TestDataValueToken{{UrlToken, `url(a.png`, "a.png"}}},
+		{`url(a b) x`, []TestDataValueToken{{BadUrlToken, `url(a b)`, ""}, {WhitespaceToken, " ", ""}, {IdentToken, "x", "x"}}},
+		{`url(a"b) x`, []TestDataValueToken{{BadUrlToken, `url(a"b)`, ""}, {WhitespaceToken, " ", ""}, {IdentToken, "x", "x"}}},
+		{`url(a(b) x`, []TestDataValueToken{{BadUrlToken, `url(a(b)`, ""}, {WhitespaceToken, " ", ""}, {IdentToken, "x", "x"}}},
+		{"url(a\u0001b)", []TestDataValueToken{{BadUrlToken, "url(a\u0001b)", ""}}},
+		{"url(a\\\nb)", []TestDataValueToken{{BadUrlToken, "url(a\\\nb)", ""}}},
+		{`url(a b\)c)`, []TestDataValueToken{{BadUrlToken, `url(a b\)c)`, ""}}},
+		{`url("a.png")`, []TestDataValueToken{{FunctionToken, `url(`, "url"}, {StringToken, `"a.png"`, "a.png"}, {RightParenthesisToken, ")", ""}}},
+		{`url(  'a.png')`, []TestDataValueToken{{FunctionToken, `url( `, "url"}, {WhitespaceToken, " ", ""}, {StringToken, `'a.png'`, "a.png"}, {RightParenthesisToken, ")", ""}}},
+		{`u\72l(a.png)`, []TestDataValueToken{{UrlToken, `u\72l(a.png)`, "a.png"}}},
+		{`content: "\f101";`, []TestDataValueToken{{IdentToken, "content", "content"}, {ColonToken, ":", ""}, {WhitespaceToken, " ", ""}, {StringToken, `"\f101"`, "\uf101"}, {SemicolonToken, ";", ""}}},
+	}
+
+	for _, v := range values {
+		l := Lexer{Text: []rune(v.css)}
+		tokens := []Token{}
+		for token := l.NextToken(); token.Type != EOF; token = l.NextToken() {
+			tokens = append(tokens, token)
+		}
+
+		if len(tokens) != len(v.tokens) {
+			t.Fatalf("%s len(tokens) - %v != %v - len(v.tokens)", v.css, len(tokens), len(v.tokens))
+		}
+
+		for i, token := range tokens {
+			expected := v.tokens[i]
+			if token.Type != expected.tokenType {
+				t.Fatalf("%s %d. token type (expected) %v != %v (actual)", v.css, i, expected.tokenType.String(), token.Type.String())
+			}
+
+			if string(token.Val) != expected.val {
+				t.Fatalf("%s %d. token value (expected) %q != %q (actual)", v.css, i, expected.val, string(token.Val))
+			}
+
+			if string(token.Value) != expected.value {
+				t.Fatalf("%s %d. token decoded value (expected) %q != %q (actual)", v.css, i, expected.value, string(token.Value))
+			}
+		}
+	}
+}
+
+func TestNextTokenNumericValues(t *testing.T) {
+	values := []struct {
+		css       string
+		tokenType TokenType
+		number    float64
+		flag      TypeFlag
+		sign      rune
+		unit      string
+	}{
+		{"10", NumberToken, 10, IntegerFlag, 0, ""},
+		{"+10", NumberToken, 10, IntegerFlag, '+', ""},
+		{"-10", NumberToken, -10, IntegerFlag, '-', ""},
+		{"-.5", NumberToken, -0.5, NumberFlag, '-', ""},
+		{"1e3", NumberToken, 1000, NumberFlag, 0, ""},
+		{"1e-3", NumberToken, 0.001, NumberFlag, 0, ""},
+		{"2E+5", NumberToken, 200000, NumberFlag, 0, ""},
+		{"1.5e2", NumberToken, 150, NumberFlag, 0, ""},
+		{"-10px", DimensionToken, -10, IntegerFlag, '-', "px"},
+		{"1e3px", DimensionToken, 1000, NumberFlag, 0, "px"},
+		{"1em", DimensionToken, 1, IntegerFlag, 0, "em"},
+		{"1e-x", DimensionToken, 1, IntegerFlag, 0, "e-x"},
+		{"2\\70 x", DimensionToken, 2, IntegerFlag, 0, "px"},
+		{"+.5%", PercentageToken, 0.5, NumberFlag, '+', ""},
+		{"50%", PercentageToken, 50, IntegerFlag, 0, ""},
+	}
+
+	for _, v := range values {
+		l := Lexer{Text: []rune(v.css)}
+		token := l.NextToken()
+		if next := l.NextToken(); next.Type != EOF {
+			t.Fatalf("%s expected a single token, got %v after %v", v.css, next.Type.String(), token.Type.String())
+		}
+
+		if token.Type != v.tokenType {
+			t.Fatalf("%s token type (expected) %v != %v (actual)", v.css, v.tokenType.String(), token.Type.String())
+		}
+
+		if string(token.Val) != v.css {
+			t.Fatalf("%s token value (expected) %q != %q (actual)", v.css, v.css, string(token.Val))
+		}
+
+		if token.Number != v.number || token.Flag != v.flag || token.Sign != v.sign || string(token.Unit) != v.unit {
+			t.Fatalf("%s (expected) %v %v %q %q != %v %v %q %q (actual)", v.css, v.number, v.flag, v.sign, v.unit, token.Number, token.Flag, token.Sign, string(token.Unit))
+		}
+	}
+}
+
+func TestNextTokenPositions(t *testing.T) {
+	type position struct {
+		val       string
+		start     int
+		line, col int
+	}
+
+	values := []struct {
+		css       string
+		positions []position
+	}{
+		{"a {\n  color: red;\n}", []position{
+			{"a", 0, 1, 1}, {" ", 1, 1, 2}, {"{", 2, 1, 3}, {"\n  ", 3, 1, 4}, {"color", 6, 2, 3}, {":", 11, 2, 8},
+			{" ", 12, 2, 9}, {"red", 13, 2, 10}, {";", 16, 2, 13}, {"\n", 17, 2, 14}, {"}", 18, 3, 1},
+		}},
+		{"a\r\nb\rc\fd\n\ne", []position{
+			{"a", 0, 1, 1}, {"\r\n", 1, 1, 2}, {"b", 3, 2, 1}, {"\r", 4, 2, 2}, {"c", 5, 3, 1}, {"\f", 6, 3, 2},
+			{"d", 7, 4, 1}, {"\n\n", 8, 4, 2}, {"e", 10, 6, 1},
+		}},
+		{"/* a\r\n b */x \"\\\r\n\"y", []position{
+			{"/* a\r\n b */", 0, 1, 1}, {"x", 11, 2, 6}, {" ", 12, 2, 7}, {"\"\\\r\n\"", 13, 2, 8}, {"y", 18, 3, 2},
+		}},
+		{"\u00e9t\u00e9 1", []position{{"\u00e9t\u00e9", 0, 1, 1}, {" ", 3, 1, 4}, {"1", 4, 1, 5}}},
+	}
+
+	for _, v := range values {
+		l := Lexer{Text: []rune(v.css)}
+		for i, expected := range v.positions {
+			token := l.NextToken()
+			if string(token.Val) != expected.val {
+				t.Fatalf("%q %d. token value (expected) %q != %q (actual)", v.css, i, expected.val, string(token.Val))
+			}
+
+			if token.Start != expected.start || token.End != expected.start+len([]rune(expected.val)) {
+				t.Fatalf("%q %d. token offsets (expected) %d-%d != %d-%d (actual)", v.css, i, expected.start, expected.start+len([]rune(expected.val)), token.Start, token.End)
+			}
+
+			if token.Line != expected.line || token.Col != expected.col {
+				t.Fatalf("%q %d. token position (expected) %d:%d != %d:%d (actual)", v.css, i, expected.line, expected.col, token.Line, token.Col)
+			}
+		}
+
+		if token := l.NextToken(); token.Type != EOF || token.Start != len([]rune(v.css)) {
+			t.Fatalf("%q expected EOF at %d, got %v at %d", v.css, len([]rune(v.css)), token.Type.String(), token.Start)
+		}
+	}
+}
+
+func TestNextTokenHashFlag(t *testing.T) {
+	values := []struct {
+		css  string
+		flag TypeFlag
+	}{
+		{"#main", IDFlag},
+		{"#-main", IDFlag},
+		{"#\\31 23", IDFlag},
+		{"#123", UnrestrictedFlag},
+		{"#-1", UnrestrictedFlag},
+		{"#fff", IDFlag},
+		{"#0af", UnrestrictedFlag},
+	}
+
+	for _, v := range values {
+		l := Lexer{Text: []rune(v.css)}
+		if token := l.NextToken(); token.Type != HashToken || token.Flag != v.flag {
+			t.Fatalf("%s (expected) Hash %v != %v %v (actual)", v.css, v.flag, token.Type.String(), token.Flag)
+		}
+	}
+}
+
+func TestResume(t *testing.T) {
+	css := "a {\r\n  color: red; /* x\ny */\n  b: url(c)\n}"
+	l := Lexer{Text: []rune(css)}
+	tokens := []Token{}
+	states := []State{}
+	for {
+		states = append(states, l.State())
+		v := l.NextToken()
+		if v.Type == EOF {
+			break
+		}
+		tokens = append(tokens, v)
+	}
+
+	for i, s := range states[:len(tokens)] {
+		if s.Offset != tokens[i].Start || s.Line != tokens[i].Line || s.Col != tokens[i].Col {
+			t.Fatalf("%d. state %+v does not match token %+v", i, s, tokens[i])
+		}
+
+		resumed := Lexer{Text: []rune(css)}
+		resumed.Resume(s)
+		for j := i; j < len(tokens); j++ {
+			v := resumed.NextToken()
+			if v.Type != tokens[j].Type || v.Start != tokens[j].Start || v.Line != tokens[j].Line || v.Col != tokens[j].Col {
+				t.Fatalf("resumed at %d, %d. token (expected) %+v != %+v (actual)", i, j, tokens[j], v)
+			}
+		}
+	}
+}
+
+func TestTokenTypeMetadata(t *testing.T) {
+	values := []struct {
+		tokenType TokenType
+		name      string
+		category  Category
+		trivia    bool
+		bracket   bool
+	}{
+		{ErrorToken, "Error", ErrorCategory, false, false},
+		{IdentToken, "Ident", NameCategory, false, false},
+		{HashToken, "Hash", NameCategory, false, false},
+		{BadUrlToken, "BadUrl", ErrorCategory, false, false},
+		{DimensionToken, "Dimension", LiteralCategory, false, false},
+		{WhitespaceToken, "Whitespace", TriviaCategory, true, false},
+		{CommentToken, "Comment", TriviaCategory, true, false},
+		{LeftParenthesisToken, "LeftParenthesis", PunctuationCategory, false, true},
+		{RightBracketToken, "RightBracket", PunctuationCategory, false, true},
+		{FunctionToken, "Function", NameCategory, false, false},
+		{CDOToken, "CDO", PunctuationCategory, false, false},
+		{ColumnToken, "Column", PunctuationCategory, false, false},
+		{EOF, "EOF", NoCategory, false, false},
+	}
+
+	for _, v := range values {
+		tt := v.tokenType
+		if tt.String() != v.name || tt.Category() != v.category || tt.IsTrivia() != v.trivia || tt.IsBracket() != v.bracket {
+			t.Fatalf("%s (expected) %s %v %v != %s %s %v %v (actual)", v.name, v.category, v.trivia, v.bracket, tt, tt.Category(), tt.IsTrivia(), tt.IsBracket())
+		}
+	}
+
+	types := TokenTypes()
+	if types[len(types)-1] != EOF {
+		t.Fatalf("last token type (expected) EOF != %v (actual)", types[len(types)-1])
+	}
+
+	for _, tt := range types {
+		if tt != EOF && tt.Category() == NoCategory {
+			t.Fatalf("%v has no category", tt)
+		}
+
+		parsed, err := ParseTokenType(tt.String())
+		if err != nil || parsed != tt {
+			t.Fatalf("ParseTokenType(%q) (expected) %v != %v %v (actual)", tt.String(), tt, parsed, err)
+		}
+	}
+
+	if tt, err := ParseTokenType("bad-url"); err == nil {
+		t.Fatalf("ParseTokenType(\"bad-url\") (expected) error != %v (actual)", tt)
+	}
+
+	if tt, err := ParseTokenType("badurl"); err != nil || tt != BadUrlToken {
+		t.Fatalf("ParseTokenType(\"badurl\") (expected) BadUrl != %v %v (actual)", tt, err)
+	}
+
+	for _, c := range Categories() {
+		if parsed, err := ParseCategory(c.String()); err != nil || parsed != c {
+			t.Fatalf("ParseCategory(%q) (expected) %v != %v %v (actual)", c.String(), c, parsed, err)
+		}
+	}
+}
+
+func TestDialects(t *testing.T) {
+	values := []struct {
+		dialect Dialect
+		css     string
+		tokens  []TestDataToken
+	}{
+		{CSS, "// a", []TestDataToken{{DelimToken, "/"}, {DelimToken, "/"}, {WhitespaceToken, " "}, {IdentToken, "a"}}},
+		{CSS, "$a &b", []TestDataToken{{DelimToken, "$"}, {IdentToken, "a"}, {WhitespaceToken, " "}, {DelimToken, "&"}, {IdentToken, "b"}}},
+		{SCSS, "a // b\nc", []TestDataToken{{IdentToken, "a"}, {WhitespaceToken, " "}, {CommentToken, "// b"}, {WhitespaceToken, "\n"}, {IdentToken, "c"}}},
+		{SCSS, "url(http://a) '//b'", []TestDataToken{{UrlToken, "url(http://a)"}, {WhitespaceToken, " "}, {StringToken, "'//b'"}}},
+		{SCSS, "$main-color: #fff !default;", []TestDataToken{{VariableToken, "$main-color"}, {ColonToken, ":"}, {WhitespaceToken, " "}, {HashToken, "#fff"}, {WhitespaceToken, " "}, {DelimToken, "!"}, {IdentToken, "default"}, {SemicolonToken, ";"}}},
+		{SCSS, "$ $= #{$a}", []TestDataToken{{DelimToken, "$"}, {WhitespaceToken, " "}, {SuffixMatchToken, "$="}, {WhitespaceToken, " "}, {InterpolationToken, "#{"}, {VariableToken, "$a"}, {RightBraceToken, "}"}}},
+		{SCSS, "@mixin m($x) { &:hover, &__el {} } @include m(1);", []TestDataToken{
+			{AtKeywordToken, "@mixin"}, {WhitespaceToken, " "}, {FunctionToken, "m("}, {VariableToken, "$x"}, {RightParenthesisToken, ")"}, {WhitespaceToken, " "},
+			{LeftBraceToken, "{"}, {WhitespaceToken, " "}, {ParentSelectorToken, "&"}, {ColonToken, ":"}, {IdentToken, "hover"}, {CommaToken, ","}, {WhitespaceToken, " "},
+			{ParentSelectorToken, "&__el"}, {WhitespaceToken, " "}, {LeftBraceToken, "{"}, {RightBraceToken, "}"}, {WhitespaceToken, " "}, {RightBraceToken, "}"}, {WhitespaceToken, " "},
+			{AtKeywordToken, "@include"}, {WhitespaceToken, " "}, {FunctionToken, "m("}, {NumberToken, "1"}, {RightParenthesisToken, ")"}, {SemicolonToken, ";"},
+		}},
+		{SCSS, "@a{b}", []TestDataToken{{AtKeywordToken, "@a"}, {LeftBraceToken, "{"}, {IdentToken, "b"}, {RightBraceToken, "}"}}},
+		{Less, "@color : red; @media x { a { color: @color } }", []TestDataToken{
+			{VariableToken, "@color"}, {WhitespaceToken, " "}, {ColonToken, ":"}, {WhitespaceToken, " "}, {IdentToken, "red"}, {SemicolonToken, ";"}, {WhitespaceToken, " "},
+			{AtKeywordToken, "@media"}, {WhitespaceToken, " "}, {IdentToken, "x"}, {WhitespaceToken, " "}, {LeftBraceToken, "{"}, {WhitespaceToken, " "},
+			{IdentToken, "a"}, {WhitespaceToken, " "}, {LeftBraceToken, "{"}, {WhitespaceToken, " "}, {IdentToken, "color"}, {ColonToken, ":"}, {WhitespaceToken, " "},
+			{VariableToken, "@color"}, {WhitespaceToken, " "}, {RightBraceToken, "}"}, {WhitespaceToken, " "}, {RightBraceToken, "}"},
+		}},
+		{Less, "@-webkit-keyframes @media: 1", []TestDataToken{{AtKeywordToken, "@-webkit-keyframes"}, {WhitespaceToken, " "}, {VariableToken, "@media"}, {ColonToken, ":"}, {WhitespaceToken, " "}, {NumberToken, "1"}}},
+		{Less, ".a { .mixin(); .b; } .c.d(@x) {} .5em", []TestDataToken{
+			{DelimToken, "."}, {IdentToken, "a"}, {WhitespaceToken, " "}, {LeftBraceToken, "{"}, {WhitespaceToken, " "},
+			{MixinToken, ".mixin("}, {RightParenthesisToken, ")"}, {SemicolonToken, ";"}, {WhitespaceToken, " "},
+			{DelimToken, "."}, {IdentToken, "b"}, {SemicolonToken, ";"}, {WhitespaceToken, " "}, {RightBraceToken, "}"}, {WhitespaceToken, " "},
+			{DelimToken, "."}, {IdentToken, "c"}, {MixinToken, ".d("}, {VariableToken, "@x"}, {RightParenthesisToken, ")"}, {WhitespaceToken, " "},
+			{LeftBraceToken, "{"}, {RightBraceToken, "}"}, {WhitespaceToken, " "}, {DimensionToken, ".5em"},
+		}},
+		{Less, "~\"@{a}\" .@{b} { // c", []TestDataToken{
+			{DelimToken, "~"}, {StringToken, "\"@{a}\""}, {WhitespaceToken, " "}, {DelimToken, "."}, {InterpolationToken, "@{"}, {IdentToken, "b"}, {RightBraceToken, "}"},
+			{WhitespaceToken, " "}, {LeftBraceToken, "{"}, {WhitespaceToken, " "}, {CommentToken, "// c"},
+		}},
+	}
+
+	for _, v := range values {
+		text := Lexer{Text: []rune(v.css), Dialect: v.dialect}
+		stream := NewLexer(strings.NewReader(v.css))
+		stream.Dialect = v.dialect
+		for _, l := range []*Lexer{&text, stream} {
+			for i, expected := range v.tokens {
+				token := l.NextToken()
+				if token.Type != expected.tokenType || string(token.Val) != expected.val {
+					t.Fatalf("%v %q %d. token (expected) %v %q != %v %q (actual)", v.dialect, v.css, i, expected.tokenType, expected.val, token.Type, string(token.Val))
+				}
+			}
+
+			if token := l.NextToken(); token.Type != EOF {
+				t.Fatalf("%v %q unexpected token %v %q", v.dialect, v.css, token.Type, string(token.Val))
+			}
+		}
+	}
+
+	for _, v := range []struct {
+		path    string
+		dialect Dialect
+	}{{"a.css", CSS}, {"a/b.SCSS", SCSS}, {"b.less", Less}, {"c.sass", CSS}, {"d", CSS}} {
+		if d := DialectOf(v.path); d != v.dialect {
+			t.Fatalf("%s (expected) %v != %v (actual)", v.path, v.dialect, d)
+		}
+	}
+}
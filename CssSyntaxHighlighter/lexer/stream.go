@@ -0,0 +1,97 @@
+package lexer
+
+import (
+	"bufio"
+	"io"
+	"unicode"
+)
+
+// compactThreshold is the number of already shifted runes a streaming lexer
+// keeps in its buffer before moving the rest to a new one.
+const compactThreshold = 4096
+
+// NewLexer returns a lexer that reads its input from r as it tokenizes, so
+// only the current token and a few runes of lookahead are kept in memory.
+// The input is preprocessed as the spec describes and token offsets refer to
+// the preprocessed input.
+// https://www.w3.org/TR/css-syntax-3/#input-preprocessing
+func NewLexer(r io.Reader) *Lexer {
+	rs, ok := r.(io.RuneScanner)
+	if !ok {
+		rs = bufio.NewReader(r)
+	}
+
+	return &Lexer{src: rs}
+}
+
+// Err returns the first read error of a streaming lexer other than io.EOF.
+// The lexer returns an EOF token after a read error.
+func (lex *Lexer) Err() error {
+	if lex.err == io.EOF {
+		return nil
+	}
+
+	return lex.err
+}
+
+// fill makes sure that the buffer holds the rune at index i and reports
+// whether the input is long enough. It may move the buffer, so indexes into
+// it have to be recomputed afterwards.
+func (lex *Lexer) fill(i int) bool {
+	if lex.src == nil {
+		if lex.buf == nil {
+			lex.buf = lex.Text
+		}
+
+		return i < len(lex.buf)
+	}
+
+	if lex.err != nil {
+		return false
+	}
+
+	// Tokens may still reference the old array, so it is never overwritten.
+	if shifted := lex.start - lex.base; shifted >= compactThreshold {
+		buf := make([]rune, len(lex.buf)-shifted, len(lex.buf)-shifted+compactThreshold)
+		copy(buf, lex.buf[shifted:])
+		lex.buf = buf
+		lex.base = lex.start
+		i -= shifted
+	}
+
+	for len(lex.buf) <= i {
+		r, err := lex.readRune()
+		if err != nil {
+			lex.err = err
+			return false
+		}
+
+		lex.buf = append(lex.buf, r)
+	}
+
+	return true
+}
+
+func (lex *Lexer) readRune() (rune, error) {
+	r, _, err := lex.src.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case r == '\r':
+		if r1, _, err := lex.src.ReadRune(); err != nil {
+			lex.err = err
+		} else if r1 != '\n' {
+			lex.src.UnreadRune()
+		}
+
+		return '\n', nil
+	case r == '\f':
+		return '\n', nil
+	case r == 0, r >= 0xD800 && r <= 0xDFFF:
+		return unicode.ReplacementChar, nil
+	default:
+		return r, nil
+	}
+}
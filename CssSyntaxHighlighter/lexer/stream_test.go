@@ -0,0 +1,76 @@
+package lexer
+
+import (
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestNewLexerMatchesTextLexer(t *testing.T) {
+	var sb strings.Builder
+	for i := 0; i < 2000; i++ {
+		sb.WriteString(".icon-\\31 0px:before { content: \"\\f101\"; margin: -1.5e2px 0 url( a.png ) /* é */ }\n")
+	}
+	css := sb.String()
+
+	expected := Lexer{Text: []rune(css)}
+	actual := NewLexer(iotest.OneByteReader(strings.NewReader(css)))
+	tokens := []Token{}
+	for i := 0; ; i++ {
+		e, a := expected.NextToken(), actual.NextToken()
+		if e.Type != a.Type || string(e.Val) != string(a.Val) || string(e.Value) != string(a.Value) {
+			t.Fatalf("%d. token (expected) %v %q != %v %q (actual)", i, e.Type.String(), string(e.Val), a.Type.String(), string(a.Val))
+		}
+
+		if e.Start != a.Start || e.End != a.End || e.Line != a.Line || e.Col != a.Col {
+			t.Fatalf("%d. token position (expected) %d-%d %d:%d != %d-%d %d:%d (actual)", i, e.Start, e.End, e.Line, e.Col, a.Start, a.End, a.Line, a.Col)
+		}
+
+		if e.Type == EOF {
+			break
+		}
+		tokens = append(tokens, a)
+	}
+
+	// Earlier tokens must stay intact while the buffer slides.
+	var out strings.Builder
+	for _, token := range tokens {
+		out.WriteString(string(token.Val))
+	}
+
+	if out.String() != css {
+		t.Fatal("concatenated token values differ from the input")
+	}
+
+	if actual.Err() != nil {
+		t.Fatal(actual.Err())
+	}
+}
+
+func TestNewLexerPreprocessing(t *testing.T) {
+	values := []struct {
+		css    string
+		tokens []TestDataToken
+	}{
+		{"a\r\nb", []TestDataToken{{IdentToken, "a"}, {WhitespaceToken, "\n"}, {IdentToken, "b"}}},
+		{"a\r\rb", []TestDataToken{{IdentToken, "a"}, {WhitespaceToken, "\n\n"}, {IdentToken, "b"}}},
+		{"a\fb\r", []TestDataToken{{IdentToken, "a"}, {WhitespaceToken, "\n"}, {IdentToken, "b"}, {WhitespaceToken, "\n"}}},
+		{"a\x00b", []TestDataToken{{IdentToken, "a\ufffdb"}}},
+		{"\"a\xffb\"", []TestDataToken{{StringToken, "\"a\ufffdb\""}}},
+		{"\"a\\\r\nb\"", []TestDataToken{{StringToken, "\"a\\\nb\""}}},
+	}
+
+	for _, v := range values {
+		l := NewLexer(strings.NewReader(v.css))
+		for i, expected := range v.tokens {
+			token := l.NextToken()
+			if token.Type != expected.tokenType || string(token.Val) != expected.val {
+				t.Fatalf("%q %d. token (expected) %v %q != %v %q (actual)", v.css, i, expected.tokenType.String(), expected.val, token.Type.String(), string(token.Val))
+			}
+		}
+
+		if token := l.NextToken(); token.Type != EOF {
+			t.Fatalf("%q expected EOF, got %v", v.css, token.Type.String())
+		}
+	}
+}
@@ -0,0 +1,44 @@
+// Code generated by gen_tokentypes.go; DO NOT EDIT.
+
+package lexer
+
+var tokenTypes = [...]tokenTypeInfo{
+	ErrorToken:            {"Error", ErrorCategory, false},
+	IdentToken:            {"Ident", NameCategory, false},
+	FunctionToken:         {"Function", NameCategory, false},
+	AtKeywordToken:        {"AtKeyword", NameCategory, false},
+	HashToken:             {"Hash", NameCategory, false},
+	StringToken:           {"String", LiteralCategory, false},
+	BadStringToken:        {"BadString", ErrorCategory, false},
+	UrlToken:              {"Url", LiteralCategory, false},
+	BadUrlToken:           {"BadUrl", ErrorCategory, false},
+	NumberToken:           {"Number", LiteralCategory, false},
+	DimensionToken:        {"Dimension", LiteralCategory, false},
+	PercentageToken:       {"Percentage", LiteralCategory, false},
+	WhitespaceToken:       {"Whitespace", TriviaCategory, false},
+	LeftParenthesisToken:  {"LeftParenthesis", PunctuationCategory, true},
+	RightParenthesisToken: {"RightParenthesis", PunctuationCategory, true},
+	LeftBraceToken:        {"LeftBrace", PunctuationCategory, true},
+	RightBraceToken:       {"RightBrace", PunctuationCategory, true},
+	ColonToken:            {"Colon", PunctuationCategory, false},
+	SemicolonToken:        {"Semicolon", PunctuationCategory, false},
+	CommaToken:            {"Comma", PunctuationCategory, false},
+	CommentToken:          {"Comment", TriviaCategory, false},
+	AtToken:               {"At", PunctuationCategory, false},
+	CDOToken:              {"CDO", PunctuationCategory, false},
+	CDCToken:              {"CDC", PunctuationCategory, false},
+	DelimToken:            {"Delim", PunctuationCategory, false},
+	LeftBracketToken:      {"LeftBracket", PunctuationCategory, true},
+	RightBracketToken:     {"RightBracket", PunctuationCategory, true},
+	IncludeMatchToken:     {"IncludeMatch", PunctuationCategory, false},
+	DashMatchToken:        {"DashMatch", PunctuationCategory, false},
+	PrefixMatchToken:      {"PrefixMatch", PunctuationCategory, false},
+	SuffixMatchToken:      {"SuffixMatch", PunctuationCategory, false},
+	SubstringMatchToken:   {"SubstringMatch", PunctuationCategory, false},
+	ColumnToken:           {"Column", PunctuationCategory, false},
+	VariableToken:         {"Variable", NameCategory, false},
+	InterpolationToken:    {"Interpolation", PunctuationCategory, true},
+	ParentSelectorToken:   {"ParentSelector", NameCategory, false},
+	MixinToken:            {"Mixin", NameCategory, false},
+	EOF:                   {"EOF", NoCategory, false},
+}
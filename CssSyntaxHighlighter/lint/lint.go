@@ -0,0 +1,316 @@
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/QuickOrBeDead/GoLangLearning/cssdata"
+	"github.com/QuickOrBeDead/GoLangLearning/lexer"
+	"github.com/QuickOrBeDead/GoLangLearning/parser"
+	"github.com/QuickOrBeDead/GoLangLearning/selector"
+)
+
+type Severity int
+
+const (
+	Info Severity = iota
+	Warning
+	Error
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Info:
+		return "info"
+	case Warning:
+		return "warning"
+	case Error:
+		return "error"
+	default:
+		return ""
+	}
+}
+
+func ParseSeverity(s string) (Severity, error) {
+	switch s {
+	case "info":
+		return Info, nil
+	case "warning":
+		return Warning, nil
+	case "error":
+		return Error, nil
+	default:
+		return 0, fmt.Errorf("unknown severity %q", s)
+	}
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *Severity) UnmarshalText(b []byte) error {
+	v, err := ParseSeverity(string(b))
+	*s = v
+	return err
+}
+
+// Problem is a problem a rule found in a file.
+type Problem struct {
+	File     string   `json:"file"`
+	Line     int      `json:"line"`
+	Col      int      `json:"col"`
+	Severity Severity `json:"severity"`
+	Rule     string   `json:"rule"`
+	Message  string   `json:"message"`
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s: %s (%s)", p.File, p.Line, p.Col, p.Severity, p.Message, p.Rule)
+}
+
+// Block is a declaration block of a style rule, a keyframe or an at-rule
+// like @font-face.
+type Block struct {
+	// AtRule is the lowercase name of the at-rule the block belongs to, ""
+	// for style rules.
+	AtRule string
+	parser.Span
+	// Selectors are the selectors of a style rule, nil when they are not
+	// valid.
+	Selectors selector.List
+	Items     []parser.BlockItem
+}
+
+// Declarations returns the declarations of the block.
+func (b *Block) Declarations() []*parser.Declaration {
+	declarations := []*parser.Declaration{}
+	for _, item := range b.Items {
+		if d, ok := item.(*parser.Declaration); ok {
+			declarations = append(declarations, d)
+		}
+	}
+
+	return declarations
+}
+
+// Reporter collects the problems of a rule.
+type Reporter interface {
+	Report(pos parser.Span, message string)
+}
+
+// Rule checks the declaration blocks of a stylesheet.
+type Rule interface {
+	Name() string
+	DefaultSeverity() Severity
+	Check(b *Block, r Reporter)
+}
+
+// Configurable is implemented by rules that take options from the config
+// file.
+type Configurable interface {
+	Configure(options json.RawMessage) error
+}
+
+// Config is the JSON config file of the linter. Rules that are not in it run
+// with their default severity.
+//
+//	{"rules": {"important": "off", "zero-units": "error", "overly-specific-selector": {"options": {"max": [0, 3, 0]}}}}
+type Config struct {
+	Rules map[string]RuleConfig `json:"rules"`
+}
+
+// RuleConfig is either "off", a severity, or an object with the severity
+// and the options of the rule.
+type RuleConfig struct {
+	Off      bool            `json:"off,omitempty"`
+	Severity *Severity       `json:"severity,omitempty"`
+	Options  json.RawMessage `json:"options,omitempty"`
+}
+
+func (c *RuleConfig) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		if s == "off" {
+			c.Off = true
+			return nil
+		}
+
+		severity, err := ParseSeverity(s)
+		c.Severity = &severity
+		return err
+	}
+
+	type plain RuleConfig
+	return json.Unmarshal(b, (*plain)(c))
+}
+
+func LoadConfig(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Config{}
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return c, nil
+}
+
+type Linter struct {
+	rules      []Rule
+	severities map[string]Severity
+}
+
+// New returns a linter with the rules enabled by c, or with all the default
+// rules when c is nil.
+func New(c *Config, rules []Rule) (*Linter, error) {
+	l := &Linter{severities: make(map[string]Severity)}
+	names := make(map[string]bool)
+	for _, r := range rules {
+		names[r.Name()] = true
+		rc := RuleConfig{}
+		if c != nil {
+			rc = c.Rules[r.Name()]
+		}
+
+		if rc.Off {
+			continue
+		}
+
+		l.severities[r.Name()] = r.DefaultSeverity()
+		if rc.Severity != nil {
+			l.severities[r.Name()] = *rc.Severity
+		}
+
+		if rc.Options != nil {
+			configurable, ok := r.(Configurable)
+			if !ok {
+				return nil, fmt.Errorf("rule %s has no options", r.Name())
+			}
+
+			if err := configurable.Configure(rc.Options); err != nil {
+				return nil, fmt.Errorf("rule %s: %w", r.Name(), err)
+			}
+		}
+
+		l.rules = append(l.rules, r)
+	}
+
+	if c != nil {
+		for name := range c.Rules {
+			if !names[name] {
+				return nil, fmt.Errorf("unknown rule %s", name)
+			}
+		}
+	}
+
+	return l, nil
+}
+
+type reporter struct {
+	file     string
+	rule     string
+	severity Severity
+	problems *[]Problem
+}
+
+func (r reporter) Report(pos parser.Span, message string) {
+	*r.problems = append(*r.problems, Problem{File: r.file, Line: pos.Line, Col: pos.Col, Severity: r.severity, Rule: r.rule, Message: message})
+}
+
+// Lint returns the problems of the stylesheet read by lex sorted by their
+// position. Syntax errors are reported as problems of the syntax rule.
+func (l *Linter) Lint(file string, lex *lexer.Lexer) ([]Problem, error) {
+	problems := []Problem{}
+	p := parser.New(lex)
+	s := p.ParseStylesheet()
+	if err := lex.Err(); err != nil {
+		return nil, err
+	}
+
+	syntax := reporter{file: file, rule: "syntax", severity: Error, problems: &problems}
+	for _, e := range p.Errors {
+		syntax.Report(parser.Span{Line: e.Line, Col: e.Col}, e.Msg)
+	}
+
+	blocks := []*Block{}
+	var walk func(rules []parser.Rule)
+	var declarations func(atRule string, span parser.Span, selectors selector.List, b *parser.SimpleBlock)
+	declarations = func(atRule string, span parser.Span, selectors selector.List, b *parser.SimpleBlock) {
+		p := parser.NewFromValues(b.Values)
+		items := p.ParseDeclarationList()
+		blocks = append(blocks, &Block{AtRule: atRule, Span: span, Selectors: selectors, Items: items})
+		for _, e := range p.Errors {
+			syntax.Report(parser.Span{Line: e.Line, Col: e.Col}, e.Msg)
+		}
+
+		// The rules nested in the block, as in CSS nesting, have blocks of
+		// declarations too. Their selectors may start with & or a
+		// combinator, which the selector parser does not take, so they are
+		// not checked.
+		for _, item := range items {
+			switch item := item.(type) {
+			case *parser.QualifiedRule:
+				list, _ := selector.Parse(item.Prelude)
+				declarations("", item.Span, list, item.Block)
+			case *parser.AtRule:
+				if item.Block != nil {
+					declarations(strings.ToLower(item.Name()), item.Span, nil, item.Block)
+				}
+			}
+		}
+	}
+	walk = func(rules []parser.Rule) {
+		for _, r := range rules {
+			switch r := r.(type) {
+			case *parser.QualifiedRule:
+				list, err := selector.Parse(r.Prelude)
+				if e, ok := err.(parser.Error); ok {
+					syntax.Report(parser.Span{Line: e.Line, Col: e.Col}, e.Msg)
+				}
+				declarations("", r.Span, list, r.Block)
+			case *parser.AtRule:
+				if r.Block == nil {
+					continue
+				}
+
+				name := strings.ToLower(r.Name())
+				switch {
+				case cssdata.KeyframesRules[name]:
+					for _, k := range r.Block.Rules() {
+						if k, ok := k.(*parser.QualifiedRule); ok {
+							declarations(name, k.Span, nil, k.Block)
+						}
+					}
+				case cssdata.GroupRules[name]:
+					walk(r.Block.Rules())
+				default:
+					declarations(name, r.Span, nil, r.Block)
+				}
+			}
+		}
+	}
+	walk(s.Rules)
+
+	for _, r := range l.rules {
+		rep := reporter{file: file, rule: r.Name(), severity: l.severities[r.Name()], problems: &problems}
+		for _, b := range blocks {
+			r.Check(b, rep)
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].Line != problems[j].Line {
+			return problems[i].Line < problems[j].Line
+		}
+
+		return problems[i].Col < problems[j].Col
+	})
+
+	return problems, nil
+}
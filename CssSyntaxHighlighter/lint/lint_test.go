@@ -0,0 +1,150 @@
+package lint
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/QuickOrBeDead/GoLangLearning/lexer"
+)
+
+func lint(t *testing.T, c *Config, css string) []string {
+	l, err := New(c, DefaultRules())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	problems, err := l.Lint("a.css", &lexer.Lexer{Text: []rune(css)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := make([]string, len(problems))
+	for i, p := range problems {
+		lines[i] = p.String()
+	}
+
+	return lines
+}
+
+func TestRules(t *testing.T) {
+	values := []struct {
+		css      string
+		problems []string
+	}{
+		{"a { color: red; --x: #zz }", nil},
+		{"a { colour: red; -webkit-foo: 1; --x: 1 }", []string{`a.css:1:5: warning: unknown property "colour" (unknown-property)`}},
+		{"a { color: red; top: 0; color: red }\nb { display: -webkit-box; display: flex }", []string{`a.css:1:25: warning: duplicate property "color", first set at 1:5 (duplicate-property)`}},
+		{"a { color: #abcd; background: url(x) #ab; border-color: #12345g }", []string{
+			`a.css:1:38: error: invalid hex color "#ab" (invalid-hex-color)`,
+			`a.css:1:57: error: invalid hex color "#12345g" (invalid-hex-color)`,
+		}},
+		{"a { content: \"x\n; background: url(a b) }", []string{
+			`a.css:1:14: error: invalid BadString token "\"x" (invalid-token)`,
+			`a.css:2:15: error: invalid BadUrl token "url(a b)" (invalid-token)`,
+		}},
+		{"a { margin: 0px 0 0% 0.0em; width: calc(0px + 1em); height: -webkit-calc(0px + 1em); flex: 1 1 0px }", []string{
+			`a.css:1:13: info: unit of zero length "0px" is not needed (zero-units)`,
+			`a.css:1:22: info: unit of zero length "0.0em" is not needed (zero-units)`,
+		}},
+		{"a { color: red !important }", []string{`a.css:1:5: warning: !important used in "color" (important)`}},
+		{"a { }\nb { /* c */ }\n@font-face { }", []string{"a.css:1:1: warning: empty rule (empty-rule)", "a.css:2:1: warning: empty rule (empty-rule)"}},
+		{"a { -webkit-transition: x; -moz-box-sizing: b; box-sizing: b; -webkit-tap-highlight-color: red }", []string{
+			`a.css:1:5: warning: vendor-prefixed property "-webkit-transition" without the standard "transition" (vendor-prefix)`,
+		}},
+		{"#a .b { top: 0 }\n.a .b .c .d .e, .f { top: 0 }", []string{
+			`a.css:1:1: warning: selector "#a .b" has specificity (1,1,0), more than (0,4,0) (overly-specific-selector)`,
+			`a.css:2:1: warning: selector ".a .b .c .d .e" has specificity (0,5,0), more than (0,4,0) (overly-specific-selector)`,
+		}},
+		{"@media print { a { top: 0px } }\n@keyframes k { from { top: 0 } }\n@-moz-keyframes k { 50% { top: 0px } }", []string{
+			`a.css:1:25: info: unit of zero length "0px" is not needed (zero-units)`,
+			`a.css:3:32: info: unit of zero length "0px" is not needed (zero-units)`,
+		}},
+		{"a { .b { top: 0px } & > c { } @media print { color: red !important } }", []string{
+			`a.css:1:15: info: unit of zero length "0px" is not needed (zero-units)`,
+			"a.css:1:21: warning: empty rule (empty-rule)",
+			`a.css:1:46: warning: !important used in "color" (important)`,
+		}},
+		{"a..b { top: 0 }\nc { 1; top: 0 }", []string{"a.css:1:3: error: expected a class name after '.' (syntax)", "a.css:2:5: error: expected a declaration (syntax)"}},
+	}
+
+	for _, v := range values {
+		problems := lint(t, nil, v.css)
+		if strings.Join(problems, "\n") != strings.Join(v.problems, "\n") {
+			t.Fatalf("%s (expected)\n%s\n!= (actual)\n%s", v.css, strings.Join(v.problems, "\n"), strings.Join(problems, "\n"))
+		}
+	}
+}
+
+func TestConfig(t *testing.T) {
+	c := &Config{}
+	err := json.Unmarshal([]byte(`{"rules": {
+		"important": "off",
+		"zero-units": "error",
+		"overly-specific-selector": {"severity": "info", "options": {"max": [0, 1, 0]}}
+	}}`), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	problems := lint(t, c, "a.b.c { top: 0px !important }")
+	expected := []string{
+		`a.css:1:1: info: selector "a.b.c" has specificity (0,2,1), more than (0,1,0) (overly-specific-selector)`,
+		`a.css:1:14: error: unit of zero length "0px" is not needed (zero-units)`,
+	}
+	if strings.Join(problems, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("(expected) %q != %q (actual)", expected, problems)
+	}
+
+	for _, config := range []string{
+		`{"rules": {"missing": "off"}}`,
+		`{"rules": {"important": "fatal"}}`,
+		`{"rules": {"important": {"options": {}}}}`,
+		`{"rules": {"overly-specific-selector": {"options": {"max": "x"}}}}`,
+	} {
+		c := &Config{}
+		err := json.Unmarshal([]byte(config), c)
+		if err == nil {
+			_, err = New(c, DefaultRules())
+		}
+
+		if err == nil {
+			t.Fatalf("%s expected an error", config)
+		}
+	}
+}
+
+func TestWriteCheckstyle(t *testing.T) {
+	problems := []Problem{
+		{File: "a.css", Line: 1, Col: 2, Severity: Warning, Rule: "important", Message: `"x" <y>`},
+		{File: "b.css", Line: 3, Col: 4, Severity: Error, Rule: "syntax", Message: "z"},
+	}
+
+	var sb strings.Builder
+	if err := WriteCheckstyle(&sb, problems); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<checkstyle version="4.3">
+  <file name="a.css">
+    <error line="1" column="2" severity="warning" message="&#34;x&#34; &lt;y&gt;" source="csslint.important"></error>
+  </file>
+  <file name="b.css">
+    <error line="3" column="4" severity="error" message="z" source="csslint.syntax"></error>
+  </file>
+</checkstyle>
+`
+	if sb.String() != expected {
+		t.Fatalf("(expected) %q != %q (actual)", expected, sb.String())
+	}
+
+	sb.Reset()
+	if err := WriteJSON(&sb, problems[1:]); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(sb.String(), `"severity": "error"`) {
+		t.Fatalf("unexpected json %s", sb.String())
+	}
+}
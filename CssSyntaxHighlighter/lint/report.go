@@ -0,0 +1,80 @@
+package lint
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// WriteText writes one problem per line as file:line:col: severity: message.
+func WriteText(w io.Writer, problems []Problem) error {
+	for _, p := range problems {
+		if _, err := fmt.Fprintln(w, p.String()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func WriteJSON(w io.Writer, problems []Problem) error {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(problems)
+}
+
+type checkstyleError struct {
+	Line     int    `xml:"line,attr"`
+	Column   int    `xml:"column,attr"`
+	Severity string `xml:"severity,attr"`
+	Message  string `xml:"message,attr"`
+	Source   string `xml:"source,attr"`
+}
+
+type checkstyleFile struct {
+	Name   string            `xml:"name,attr"`
+	Errors []checkstyleError `xml:"error"`
+}
+
+type checkstyle struct {
+	XMLName struct{}         `xml:"checkstyle"`
+	Version string           `xml:"version,attr"`
+	Files   []checkstyleFile `xml:"file"`
+}
+
+// WriteCheckstyle writes the problems in the checkstyle XML format CI servers
+// read. Files are listed in the order of their first problem.
+func WriteCheckstyle(w io.Writer, problems []Problem) error {
+	c := checkstyle{Version: "4.3", Files: []checkstyleFile{}}
+	files := make(map[string]int)
+	for _, p := range problems {
+		i, ok := files[p.File]
+		if !ok {
+			i = len(c.Files)
+			files[p.File] = i
+			c.Files = append(c.Files, checkstyleFile{Name: p.File})
+		}
+
+		c.Files[i].Errors = append(c.Files[i].Errors, checkstyleError{
+			Line:     p.Line,
+			Column:   p.Col,
+			Severity: p.Severity.String(),
+			Message:  p.Message,
+			Source:   "csslint." + p.Rule,
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	e := xml.NewEncoder(w)
+	e.Indent("", "  ")
+	if err := e.Encode(c); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
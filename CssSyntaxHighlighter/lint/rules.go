@@ -0,0 +1,266 @@
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/QuickOrBeDead/GoLangLearning/cssdata"
+	"github.com/QuickOrBeDead/GoLangLearning/lexer"
+	"github.com/QuickOrBeDead/GoLangLearning/parser"
+	"github.com/QuickOrBeDead/GoLangLearning/selector"
+)
+
+// DefaultRules returns new instances of all the rules.
+func DefaultRules() []Rule {
+	return []Rule{
+		&UnknownProperty{},
+		&DuplicateProperty{},
+		&InvalidHexColor{},
+		&InvalidToken{},
+		&ZeroUnits{},
+		&Important{},
+		&EmptyRule{},
+		&VendorPrefix{},
+		&OverlySpecificSelector{Max: selector.Specificity{0, 4, 0}},
+	}
+}
+
+// UnknownProperty reports properties that are not standard. Custom and
+// vendor-prefixed properties are not checked.
+type UnknownProperty struct{}
+
+func (*UnknownProperty) Name() string              { return "unknown-property" }
+func (*UnknownProperty) DefaultSeverity() Severity { return Warning }
+
+func (*UnknownProperty) Check(b *Block, r Reporter) {
+	for _, d := range b.Declarations() {
+		name := strings.ToLower(d.Name())
+		if _, prefixed := unprefixed(name); prefixed || strings.HasPrefix(name, "--") {
+			continue
+		}
+
+		if !knownProperties[name] {
+			r.Report(d.Span, fmt.Sprintf("unknown property %q", d.Name()))
+		}
+	}
+}
+
+// DuplicateProperty reports properties that are set twice in a block.
+// Consecutive declarations with different values are allowed, they are the
+// usual fallbacks for older browsers.
+type DuplicateProperty struct{}
+
+func (*DuplicateProperty) Name() string              { return "duplicate-property" }
+func (*DuplicateProperty) DefaultSeverity() Severity { return Warning }
+
+func (*DuplicateProperty) Check(b *Block, r Reporter) {
+	last := make(map[string]int)
+	declarations := b.Declarations()
+	for i, d := range declarations {
+		name := d.Name()
+		if !strings.HasPrefix(name, "--") {
+			name = strings.ToLower(name)
+		}
+
+		if j, ok := last[name]; ok && (j != i-1 || valueString(declarations[j]) == valueString(d)) {
+			prev := declarations[j]
+			r.Report(d.Span, fmt.Sprintf("duplicate property %q, first set at %d:%d", d.Name(), prev.Line, prev.Col))
+		}
+		last[name] = i
+	}
+}
+
+// InvalidHexColor reports hash tokens in values that are not 3, 4, 6 or 8
+// hex digits.
+type InvalidHexColor struct{}
+
+func (*InvalidHexColor) Name() string              { return "invalid-hex-color" }
+func (*InvalidHexColor) DefaultSeverity() Severity { return Error }
+
+func (*InvalidHexColor) Check(b *Block, r Reporter) {
+	for _, d := range b.Declarations() {
+		if strings.HasPrefix(d.Name(), "--") {
+			continue
+		}
+
+		eachToken(d.Value, nil, func(t lexer.Token, _ []string) {
+			if t.Type == lexer.HashToken && !isHexColor(string(t.Value)) {
+				r.Report(position(t), fmt.Sprintf("invalid hex color %q", string(t.Val)))
+			}
+		})
+	}
+}
+
+// InvalidToken reports tokens of the error category in values, like unclosed
+// strings and malformed urls.
+type InvalidToken struct{}
+
+func (*InvalidToken) Name() string              { return "invalid-token" }
+func (*InvalidToken) DefaultSeverity() Severity { return Error }
+
+func (*InvalidToken) Check(b *Block, r Reporter) {
+	for _, d := range b.Declarations() {
+		eachToken(d.Value, nil, func(t lexer.Token, _ []string) {
+			if t.Type.Category() == lexer.ErrorCategory {
+				r.Report(position(t), fmt.Sprintf("invalid %s token %q", t.Type, string(t.Val)))
+			}
+		})
+	}
+}
+
+func isHexColor(v string) bool {
+	if len(v) != 3 && len(v) != 4 && len(v) != 6 && len(v) != 8 {
+		return false
+	}
+
+	for _, c := range v {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ZeroUnits reports zero lengths with a unit outside of math functions,
+// except in flex and flex-basis where the unit matters.
+type ZeroUnits struct{}
+
+func (*ZeroUnits) Name() string              { return "zero-units" }
+func (*ZeroUnits) DefaultSeverity() Severity { return Info }
+
+func (*ZeroUnits) Check(b *Block, r Reporter) {
+	for _, d := range b.Declarations() {
+		name := strings.ToLower(d.Name())
+		if strings.HasPrefix(name, "--") || name == "flex" || name == "flex-basis" {
+			continue
+		}
+
+		eachToken(d.Value, nil, func(t lexer.Token, functions []string) {
+			for _, f := range functions {
+				if cssdata.MathFunctions[f] {
+					return
+				}
+			}
+
+			if t.Type == lexer.DimensionToken && t.Number == 0 && cssdata.LengthUnits[strings.ToLower(string(t.Unit))] {
+				r.Report(position(t), fmt.Sprintf("unit of zero length %q is not needed", string(t.Val)))
+			}
+		})
+	}
+}
+
+type Important struct{}
+
+func (*Important) Name() string              { return "important" }
+func (*Important) DefaultSeverity() Severity { return Warning }
+
+func (*Important) Check(b *Block, r Reporter) {
+	for _, d := range b.Declarations() {
+		if d.Important {
+			r.Report(d.Span, fmt.Sprintf("!important used in %q", d.Name()))
+		}
+	}
+}
+
+// EmptyRule reports style rules without declarations.
+type EmptyRule struct{}
+
+func (*EmptyRule) Name() string              { return "empty-rule" }
+func (*EmptyRule) DefaultSeverity() Severity { return Warning }
+
+func (*EmptyRule) Check(b *Block, r Reporter) {
+	if b.AtRule != "" {
+		return
+	}
+
+	for _, item := range b.Items {
+		if _, ok := item.(*parser.Comment); !ok {
+			return
+		}
+	}
+
+	r.Report(b.Span, "empty rule")
+}
+
+// VendorPrefix reports vendor-prefixed properties without the standard
+// property in the same block.
+type VendorPrefix struct{}
+
+func (*VendorPrefix) Name() string              { return "vendor-prefix" }
+func (*VendorPrefix) DefaultSeverity() Severity { return Warning }
+
+func (*VendorPrefix) Check(b *Block, r Reporter) {
+	names := make(map[string]bool)
+	for _, d := range b.Declarations() {
+		names[strings.ToLower(d.Name())] = true
+	}
+
+	for _, d := range b.Declarations() {
+		standard, prefixed := unprefixed(strings.ToLower(d.Name()))
+		if prefixed && knownProperties[standard] && !names[standard] {
+			r.Report(d.Span, fmt.Sprintf("vendor-prefixed property %q without the standard %q", d.Name(), standard))
+		}
+	}
+}
+
+// OverlySpecificSelector reports selectors that are more specific than Max.
+type OverlySpecificSelector struct {
+	Max selector.Specificity
+}
+
+func (*OverlySpecificSelector) Name() string              { return "overly-specific-selector" }
+func (*OverlySpecificSelector) DefaultSeverity() Severity { return Warning }
+
+// Configure reads {"max": [a, b, c]}.
+func (o *OverlySpecificSelector) Configure(options json.RawMessage) error {
+	var v struct {
+		Max *selector.Specificity `json:"max"`
+	}
+	if err := json.Unmarshal(options, &v); err != nil {
+		return err
+	}
+
+	if v.Max != nil {
+		o.Max = *v.Max
+	}
+
+	return nil
+}
+
+func (o *OverlySpecificSelector) Check(b *Block, r Reporter) {
+	for _, c := range b.Selectors {
+		if s := c.Specificity(); s.Compare(o.Max) > 0 {
+			r.Report(c.Span, fmt.Sprintf("selector %q has specificity %s, more than %s", c.String(), s, o.Max))
+		}
+	}
+}
+
+// eachToken calls f with the preserved tokens of values and the lowercase
+// names of the functions they are in.
+func eachToken(values []parser.ComponentValue, functions []string, f func(t lexer.Token, functions []string)) {
+	for _, v := range values {
+		switch v := v.(type) {
+		case *parser.Token:
+			f(v.Token, functions)
+		case *parser.Function:
+			eachToken(v.Values, append(functions, strings.ToLower(v.Name())), f)
+		case *parser.SimpleBlock:
+			eachToken(v.Values, functions, f)
+		}
+	}
+}
+
+func position(t lexer.Token) parser.Span {
+	return parser.Span{Start: t.Start, Stop: t.End, Line: t.Line, Col: t.Col}
+}
+
+func valueString(d *parser.Declaration) string {
+	var sb strings.Builder
+	for _, v := range d.Value {
+		sb.WriteString(v.String())
+	}
+
+	return sb.String()
+}
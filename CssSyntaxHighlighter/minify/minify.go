@@ -0,0 +1,607 @@
+package minify
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	"github.com/QuickOrBeDead/GoLangLearning/cssdata"
+	"github.com/QuickOrBeDead/GoLangLearning/lexer"
+	"github.com/QuickOrBeDead/GoLangLearning/sourcemap"
+)
+
+type blockKind int
+
+const (
+	rulesBlock blockKind = iota
+	declarationsBlock
+)
+
+// minifier tracks just enough of the stylesheet structure to know where
+// whitespace is significant and where values may be shortened.
+type minifier struct {
+	w   *bufio.Writer
+	lex *lexer.Lexer
+	// current is the token being minified and ahead the tokens after it
+	// that were read to tell nested rules from declarations.
+	current lexer.Token
+	ahead   []lexer.Token
+	// sourceMap is nil when no source map is written, source is the name of
+	// the input in it.
+	sourceMap *sourcemap.Generator
+	source    string
+	// blocks is the stack of open {} blocks.
+	blocks []blockKind
+	// prelude is the name of the at-rule whose prelude is being written, ""
+	// for a selector.
+	prelude string
+	// inValue is set after the colon of a declaration, property is its name.
+	inValue  bool
+	property string
+	// selector is set in the selector of a rule nested in a declaration
+	// block.
+	selector bool
+	// functions is the stack of open functions and blocks inside a prelude
+	// or a declaration, "" for () and [] blocks.
+	functions []string
+
+	prev lexer.Token
+	// pendingSpace is set when whitespace comes before the next token and
+	// pendingComment when only comments do.
+	pendingSpace   bool
+	pendingComment bool
+}
+
+// Minify writes the tokens of lex to w without comments and with as little
+// whitespace as keeps the stylesheet equivalent. Comments that separate two
+// tokens are written as /**/. Numbers, zero lengths and hex colors in
+// declaration values are shortened. A string or url() cut off by the end of
+// the input is closed, so that another stylesheet can follow the output.
+// The tokens are written as they are read, with a few tokens of lookahead.
+func Minify(w io.Writer, lex *lexer.Lexer) error {
+	return MinifyWithSourceMap(w, lex, nil, "")
+}
+
+// MinifyWithSourceMap minifies like Minify and maps every token of the output
+// to its position in the input, which is named source in the map.
+func MinifyWithSourceMap(w io.Writer, lex *lexer.Lexer, sourceMap *sourcemap.Generator, source string) error {
+	m := &minifier{w: bufio.NewWriter(w), lex: lex, sourceMap: sourceMap, source: source, prev: lexer.Token{Type: lexer.EOF}}
+	for m.current = m.next(); m.current.Type != lexer.EOF; m.current = m.next() {
+		m.token(m.current)
+	}
+
+	if err := lex.Err(); err != nil {
+		return err
+	}
+
+	return m.w.Flush()
+}
+
+// next returns the token after the current one.
+func (m *minifier) next() lexer.Token {
+	if len(m.ahead) == 0 {
+		return m.lex.NextToken()
+	}
+
+	t := m.ahead[0]
+	m.ahead = m.ahead[1:]
+	return t
+}
+
+// peek returns the token i tokens after the current one, or the current one
+// when i is 0, without moving past it.
+func (m *minifier) peek(i int) lexer.Token {
+	if i == 0 {
+		return m.current
+	}
+
+	for len(m.ahead) < i {
+		if n := len(m.ahead); n > 0 && m.ahead[n-1].Type == lexer.EOF {
+			return m.ahead[n-1]
+		}
+		m.ahead = append(m.ahead, m.lex.NextToken())
+	}
+
+	return m.ahead[i-1]
+}
+
+// String minifies css.
+func String(css string) string {
+	var sb strings.Builder
+	Minify(&sb, &lexer.Lexer{Text: []rune(css)})
+	return sb.String()
+}
+
+func (m *minifier) token(t lexer.Token) {
+	if m.sourceMap != nil {
+		m.sourceMap.WriteSource(m.source, string(t.Val))
+	}
+
+	switch {
+	case t.Type == lexer.WhitespaceToken:
+		m.pendingSpace = true
+		return
+	case t.Type.IsTrivia():
+		m.pendingComment = true
+		return
+	}
+
+	if m.atStart() && m.inDeclarations() && t.Type != lexer.AtKeywordToken {
+		m.selector = !isDeclaration(m.peek)
+	}
+
+	val := closeAtEOF(t)
+	if m.inDeclarations() && m.inValue && !strings.HasPrefix(m.property, "--") {
+		val = m.shorten(t)
+	}
+
+	// A comment between tokens that would run together is kept as an empty
+	// one, since a space could be a descendant combinator.
+	if m.prev.Type != lexer.EOF {
+		switch {
+		case m.pendingSpace && (needsSeparator(m.prev, t) || m.significantSpace(t)):
+			m.write(" ")
+		case needsSeparator(m.prev, t) && m.pendingComment:
+			m.write("/**/")
+		case needsSeparator(m.prev, t):
+			m.write(" ")
+		}
+	}
+
+	if m.sourceMap != nil {
+		m.sourceMap.Add(m.source, t.Line, t.Col)
+	}
+	m.write(val)
+	m.pendingSpace = false
+	m.pendingComment = false
+	m.prev = t
+	if t.Type == lexer.DimensionToken && len(val) == numberLength(t.Val) {
+		m.prev.Type = lexer.NumberToken
+	}
+
+	m.track(t)
+}
+
+func (m *minifier) write(s string) {
+	m.w.WriteString(s)
+	if m.sourceMap != nil {
+		m.sourceMap.WriteString(s)
+	}
+}
+
+// track updates the position in the stylesheet structure after t.
+func (m *minifier) track(t lexer.Token) {
+	switch t.Type {
+	case lexer.LeftBraceToken:
+		// {} blocks in values, like those of custom properties, are a part
+		// of the value.
+		if len(m.functions) > 0 || (m.inDeclarations() && m.inValue) {
+			m.functions = append(m.functions, "")
+			return
+		}
+
+		// Group rules nested in a declaration block contain declarations
+		// too.
+		kind := declarationsBlock
+		if cssdata.GroupRules[m.prelude] && (len(m.blocks) == 0 || m.blocks[len(m.blocks)-1] == rulesBlock) {
+			kind = rulesBlock
+		}
+		m.blocks = append(m.blocks, kind)
+		m.reset()
+	case lexer.RightBraceToken:
+		if len(m.functions) > 0 {
+			m.functions = m.functions[:len(m.functions)-1]
+			return
+		}
+
+		if len(m.blocks) > 0 {
+			m.blocks = m.blocks[:len(m.blocks)-1]
+		}
+		m.reset()
+	case lexer.SemicolonToken:
+		if len(m.functions) == 0 {
+			m.reset()
+		}
+	case lexer.FunctionToken:
+		m.functions = append(m.functions, strings.ToLower(string(t.Value)))
+	case lexer.LeftParenthesisToken, lexer.LeftBracketToken:
+		m.functions = append(m.functions, "")
+	case lexer.RightParenthesisToken, lexer.RightBracketToken:
+		if len(m.functions) > 0 {
+			m.functions = m.functions[:len(m.functions)-1]
+		}
+	case lexer.AtKeywordToken:
+		if m.atStart() {
+			m.prelude = strings.ToLower(string(t.Value))
+		}
+	case lexer.IdentToken:
+		if m.inDeclarations() && !m.inValue && m.property == "" {
+			m.property = strings.ToLower(string(t.Value))
+		}
+	case lexer.ColonToken:
+		if m.inDeclarations() && len(m.functions) == 0 && m.property != "" {
+			m.inValue = true
+		}
+	}
+}
+
+func (m *minifier) reset() {
+	m.prelude = ""
+	m.inValue = false
+	m.property = ""
+	m.selector = false
+	m.functions = m.functions[:0]
+}
+
+func (m *minifier) inDeclarations() bool {
+	return len(m.blocks) > 0 && m.blocks[len(m.blocks)-1] == declarationsBlock && m.prelude == "" && !m.selector
+}
+
+// isDeclaration reports whether the item of a declaration block that starts
+// with the token peek(0) is a declaration and not a nested rule. Like in the
+// parser, an ident and a colon start a declaration, unless its value has a {}
+// block together with other values and it is not a custom property. peek is
+// called only up to the first token that decides it.
+// https://www.w3.org/TR/css-syntax-3/#consume-declaration
+func isDeclaration(peek func(i int) lexer.Token) bool {
+	i := skipTrivia(peek, 0)
+	if peek(i).Type != lexer.IdentToken {
+		return false
+	}
+
+	if strings.HasPrefix(string(peek(i).Val), "--") {
+		return peek(skipTrivia(peek, i+1)).Type == lexer.ColonToken
+	}
+
+	i = skipTrivia(peek, i+1)
+	if peek(i).Type != lexer.ColonToken {
+		return false
+	}
+
+	depth, values, block := 0, 0, false
+	for i++; peek(i).Type != lexer.EOF; i++ {
+		t := peek(i)
+		if t.Type.IsTrivia() {
+			continue
+		}
+
+		if depth == 0 {
+			if t.Type == lexer.SemicolonToken || t.Type == lexer.RightBraceToken {
+				break
+			}
+			values++
+			block = block || t.Type == lexer.LeftBraceToken
+			if block && values > 1 {
+				return false
+			}
+		}
+
+		switch t.Type {
+		case lexer.LeftBraceToken, lexer.LeftParenthesisToken, lexer.LeftBracketToken, lexer.FunctionToken:
+			depth++
+		case lexer.RightBraceToken, lexer.RightParenthesisToken, lexer.RightBracketToken:
+			if depth > 0 {
+				depth--
+			}
+		}
+	}
+
+	return true
+}
+
+func skipTrivia(peek func(i int) lexer.Token, i int) int {
+	for peek(i).Type.IsTrivia() {
+		i++
+	}
+
+	return i
+}
+
+// closeAtEOF returns the text of t, with the closing quote or parenthesis
+// added when t is a string or url() cut off by the end of the input. A
+// backslash that ends such a string is dropped, as the lexer ignores it.
+func closeAtEOF(t lexer.Token) string {
+	val := string(t.Val)
+	end := ')'
+	switch t.Type {
+	case lexer.StringToken:
+		end = t.Val[0]
+	case lexer.UrlToken:
+	default:
+		return val
+	}
+
+	n := len(t.Val)
+	if n > 1 && t.Val[n-1] == end && backslashesBefore(t.Val, n-1)%2 == 0 {
+		return val
+	}
+
+	if t.Type == lexer.StringToken && backslashesBefore(t.Val, n)%2 == 1 {
+		val = val[:len(val)-1]
+	}
+	return val + string(end)
+}
+
+// backslashesBefore counts the backslashes right before val[i].
+func backslashesBefore(val []rune, i int) int {
+	n := 0
+	for i--; i >= 0 && val[i] == '\\'; i-- {
+		n++
+	}
+
+	return n
+}
+
+// atStart reports whether nothing of the current rule or declaration has
+// been written yet.
+func (m *minifier) atStart() bool {
+	switch m.prev.Type {
+	case lexer.EOF, lexer.LeftBraceToken, lexer.RightBraceToken, lexer.SemicolonToken, lexer.CDOToken, lexer.CDCToken:
+		return true
+	default:
+		return false
+	}
+}
+
+func (m *minifier) inMath() bool {
+	return inMath(m.functions)
+}
+
+func inMath(functions []string) bool {
+	for _, f := range functions {
+		if cssdata.MathFunctions[f] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// significantSpace reports whether removed whitespace before t has to be
+// kept as a single space.
+func (m *minifier) significantSpace(t lexer.Token) bool {
+	c := spaceContext{functions: m.functions}
+	switch {
+	case m.inDeclarations() && m.inValue:
+		c.item, c.custom = valueItem, strings.HasPrefix(m.property, "--")
+	case m.inDeclarations():
+		c.item = nameItem
+	case m.prelude != "":
+		c.item = preludeItem
+	}
+
+	return significantSpace(c, m.prev, t)
+}
+
+// itemKind is the part of a rule or a declaration that a token is in.
+type itemKind int
+
+const (
+	selectorItem itemKind = iota
+	preludeItem
+	nameItem
+	valueItem
+)
+
+// spaceContext is the position in the stylesheet that decides whether
+// whitespace is significant.
+type spaceContext struct {
+	item itemKind
+	// custom is set in the values of custom properties.
+	custom bool
+	// functions are the open functions and blocks in the item, "" for ()
+	// and [] blocks.
+	functions []string
+}
+
+// significantSpace reports whether whitespace between prev and t changes
+// the meaning of the stylesheet in the context c.
+func significantSpace(c spaceContext, prev lexer.Token, t lexer.Token) bool {
+	if isAny(prev, lexer.LeftBraceToken, lexer.RightBraceToken, lexer.SemicolonToken, lexer.CommaToken, lexer.FunctionToken, lexer.LeftParenthesisToken, lexer.LeftBracketToken) ||
+		isAny(t, lexer.LeftBraceToken, lexer.RightBraceToken, lexer.SemicolonToken, lexer.CommaToken, lexer.RightParenthesisToken, lexer.RightBracketToken) {
+		return false
+	}
+
+	if inMath(c.functions) {
+		return isDelim(prev, '+', '-') || isDelim(t, '+', '-')
+	}
+
+	switch c.item {
+	case nameItem:
+		return false
+	case valueItem:
+		return c.custom && !isAny(prev, lexer.ColonToken)
+	case preludeItem:
+		// In at-rule preludes whitespace separates the keywords and the
+		// conditions, as in "and (", and @charset must be followed by a
+		// space.
+		return !isAny(prev, lexer.ColonToken) && !isAny(t, lexer.ColonToken)
+	}
+
+	// Whitespace is the descendant combinator in selectors, except next to
+	// other combinators and inside attribute selectors. Arguments like the
+	// An+B of :nth-child() keep it.
+	for _, f := range c.functions {
+		if f == "" {
+			return false
+		}
+
+		if !cssdata.SelectorFunctions[f] {
+			return true
+		}
+	}
+
+	return !isDelim(prev, '>', '+', '~') && !isDelim(t, '>', '+', '~') && !isAny(prev, lexer.ColumnToken) && !isAny(t, lexer.ColumnToken)
+}
+
+// shorten returns the shortest form of a token in a declaration value.
+func (m *minifier) shorten(t lexer.Token) string {
+	val := string(t.Val)
+	switch t.Type {
+	case lexer.NumberToken, lexer.PercentageToken:
+		n := numberLength(t.Val)
+		return shortenNumber(val[:n]) + val[n:]
+	case lexer.DimensionToken:
+		n := numberLength(t.Val)
+		num := shortenNumber(val[:n])
+		if t.Number == 0 && cssdata.LengthUnits[strings.ToLower(string(t.Unit))] && !m.inMath() && m.property != "flex" && m.property != "flex-basis" {
+			return num
+		}
+
+		return num + val[n:]
+	case lexer.HashToken:
+		return shortenHexColor(t)
+	default:
+		return closeAtEOF(t)
+	}
+}
+
+// numberLength returns the length of the number at the start of a numeric
+// token's text, without the unit or the percent sign.
+func numberLength(val []rune) int {
+	i := 0
+	if i < len(val) && (val[i] == '+' || val[i] == '-') {
+		i++
+	}
+
+	for i < len(val) && isDigit(val[i]) {
+		i++
+	}
+
+	if i+1 < len(val) && val[i] == '.' && isDigit(val[i+1]) {
+		i++
+		for i < len(val) && isDigit(val[i]) {
+			i++
+		}
+	}
+
+	if i < len(val) && (val[i] == 'e' || val[i] == 'E') {
+		j := i + 1
+		if j < len(val) && (val[j] == '+' || val[j] == '-') {
+			j++
+		}
+
+		if j < len(val) && isDigit(val[j]) {
+			i = j
+			for i < len(val) && isDigit(val[i]) {
+				i++
+			}
+		}
+	}
+
+	return i
+}
+
+// shortenNumber drops the redundant zeros of a number: 0.50 becomes .5 and
+// 010.0 becomes 10. Numbers with an exponent are kept as they are.
+func shortenNumber(num string) string {
+	if strings.ContainsAny(num, "eE") {
+		return num
+	}
+
+	sign := ""
+	if num[0] == '+' || num[0] == '-' {
+		sign, num = num[:1], num[1:]
+	}
+
+	integer, fraction, _ := strings.Cut(num, ".")
+	integer = strings.TrimLeft(integer, "0")
+	fraction = strings.TrimRight(fraction, "0")
+	if fraction == "" {
+		if integer == "" {
+			integer = "0"
+		}
+
+		return sign + integer
+	}
+
+	return sign + integer + "." + fraction
+}
+
+// shortenHexColor lowercases hex colors and shortens #aabbcc to #abc.
+func shortenHexColor(t lexer.Token) string {
+	v := strings.ToLower(string(t.Value))
+	if string(t.Val) != "#"+string(t.Value) || (len(v) != 3 && len(v) != 4 && len(v) != 6 && len(v) != 8) {
+		return string(t.Val)
+	}
+
+	for _, r := range v {
+		if !isDigit(r) && (r < 'a' || r > 'f') {
+			return string(t.Val)
+		}
+	}
+
+	if len(v) == 6 || len(v) == 8 {
+		short := ""
+		for i := 0; i < len(v); i += 2 {
+			if v[i] != v[i+1] {
+				return "#" + v
+			}
+			short += v[i : i+1]
+		}
+
+		return "#" + short
+	}
+
+	return "#" + v
+}
+
+// needsSeparator reports whether two tokens would be lexed differently when
+// written next to each other.
+// https://www.w3.org/TR/css-syntax-3/#serialization
+func needsSeparator(a lexer.Token, b lexer.Token) bool {
+	identLike := isAny(b, lexer.IdentToken, lexer.FunctionToken, lexer.UrlToken, lexer.BadUrlToken)
+	numeric := isAny(b, lexer.NumberToken, lexer.PercentageToken, lexer.DimensionToken)
+	switch {
+	case isAny(a, lexer.IdentToken):
+		return identLike || numeric || isDelim(b, '-') || isAny(b, lexer.CDCToken, lexer.LeftParenthesisToken)
+	case isAny(a, lexer.AtKeywordToken, lexer.HashToken, lexer.DimensionToken):
+		return identLike || numeric || isDelim(b, '-') || isAny(b, lexer.CDCToken)
+	case isDelim(a, '#', '-'):
+		return identLike || numeric || isDelim(b, '-')
+	case isAny(a, lexer.NumberToken):
+		return identLike || numeric || isDelim(b, '%', '-', '.')
+	case isAny(a, lexer.AtToken):
+		return identLike || isDelim(b, '-') || isAny(b, lexer.CDCToken)
+	case isDelim(a, '.', '+'):
+		return numeric
+	case isDelim(a, '/'):
+		return isDelim(b, '*') || isAny(b, lexer.SubstringMatchToken)
+	case isDelim(a, '~', '|', '^', '$', '*'):
+		return isDelim(b, '=', '|') || isAny(b, lexer.DashMatchToken, lexer.ColumnToken)
+	case isDelim(a, '<'):
+		return isDelim(b, '!')
+	case isDelim(a, '\\'):
+		return true
+	default:
+		return false
+	}
+}
+
+func isAny(t lexer.Token, types ...lexer.TokenType) bool {
+	for _, tt := range types {
+		if t.Type == tt {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isDelim(t lexer.Token, runes ...rune) bool {
+	if t.Type != lexer.DelimToken || len(t.Val) != 1 {
+		return false
+	}
+
+	for _, r := range runes {
+		if t.Val[0] == r {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
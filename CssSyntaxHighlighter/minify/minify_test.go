@@ -0,0 +1,141 @@
+package minify
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/QuickOrBeDead/GoLangLearning/lexer"
+	"github.com/QuickOrBeDead/GoLangLearning/sourcemap"
+)
+
+func TestString(t *testing.T) {
+	values := []struct {
+		css    string
+		output string
+	}{
+		{"a { color : #AABBCC ; margin: 0px 0.50em }", "a{color:#abc;margin:0 .5em}"},
+		{"a{color:#AbCdEf;background:#FFFFFF80}", "a{color:#abcdef;background:#ffffff80}"},
+		{"ul  li , ol > li\n{ top: +010.0px }", "ul li,ol>li{top:+10px}"},
+		{"li:nth-child( 2n + 1 ) { }", "li:nth-child(2n + 1){}"},
+		{"a [ href = 'x' ] { }", "a [href='x']{}"},
+		{"a { width: calc( 100% - 0px ) }", "a{width:calc(100% - 0px)}"},
+		{"a { width: calc(1px + -2px*3) }", "a{width:calc(1px + -2px*3)}"},
+		{"a { flex: 1 1 0px }", "a{flex:1 1 0px}"},
+		{"a { --x : { a: b } ; --y:  1px  2px }", "a{--x:{a:b};--y:1px 2px}"},
+		{"a { margin: 0 auto !important; font: 12px/1.5 a, b }", "a{margin:0 auto!important;font:12px/1.5 a,b}"},
+		{"/* c */ a/**/b { }", "a/**/b{}"},
+		{"a /* c */ b, c/**/ d, e/**/.f { }", "a b,c d,e.f{}"},
+		{"a { color: red/**/blue }", "a{color:red/**/blue}"},
+		{"a { .x .y { color: red } }", "a{.x .y{color:red}}"},
+		{"a { color: red; div:hover  span { top: 0px } & > b { c: d } }", "a{color:red;div:hover span{top:0}&>b{c:d}}"},
+		{"a { @media print { color : red; .b  .c { top: 0px } } }", "a{@media print{color:red;.b .c{top:0}}}"},
+		{"@media (min-width: 1px)  and  (max-width: 2px) { }", "@media (min-width:1px) and (max-width:2px){}"},
+		{"@charset \"utf-8\";", "@charset \"utf-8\";"},
+		{"@media screen and ( min-width : 10px ) { a { b: 0.0 } }", "@media screen and (min-width:10px){a{b:0}}"},
+		{"@import url( a.css ) screen ;", "@import url( a.css ) screen;"},
+		{"@keyframes x { from { top: 0px } 50.0% { top: 1em } }", "@keyframes x{from{top:0}50.0%{top:1em}}"},
+		{"a { b: 1 - 2 }", "a{b:1 - 2}"},
+		{"a { b: 1e3px; c: 0.0% }", "a{b:1e3px;c:0%}"},
+		{"a { content: \"x", "a{content:\"x\""},
+		{"a { b: url( x.png", "a{b:url( x.png)"},
+		{"a { b: 'c\\", "a{b:'c'"},
+		{"a { b: 'c\\\\", "a{b:'c\\\\'"},
+		{"a { b: 'c\\'", "a{b:'c\\''"},
+	}
+
+	for _, v := range values {
+		if output := String(v.css); output != v.output {
+			t.Fatalf("%s (expected) %q != %q (actual)", v.css, v.output, output)
+		}
+
+		if err := VerifyString(v.css, String(v.css)); err != nil {
+			t.Fatalf("%s verify error %v", v.css, err)
+		}
+	}
+}
+
+func TestVerifyString(t *testing.T) {
+	values := []struct {
+		css      string
+		minified string
+	}{
+		{"a b{}", "ab{}"},
+		{"a{b:1px}", "a{b:2px}"},
+		{"a{b:#abc}", "a{b:#abd}"},
+		{"a{b:c}", "a{b:c"},
+		{"a b{}", "a/**/b{}"},
+		{"a/**/b{}", "a b{}"},
+		{"a:not(.b) .c{}", "a:not(.b).c{}"},
+		{"a{.x .y{b:c}}", "a{.x.y{b:c}}"},
+		{"a{b:calc(var(--x) - 1px)}", "a{b:calc(var(--x)- 1px)}"},
+		{"a{--x:a b}", "a{--x:a/**/b}"},
+		{"@media (a) and (b){}", "@media (a)and (b){}"},
+	}
+
+	for _, v := range values {
+		if err := VerifyString(v.css, v.minified); err == nil {
+			t.Fatalf("%q %q expected an error", v.css, v.minified)
+		}
+	}
+}
+
+func TestMinifyWithSourceMapUTF16(t *testing.T) {
+	css := "a { content: \"😀\"; color: red }"
+	var sb strings.Builder
+	var g sourcemap.Generator
+	if err := MinifyWithSourceMap(&sb, lexer.NewLexer(strings.NewReader(css)), &g, "a.css"); err != nil {
+		t.Fatal(err)
+	}
+
+	if output := sb.String(); output != "a{content:\"😀\";color:red}" {
+		t.Fatalf("%q", output)
+	}
+
+	// The emoji is two UTF-16 code units in the output and in the source.
+	m, ok := sourcemap.Lookup(g.Mappings(), 0, 15)
+	if !ok || m.GeneratedCol != 15 || m.OriginalCol != 19 {
+		t.Fatalf("color is mapped to %+v", m)
+	}
+}
+
+func TestMinifyWithSourceMap(t *testing.T) {
+	css := "a {\n  color : red;\n  margin: 0px 1em }\n\n@media print {\n  b { top: 0 }\n}\n"
+	var sb strings.Builder
+	var g sourcemap.Generator
+	if err := MinifyWithSourceMap(&sb, &lexer.Lexer{Text: []rune(css)}, &g, "a.css"); err != nil {
+		t.Fatal(err)
+	}
+
+	if output := sb.String(); output != "a{color:red;margin:0 1em}@media print{b{top:0}}" {
+		t.Fatalf("%q", output)
+	}
+
+	mappings, err := sourcemap.Decode(g.Map("a.min.css"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := []struct {
+		col       int
+		line, off int
+	}{
+		{0, 0, 0},
+		{2, 1, 2},
+		{8, 1, 10},
+		{12, 2, 2},
+		{19, 2, 10},
+		{21, 2, 14},
+		{26, 4, 0},
+		{38, 5, 2},
+		{40, 5, 6},
+		{44, 5, 11},
+		{47, 6, 0},
+	}
+
+	for _, v := range values {
+		m, ok := sourcemap.Lookup(mappings, 0, v.col)
+		if !ok || m.Source != "a.css" || m.OriginalLine != v.line || m.OriginalCol != v.off {
+			t.Fatalf("%d (expected) a.css:%d:%d != %+v (actual)", v.col, v.line, v.off, m)
+		}
+	}
+}
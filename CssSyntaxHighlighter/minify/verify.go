@@ -0,0 +1,210 @@
+package minify
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/QuickOrBeDead/GoLangLearning/cssdata"
+	"github.com/QuickOrBeDead/GoLangLearning/lexer"
+)
+
+// Verify lexes the original and the minified stylesheet and checks that they
+// have the same tokens apart from whitespace and comments. Numbers are
+// compared by value, zero lengths match unitless zeros and hex colors match
+// their short form. Where whitespace is significant, like between the parts
+// of a selector, the minified stylesheet must have whitespace exactly where
+// the original has it.
+func Verify(original *lexer.Lexer, minified *lexer.Lexer) error {
+	o, m := significantTokens(original), significantTokens(minified)
+	for i := 0; ; i++ {
+		if !equivalent(o[i].Token, m[i].Token) {
+			return fmt.Errorf("token %d differs: %s %q at %d:%d, minified %s %q at %d:%d", i, o[i].Type, string(o[i].Val), o[i].Line, o[i].Col, m[i].Type, string(m[i].Val), m[i].Line, m[i].Col)
+		}
+
+		if o[i].Type == lexer.EOF {
+			break
+		}
+	}
+
+	if err := original.Err(); err != nil {
+		return err
+	}
+
+	if err := minified.Err(); err != nil {
+		return err
+	}
+
+	tokens := make([]lexer.Token, len(o))
+	for i := range o {
+		tokens[i] = o[i].Token
+	}
+
+	for i, matters := range spaceMatters(tokens) {
+		if matters && o[i].space != m[i].space {
+			return fmt.Errorf("whitespace before token %d differs: %s %q at %d:%d, minified %s %q at %d:%d", i, o[i].Type, string(o[i].Val), o[i].Line, o[i].Col, m[i].Type, string(m[i].Val), m[i].Line, m[i].Col)
+		}
+	}
+
+	return nil
+}
+
+// VerifyString checks the minified form of css as Verify does.
+func VerifyString(css string, minified string) error {
+	return Verify(&lexer.Lexer{Text: []rune(css)}, &lexer.Lexer{Text: []rune(minified)})
+}
+
+// spacedToken is a token that is not trivia and whether whitespace comes
+// before it. Comments alone are not whitespace.
+type spacedToken struct {
+	lexer.Token
+	space bool
+}
+
+// significantTokens returns the tokens of l without trivia, ending with EOF.
+func significantTokens(l *lexer.Lexer) []spacedToken {
+	tokens := []spacedToken{}
+	space := false
+	for {
+		t := l.NextToken()
+		switch {
+		case t.Type == lexer.WhitespaceToken:
+			space = true
+		case t.Type.IsTrivia():
+		default:
+			tokens = append(tokens, spacedToken{t, space})
+			space = false
+		}
+
+		if t.Type == lexer.EOF {
+			return tokens
+		}
+	}
+}
+
+// spaceMatters returns for each of the tokens, which have no trivia, whether
+// whitespace before it is significant. It follows the structure of the
+// stylesheet on its own rather than with the minifier, so that it checks it.
+func spaceMatters(tokens []lexer.Token) []bool {
+	matters := make([]bool, len(tokens))
+	blocks := []blockKind{}
+	var c spaceContext
+	prelude := ""
+	start := true
+	for i, t := range tokens {
+		inDeclarations := len(blocks) > 0 && blocks[len(blocks)-1] == declarationsBlock
+		switch {
+		case t.Type == lexer.EOF:
+		case start:
+			c, prelude, start = spaceContext{}, "", false
+			if t.Type == lexer.AtKeywordToken {
+				c.item, prelude = preludeItem, strings.ToLower(string(t.Value))
+			} else if inDeclarations && isDeclaration(peekAt(tokens, i)) {
+				c.item, c.custom = nameItem, strings.HasPrefix(string(t.Val), "--")
+			}
+		default:
+			matters[i] = significantSpace(c, tokens[i-1], t)
+		}
+
+		switch t.Type {
+		case lexer.LeftBraceToken:
+			if len(c.functions) > 0 || c.item == valueItem {
+				c.functions = append(c.functions, "")
+				break
+			}
+
+			kind := declarationsBlock
+			if c.item == preludeItem && cssdata.GroupRules[prelude] && !inDeclarations {
+				kind = rulesBlock
+			}
+			blocks = append(blocks, kind)
+			start = true
+		case lexer.RightBraceToken:
+			if len(c.functions) > 0 {
+				c.functions = c.functions[:len(c.functions)-1]
+				break
+			}
+
+			if len(blocks) > 0 {
+				blocks = blocks[:len(blocks)-1]
+			}
+			start = true
+		case lexer.SemicolonToken:
+			start = len(c.functions) == 0
+		case lexer.FunctionToken:
+			c.functions = append(c.functions, strings.ToLower(string(t.Value)))
+		case lexer.LeftParenthesisToken, lexer.LeftBracketToken:
+			c.functions = append(c.functions, "")
+		case lexer.RightParenthesisToken, lexer.RightBracketToken:
+			if len(c.functions) > 0 {
+				c.functions = c.functions[:len(c.functions)-1]
+			}
+		case lexer.ColonToken:
+			if c.item == nameItem && len(c.functions) == 0 {
+				c.item = valueItem
+			}
+		}
+	}
+
+	return matters
+}
+
+// peekAt returns a peek function for isDeclaration over tokens from i on.
+func peekAt(tokens []lexer.Token, i int) func(int) lexer.Token {
+	return func(j int) lexer.Token {
+		if i+j >= len(tokens) {
+			return lexer.Token{Type: lexer.EOF}
+		}
+
+		return tokens[i+j]
+	}
+}
+
+func equivalent(o lexer.Token, m lexer.Token) bool {
+	if o.Type == lexer.DimensionToken && m.Type == lexer.NumberToken {
+		return o.Number == 0 && m.Number == 0 && cssdata.LengthUnits[strings.ToLower(string(o.Unit))]
+	}
+
+	if o.Type != m.Type {
+		return false
+	}
+
+	switch o.Type {
+	case lexer.NumberToken, lexer.PercentageToken:
+		return o.Number == m.Number
+	case lexer.DimensionToken:
+		return o.Number == m.Number && string(o.Unit) == string(m.Unit)
+	case lexer.HashToken:
+		return expandHexColor(string(o.Value)) == expandHexColor(string(m.Value))
+	case lexer.IdentToken, lexer.FunctionToken, lexer.AtKeywordToken, lexer.StringToken, lexer.UrlToken:
+		return string(o.Value) == string(m.Value)
+	default:
+		return string(o.Val) == string(m.Val)
+	}
+}
+
+// expandHexColor returns the lowercase long form of a hex color, or v when it
+// is not one.
+func expandHexColor(v string) string {
+	lower := strings.ToLower(v)
+	if len(lower) != 3 && len(lower) != 4 && len(lower) != 6 && len(lower) != 8 {
+		return v
+	}
+
+	for _, r := range lower {
+		if !isDigit(r) && (r < 'a' || r > 'f') {
+			return v
+		}
+	}
+
+	if len(lower) > 4 {
+		return lower
+	}
+
+	var sb strings.Builder
+	for _, r := range lower {
+		sb.WriteRune(r)
+		sb.WriteRune(r)
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,289 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/QuickOrBeDead/GoLangLearning/lexer"
+)
+
+// Span is the location of a node in the source text. Start and Stop are rune
+// offsets, Line and Col are the 1-based position of Start.
+type Span struct {
+	Start int
+	Stop  int
+	Line  int
+	Col   int
+}
+
+func (s Span) Position() Span {
+	return s
+}
+
+// Node is implemented by every node of the tree. String serializes the node
+// back to CSS text.
+type Node interface {
+	Position() Span
+	String() string
+}
+
+// ComponentValue is a *Token, *Function or *SimpleBlock.
+// https://www.w3.org/TR/css-syntax-3/#component-value
+type ComponentValue interface {
+	Node
+	componentValue()
+}
+
+// Rule is a *QualifiedRule, *AtRule or a *Comment found between rules.
+type Rule interface {
+	Node
+	rule()
+}
+
+// BlockItem is a *Declaration, *AtRule, *Comment or a nested *QualifiedRule
+// found in a list of declarations.
+type BlockItem interface {
+	Node
+	blockItem()
+}
+
+// Stylesheet is the result of ParseStylesheet. Its String method writes the
+// whitespace and the invalid content between the rules as they were in the
+// input, so an unchanged stylesheet is written back to its source text.
+type Stylesheet struct {
+	Span
+	Rules []Rule
+	// values are the component values of the input.
+	values []ComponentValue
+}
+
+// https://www.w3.org/TR/css-syntax-3/#qualified-rule
+type QualifiedRule struct {
+	Span
+	Prelude []ComponentValue
+	Block   *SimpleBlock
+}
+
+// https://www.w3.org/TR/css-syntax-3/#at-rule
+type AtRule struct {
+	Span
+	Token   lexer.Token
+	Prelude []ComponentValue
+	// Block is nil when the rule ends with a semicolon or at EOF.
+	Block     *SimpleBlock
+	Semicolon bool
+}
+
+// https://www.w3.org/TR/css-syntax-3/#declaration
+// A parsed declaration is written back with the text of its name, colon and
+// !important flag as they were in the input. A declaration built by hand is
+// written as name: value.
+type Declaration struct {
+	Span
+	Token     lexer.Token
+	Value     []ComponentValue
+	Important bool
+	// head are the values from the name to the value and tail the values
+	// from the value to the end of the !important flag.
+	head []ComponentValue
+	tail []ComponentValue
+}
+
+type Comment struct {
+	Span
+	Token lexer.Token
+}
+
+// Token is a preserved token, any token that is not part of a function or a
+// simple block.
+// https://www.w3.org/TR/css-syntax-3/#preserved-tokens
+type Token struct {
+	lexer.Token
+}
+
+// https://www.w3.org/TR/css-syntax-3/#function
+// The .mixin() calls of Less are functions too.
+type Function struct {
+	Span
+	Token  lexer.Token
+	Values []ComponentValue
+	// Closed is false when the function was ended by EOF.
+	Closed bool
+}
+
+// https://www.w3.org/TR/css-syntax-3/#simple-block
+// The #{} and @{} interpolations of SCSS and Less are simple blocks too.
+type SimpleBlock struct {
+	Span
+	Open   lexer.Token
+	Values []ComponentValue
+	// Closed is false when the block was ended by EOF.
+	Closed bool
+}
+
+func (*QualifiedRule) rule() {}
+func (*AtRule) rule()        {}
+func (*Comment) rule()       {}
+
+func (*Declaration) blockItem()   {}
+func (*AtRule) blockItem()        {}
+func (*Comment) blockItem()       {}
+func (*QualifiedRule) blockItem() {}
+
+func (*Token) componentValue()       {}
+func (*Function) componentValue()    {}
+func (*SimpleBlock) componentValue() {}
+
+func (t *Token) Position() Span {
+	return Span{Start: t.Start, Stop: t.End, Line: t.Line, Col: t.Col}
+}
+
+func (r *AtRule) Name() string {
+	return string(r.Token.Value)
+}
+
+func (d *Declaration) Name() string {
+	return string(d.Token.Value)
+}
+
+func (f *Function) Name() string {
+	return string(f.Token.Value)
+}
+
+// Close returns the token type that ends the block.
+func (b *SimpleBlock) Close() lexer.TokenType {
+	switch b.Open.Type {
+	case lexer.LeftBraceToken, lexer.InterpolationToken:
+		return lexer.RightBraceToken
+	case lexer.LeftBracketToken:
+		return lexer.RightBracketToken
+	default:
+		return lexer.RightParenthesisToken
+	}
+}
+
+// Declarations parses the contents of the block as a list of declarations,
+// dropping the invalid ones.
+func (b *SimpleBlock) Declarations() []BlockItem {
+	return NewFromValues(b.Values).ParseDeclarationList()
+}
+
+// Rules parses the contents of the block as a list of rules, as the blocks of
+// @media and other conditional group rules are.
+func (b *SimpleBlock) Rules() []Rule {
+	return NewFromValues(b.Values).ParseRuleList()
+}
+
+func (s *Stylesheet) String() string {
+	var sb strings.Builder
+	if s.values == nil {
+		for i, r := range s.Rules {
+			if i > 0 {
+				sb.WriteString("\n")
+			}
+			sb.WriteString(r.String())
+		}
+
+		return sb.String()
+	}
+
+	i := 0
+	for _, r := range s.Rules {
+		span := r.Position()
+		for ; i < len(s.values) && s.values[i].Position().Start < span.Start; i++ {
+			sb.WriteString(s.values[i].String())
+		}
+
+		// Skip the values of the rule.
+		for ; i < len(s.values) && s.values[i].Position().Start < span.Stop; i++ {
+		}
+		sb.WriteString(r.String())
+	}
+	writeValues(&sb, s.values[i:])
+
+	return sb.String()
+}
+
+func (r *QualifiedRule) String() string {
+	var sb strings.Builder
+	writeValues(&sb, r.Prelude)
+	if r.Block != nil {
+		sb.WriteString(r.Block.String())
+	}
+
+	return sb.String()
+}
+
+func (r *AtRule) String() string {
+	var sb strings.Builder
+	sb.WriteString(string(r.Token.Val))
+	writeValues(&sb, r.Prelude)
+	if r.Block != nil {
+		sb.WriteString(r.Block.String())
+	} else if r.Semicolon {
+		sb.WriteString(";")
+	}
+
+	return sb.String()
+}
+
+func (d *Declaration) String() string {
+	var sb strings.Builder
+	if d.head != nil {
+		writeValues(&sb, d.head)
+	} else {
+		sb.WriteString(string(d.Token.Val))
+		sb.WriteString(": ")
+	}
+
+	writeValues(&sb, d.Value)
+	if d.Important && d.tail != nil {
+		writeValues(&sb, d.tail)
+	} else if d.Important {
+		sb.WriteString(" !important")
+	}
+
+	return sb.String()
+}
+
+func (c *Comment) String() string {
+	return string(c.Token.Val)
+}
+
+func (t *Token) String() string {
+	return string(t.Val)
+}
+
+func (f *Function) String() string {
+	var sb strings.Builder
+	sb.WriteString(string(f.Token.Val))
+	writeValues(&sb, f.Values)
+	if f.Closed {
+		sb.WriteString(")")
+	}
+
+	return sb.String()
+}
+
+func (b *SimpleBlock) String() string {
+	var sb strings.Builder
+	sb.WriteString(string(b.Open.Val))
+	writeValues(&sb, b.Values)
+	if b.Closed {
+		switch b.Close() {
+		case lexer.RightBraceToken:
+			sb.WriteString("}")
+		case lexer.RightBracketToken:
+			sb.WriteString("]")
+		default:
+			sb.WriteString(")")
+		}
+	}
+
+	return sb.String()
+}
+
+func writeValues(sb *strings.Builder, values []ComponentValue) {
+	for _, v := range values {
+		sb.WriteString(v.String())
+	}
+}
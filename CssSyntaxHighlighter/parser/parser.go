@@ -0,0 +1,567 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/QuickOrBeDead/GoLangLearning/lexer"
+)
+
+// Error is a parse error. The parser records it and recovers the way the
+// spec describes, so a tree is produced for any input. Err is the sentinel
+// error it wraps, like ErrUnexpectedEOF, if any.
+type Error struct {
+	Line int
+	Col  int
+	Msg  string
+	Err  error
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Col, e.Msg)
+}
+
+func (e Error) Unwrap() error {
+	return e.Err
+}
+
+// ErrUnexpectedEOF is wrapped by the errors of the Parse functions that
+// need a value and find the end of the input.
+var ErrUnexpectedEOF = errors.New("unexpected end of input")
+
+// Parser implements the parsing algorithms of css-syntax-3 on the component
+// values of its input.
+// https://www.w3.org/TR/css-syntax-3/#parsing
+type Parser struct {
+	values []ComponentValue
+	pos    int
+	// end is the position after the last token of the input.
+	end    Span
+	Errors []Error
+}
+
+// New returns a parser for the tokens of lex. The whole input is consumed
+// into component values before New returns.
+func New(lex *lexer.Lexer) *Parser {
+	p := &Parser{}
+	s := &tokenStream{lex: lex}
+	for {
+		t := s.next()
+		if t.Type == lexer.EOF {
+			p.end = Span{Start: t.Start, Stop: t.End, Line: t.Line, Col: t.Col}
+			break
+		}
+
+		p.values = append(p.values, p.consumeComponentValue(s, t))
+	}
+
+	return p
+}
+
+// NewFromValues returns a parser over already parsed component values, like
+// the contents of a simple block.
+func NewFromValues(values []ComponentValue) *Parser {
+	p := &Parser{values: values}
+	if len(values) > 0 {
+		last := values[len(values)-1].Position()
+		p.end = Span{Start: last.Stop, Stop: last.Stop, Line: last.Line, Col: last.Col}
+	}
+
+	return p
+}
+
+// https://www.w3.org/TR/css-syntax-3/#parse-stylesheet
+func (p *Parser) ParseStylesheet() *Stylesheet {
+	rules := p.consumeRuleList(true)
+	return &Stylesheet{Span: Span{Start: 0, Stop: p.end.Stop, Line: 1, Col: 1}, Rules: rules, values: p.values}
+}
+
+// https://www.w3.org/TR/css-syntax-3/#parse-list-of-rules
+func (p *Parser) ParseRuleList() []Rule {
+	return p.consumeRuleList(false)
+}
+
+// https://www.w3.org/TR/css-syntax-3/#parse-rule
+func (p *Parser) ParseRule() (Rule, error) {
+	p.skipWhitespace()
+	v := p.peek()
+	if v == nil {
+		return nil, p.unexpectedEOF()
+	}
+
+	var r Rule
+	if isToken(v, lexer.AtKeywordToken) {
+		p.next()
+		r = p.consumeAtRule(v.(*Token))
+	} else if q := p.consumeQualifiedRule(); q != nil {
+		r = q
+	} else {
+		return nil, p.Errors[len(p.Errors)-1]
+	}
+
+	p.skipWhitespace()
+	if v := p.peek(); v != nil {
+		return nil, p.errorAt(v.Position(), "unexpected content after the rule")
+	}
+
+	return r, nil
+}
+
+// https://www.w3.org/TR/css-syntax-3/#parse-declaration
+func (p *Parser) ParseDeclaration() (*Declaration, error) {
+	p.skipWhitespace()
+	v := p.peek()
+	if v == nil {
+		return nil, p.unexpectedEOF()
+	}
+
+	if !isToken(v, lexer.IdentToken) {
+		return nil, p.errorAt(v.Position(), "expected a property name")
+	}
+
+	d := p.consumeDeclaration(p.rest())
+	if d == nil {
+		return nil, p.Errors[len(p.Errors)-1]
+	}
+
+	return d, nil
+}
+
+// https://www.w3.org/TR/css-syntax-3/#parse-list-of-declarations
+func (p *Parser) ParseDeclarationList() []BlockItem {
+	return p.consumeDeclarationList()
+}
+
+// https://www.w3.org/TR/css-syntax-3/#parse-component-value
+func (p *Parser) ParseComponentValue() (ComponentValue, error) {
+	p.skipWhitespace()
+	v := p.next()
+	if v == nil {
+		return nil, p.unexpectedEOF()
+	}
+
+	p.skipWhitespace()
+	if next := p.peek(); next != nil {
+		return nil, p.errorAt(next.Position(), "unexpected content after the component value")
+	}
+
+	return v, nil
+}
+
+// https://www.w3.org/TR/css-syntax-3/#parse-list-of-component-values
+func (p *Parser) ParseComponentValueList() []ComponentValue {
+	return p.rest()
+}
+
+// https://www.w3.org/TR/css-syntax-3/#parse-comma-separated-list-of-component-values
+func (p *Parser) ParseCommaSeparatedComponentValueList() [][]ComponentValue {
+	lists := [][]ComponentValue{}
+	list := []ComponentValue{}
+	for v := p.next(); v != nil; v = p.next() {
+		if isToken(v, lexer.CommaToken) {
+			lists = append(lists, list)
+			list = []ComponentValue{}
+			continue
+		}
+
+		list = append(list, v)
+	}
+
+	return append(lists, list)
+}
+
+// https://www.w3.org/TR/css-syntax-3/#consume-list-of-rules
+func (p *Parser) consumeRuleList(topLevel bool) []Rule {
+	rules := []Rule{}
+	for {
+		v := p.peek()
+		switch {
+		case v == nil:
+			return rules
+		case isToken(v, lexer.WhitespaceToken):
+			p.next()
+		case isToken(v, lexer.CommentToken):
+			p.next()
+			rules = append(rules, newComment(v.(*Token)))
+		case topLevel && (isToken(v, lexer.CDOToken) || isToken(v, lexer.CDCToken)):
+			p.next()
+		case isToken(v, lexer.AtKeywordToken):
+			p.next()
+			rules = append(rules, p.consumeAtRule(v.(*Token)))
+		default:
+			if r := p.consumeQualifiedRule(); r != nil {
+				rules = append(rules, r)
+			}
+		}
+	}
+}
+
+// https://www.w3.org/TR/css-syntax-3/#consume-at-rule
+func (p *Parser) consumeAtRule(t *Token) *AtRule {
+	r := &AtRule{Span: t.Position(), Token: t.Token, Prelude: []ComponentValue{}}
+	for {
+		v := p.next()
+		switch {
+		case v == nil:
+			p.errorAt(p.end, "unexpected end of input in at-rule")
+			return r
+		case isToken(v, lexer.SemicolonToken):
+			r.Semicolon = true
+			r.Stop = v.Position().Stop
+			return r
+		case isBlock(v, lexer.LeftBraceToken):
+			r.Block = v.(*SimpleBlock)
+			r.Stop = r.Block.Stop
+			return r
+		default:
+			r.Prelude = append(r.Prelude, v)
+			r.Stop = v.Position().Stop
+		}
+	}
+}
+
+// https://www.w3.org/TR/css-syntax-3/#consume-qualified-rule
+func (p *Parser) consumeQualifiedRule() *QualifiedRule {
+	r := &QualifiedRule{Span: p.peek().Position(), Prelude: []ComponentValue{}}
+	for {
+		v := p.next()
+		switch {
+		case v == nil:
+			p.errorAt(p.end, "unexpected end of input in qualified rule")
+			return nil
+		case isBlock(v, lexer.LeftBraceToken):
+			r.Block = v.(*SimpleBlock)
+			r.Stop = r.Block.Stop
+			return r
+		default:
+			r.Prelude = append(r.Prelude, v)
+		}
+	}
+}
+
+// consumeDeclarationList consumes declarations and, as in CSS nesting,
+// nested rules.
+// https://www.w3.org/TR/css-syntax-3/#consume-block-contents
+func (p *Parser) consumeDeclarationList() []BlockItem {
+	items := []BlockItem{}
+	for {
+		v := p.next()
+		switch {
+		case v == nil:
+			return items
+		case isToken(v, lexer.WhitespaceToken), isToken(v, lexer.SemicolonToken):
+		case isToken(v, lexer.CommentToken):
+			items = append(items, newComment(v.(*Token)))
+		case isToken(v, lexer.AtKeywordToken):
+			items = append(items, p.consumeAtRule(v.(*Token)))
+		default:
+			start := p.pos - 1
+			values := []ComponentValue{v}
+			for next := p.peek(); next != nil && !isToken(next, lexer.SemicolonToken); next = p.peek() {
+				values = append(values, p.next())
+			}
+
+			if (isToken(v, lexer.IdentToken) || isToken(v, lexer.VariableToken)) && !isNestedRule(values) {
+				if d := p.consumeDeclaration(values); d != nil {
+					items = append(items, d)
+				}
+				continue
+			}
+
+			p.pos = start
+			if r := p.consumeNestedRule(); r != nil {
+				items = append(items, r)
+			}
+		}
+	}
+}
+
+// isNestedRule reports whether the values of an item of a declaration list,
+// which start with an ident, are a rule: there is a {} block and no colon
+// after the ident, or the value of a property that is not custom has a {}
+// block and other values.
+func isNestedRule(values []ComponentValue) bool {
+	block := false
+	for _, v := range values {
+		block = block || isBlock(v, lexer.LeftBraceToken)
+	}
+
+	if !block {
+		return false
+	}
+
+	i := skipTrivia(values, 1)
+	if i == len(values) || !isToken(values[i], lexer.ColonToken) {
+		return true
+	}
+
+	if strings.HasPrefix(string(values[0].(*Token).Val), "--") {
+		return false
+	}
+
+	others := 0
+	for _, v := range values[i+1:] {
+		if !isTrivia(v) {
+			others++
+		}
+	}
+
+	return others > 1
+}
+
+// consumeNestedRule consumes a qualified rule in a declaration list, which
+// is an error when a semicolon comes before its block.
+// https://www.w3.org/TR/css-syntax-3/#consume-qualified-rule
+func (p *Parser) consumeNestedRule() *QualifiedRule {
+	first := p.peek()
+	r := &QualifiedRule{Span: first.Position(), Prelude: []ComponentValue{}}
+	for {
+		v := p.peek()
+		switch {
+		case v == nil, isToken(v, lexer.SemicolonToken):
+			p.errorAt(first.Position(), "expected a declaration")
+			return nil
+		case isBlock(v, lexer.LeftBraceToken):
+			p.next()
+			r.Block = v.(*SimpleBlock)
+			r.Stop = r.Block.Stop
+			return r
+		default:
+			r.Prelude = append(r.Prelude, p.next())
+		}
+	}
+}
+
+// consumeDeclaration consumes a declaration from values, which start with
+// the ident of the name, or the variable in SCSS and Less. Comments are skipped like whitespace around the
+// colon and the !important flag.
+// https://www.w3.org/TR/css-syntax-3/#consume-declaration
+func (p *Parser) consumeDeclaration(values []ComponentValue) *Declaration {
+	name := values[0].(*Token)
+	d := &Declaration{Span: name.Position(), Token: name.Token, Value: []ComponentValue{}}
+
+	i := skipTrivia(values, 1)
+	if i == len(values) || !isToken(values[i], lexer.ColonToken) {
+		p.errorAt(name.Position(), "expected a colon after the property name")
+		return nil
+	}
+	d.Stop = values[i].Position().Stop
+
+	start := skipTrivia(values, i+1)
+	d.head = values[:start]
+	value := values[start:]
+	end, last := len(value), lastNonTrivia(value)
+	if last > 0 && isIdentValue(value[last], "important") {
+		if bang := lastNonTrivia(value[:last]); bang >= 0 && isDelim(value[bang], '!') {
+			d.Important = true
+			d.Stop = value[last].Position().Stop
+			end = bang
+		}
+	}
+
+	n := end
+	for n > 0 && isToken(value[n-1], lexer.WhitespaceToken) {
+		n--
+	}
+	if d.Important {
+		d.tail = value[n : last+1]
+	}
+	d.Value = append(d.Value, value[:n]...)
+	if len(d.Value) > 0 && !d.Important {
+		d.Stop = d.Value[len(d.Value)-1].Position().Stop
+	}
+
+	return d
+}
+
+// https://www.w3.org/TR/css-syntax-3/#consume-component-value
+func (p *Parser) consumeComponentValue(s *tokenStream, t lexer.Token) ComponentValue {
+	switch t.Type {
+	case lexer.LeftBraceToken, lexer.LeftBracketToken, lexer.LeftParenthesisToken, lexer.InterpolationToken:
+		return p.consumeSimpleBlock(s, t)
+	case lexer.FunctionToken, lexer.MixinToken:
+		return p.consumeFunction(s, t)
+	default:
+		return &Token{Token: t}
+	}
+}
+
+// https://www.w3.org/TR/css-syntax-3/#consume-simple-block
+func (p *Parser) consumeSimpleBlock(s *tokenStream, t lexer.Token) *SimpleBlock {
+	b := &SimpleBlock{Span: Span{Start: t.Start, Stop: t.End, Line: t.Line, Col: t.Col}, Open: t, Values: []ComponentValue{}}
+	end := b.Close()
+	for {
+		next := s.next()
+		switch next.Type {
+		case end:
+			b.Closed = true
+			b.Stop = next.End
+			return b
+		case lexer.EOF:
+			p.error(next, "unexpected end of input in block")
+			s.reconsume()
+			return b
+		default:
+			v := p.consumeComponentValue(s, next)
+			b.Values = append(b.Values, v)
+			b.Stop = v.Position().Stop
+		}
+	}
+}
+
+// https://www.w3.org/TR/css-syntax-3/#consume-function
+func (p *Parser) consumeFunction(s *tokenStream, t lexer.Token) *Function {
+	f := &Function{Span: Span{Start: t.Start, Stop: t.End, Line: t.Line, Col: t.Col}, Token: t, Values: []ComponentValue{}}
+	for {
+		next := s.next()
+		switch next.Type {
+		case lexer.RightParenthesisToken:
+			f.Closed = true
+			f.Stop = next.End
+			return f
+		case lexer.EOF:
+			p.error(next, "unexpected end of input in function")
+			s.reconsume()
+			return f
+		default:
+			v := p.consumeComponentValue(s, next)
+			f.Values = append(f.Values, v)
+			f.Stop = v.Position().Stop
+		}
+	}
+}
+
+func (p *Parser) next() ComponentValue {
+	if p.pos >= len(p.values) {
+		return nil
+	}
+
+	p.pos++
+	return p.values[p.pos-1]
+}
+
+func (p *Parser) peek() ComponentValue {
+	if p.pos >= len(p.values) {
+		return nil
+	}
+
+	return p.values[p.pos]
+}
+
+func (p *Parser) rest() []ComponentValue {
+	values := p.values[p.pos:]
+	p.pos = len(p.values)
+	return values
+}
+
+func (p *Parser) skipWhitespace() {
+	for v := p.peek(); v != nil && isTrivia(v); v = p.peek() {
+		p.next()
+	}
+}
+
+func (p *Parser) error(t lexer.Token, msg string) Error {
+	e := Error{Line: t.Line, Col: t.Col, Msg: msg}
+	p.Errors = append(p.Errors, e)
+	return e
+}
+
+func (p *Parser) errorAt(s Span, msg string) Error {
+	e := Error{Line: s.Line, Col: s.Col, Msg: msg}
+	p.Errors = append(p.Errors, e)
+	return e
+}
+
+func (p *Parser) unexpectedEOF() Error {
+	e := Error{Line: p.end.Line, Col: p.end.Col, Msg: ErrUnexpectedEOF.Error(), Err: ErrUnexpectedEOF}
+	p.Errors = append(p.Errors, e)
+	return e
+}
+
+// tokenStream reads tokens from a lexer with one token of lookback.
+type tokenStream struct {
+	lex     *lexer.Lexer
+	current lexer.Token
+	again   bool
+}
+
+func (s *tokenStream) next() lexer.Token {
+	if s.again {
+		s.again = false
+	} else {
+		s.current = s.lex.NextToken()
+	}
+
+	return s.current
+}
+
+func (s *tokenStream) reconsume() {
+	s.again = true
+}
+
+func newComment(t *Token) *Comment {
+	return &Comment{Span: t.Position(), Token: t.Token}
+}
+
+func isToken(v ComponentValue, tokenType lexer.TokenType) bool {
+	t, ok := v.(*Token)
+	return ok && t.Type == tokenType
+}
+
+func isBlock(v ComponentValue, open lexer.TokenType) bool {
+	b, ok := v.(*SimpleBlock)
+	return ok && b.Open.Type == open
+}
+
+func isDelim(v ComponentValue, r rune) bool {
+	t, ok := v.(*Token)
+	return ok && t.Type == lexer.DelimToken && len(t.Val) == 1 && t.Val[0] == r
+}
+
+func isIdentValue(v ComponentValue, name string) bool {
+	t, ok := v.(*Token)
+	return ok && t.Type == lexer.IdentToken && equalFold(t.Value, name)
+}
+
+func isTrivia(v ComponentValue) bool {
+	t, ok := v.(*Token)
+	return ok && t.Type.IsTrivia()
+}
+
+func skipTrivia(values []ComponentValue, i int) int {
+	for i < len(values) && isTrivia(values[i]) {
+		i++
+	}
+
+	return i
+}
+
+func lastNonTrivia(values []ComponentValue) int {
+	i := len(values) - 1
+	for i >= 0 && isTrivia(values[i]) {
+		i--
+	}
+
+	return i
+}
+
+// equalFold reports whether r matches the lowercase ASCII string s, ignoring
+// ASCII case.
+func equalFold(r []rune, s string) bool {
+	if len(r) != len(s) {
+		return false
+	}
+
+	for i, c := range r {
+		if c >= 'A' && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+
+		if c != rune(s[i]) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,270 @@
+package parser
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/QuickOrBeDead/GoLangLearning/lexer"
+)
+
+func parse(css string) (*Stylesheet, []Error) {
+	p := New(&lexer.Lexer{Text: []rune(css)})
+	s := p.ParseStylesheet()
+	return s, p.Errors
+}
+
+func TestParseStylesheet(t *testing.T) {
+	css := `@charset "utf-8";
+/* header */
+<!-- a, b > c { color: red; margin : 0 auto !important }
+@media (min-width: 10px) { .x { width: calc(100% - 2px) } } -->`
+
+	s, errs := parse(css)
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+
+	if len(s.Rules) != 4 {
+		t.Fatalf("len(s.Rules) (expected) 4 != %d (actual)", len(s.Rules))
+	}
+
+	charset, ok := s.Rules[0].(*AtRule)
+	if !ok || charset.Name() != "charset" || !charset.Semicolon || charset.Block != nil || len(charset.Prelude) != 2 {
+		t.Fatalf("unexpected @charset rule %#v", s.Rules[0])
+	}
+
+	if c, ok := s.Rules[1].(*Comment); !ok || c.String() != "/* header */" {
+		t.Fatalf("unexpected comment %#v", s.Rules[1])
+	}
+
+	rule, ok := s.Rules[2].(*QualifiedRule)
+	if !ok || rule.Block == nil || !rule.Block.Closed {
+		t.Fatalf("unexpected qualified rule %#v", s.Rules[2])
+	}
+
+	if prelude := writeString(rule.Prelude); prelude != "a, b > c " {
+		t.Fatalf("prelude (expected) %q != %q (actual)", "a, b > c ", prelude)
+	}
+
+	items := rule.Block.Declarations()
+	if len(items) != 2 {
+		t.Fatalf("len(items) (expected) 2 != %d (actual)", len(items))
+	}
+
+	color := items[0].(*Declaration)
+	if color.Name() != "color" || color.Important || writeString(color.Value) != "red" {
+		t.Fatalf("unexpected declaration %q", color.String())
+	}
+
+	margin := items[1].(*Declaration)
+	if margin.Name() != "margin" || !margin.Important || writeString(margin.Value) != "0 auto" {
+		t.Fatalf("unexpected declaration %q", margin.String())
+	}
+
+	media, ok := s.Rules[3].(*AtRule)
+	if !ok || media.Name() != "media" || media.Block == nil {
+		t.Fatalf("unexpected @media rule %#v", s.Rules[3])
+	}
+
+	if _, ok := media.Prelude[1].(*SimpleBlock); !ok {
+		t.Fatalf("expected a simple block in the @media prelude, got %#v", media.Prelude[1])
+	}
+
+	nested := media.Block.Rules()
+	if len(nested) != 1 {
+		t.Fatalf("len(nested) (expected) 1 != %d (actual)", len(nested))
+	}
+
+	width := nested[0].(*QualifiedRule).Block.Declarations()[0].(*Declaration)
+	calc, ok := width.Value[0].(*Function)
+	if !ok || calc.Name() != "calc" || !calc.Closed || writeString(calc.Values) != "100% - 2px" {
+		t.Fatalf("unexpected calc() %#v", width.Value[0])
+	}
+}
+
+func TestParseErrorRecovery(t *testing.T) {
+	values := []struct {
+		css    string
+		rules  int
+		errors int
+	}{
+		{"a { color: red", 1, 1},
+		{"a { color: red } b", 1, 1},
+		{"@import 'a.css'", 1, 1},
+		{"a { color: calc(1px", 1, 2},
+		{"} a {}", 1, 0},
+		{"a { ; ; color: red }", 1, 0},
+	}
+
+	for _, v := range values {
+		s, errs := parse(v.css)
+		if len(s.Rules) != v.rules {
+			t.Fatalf("%s len(s.Rules) (expected) %d != %d (actual)", v.css, v.rules, len(s.Rules))
+		}
+
+		if len(errs) != v.errors {
+			t.Fatalf("%s len(errs) (expected) %d != %d (actual) %v", v.css, v.errors, len(errs), errs)
+		}
+
+		// The invalid content is written back too.
+		if s.String() != v.css {
+			t.Fatalf("%s output (expected) %q != %q (actual)", v.css, v.css, s.String())
+		}
+	}
+}
+
+func TestParseDeclarationList(t *testing.T) {
+	p := New(&lexer.Lexer{Text: []rune("color : red; 12px; margin; @page { x: y } /* c */ width: 1px !IMPORTANT; top: 0 ! important")})
+	items := p.ParseDeclarationList()
+
+	expected := []string{"color : red", "@page { x: y }", "/* c */", "width: 1px !IMPORTANT", "top: 0 ! important"}
+	if len(items) != len(expected) {
+		t.Fatalf("len(items) (expected) %d != %d (actual)", len(expected), len(items))
+	}
+
+	for i, item := range items {
+		if item.String() != expected[i] {
+			t.Fatalf("%d. item (expected) %q != %q (actual)", i, expected[i], item.String())
+		}
+	}
+
+	if len(p.Errors) != 2 {
+		t.Fatalf("len(p.Errors) (expected) 2 != %d (actual) %v", len(p.Errors), p.Errors)
+	}
+
+	if p.Errors[0].Line != 1 || p.Errors[0].Col != 14 {
+		t.Fatalf("first error position (expected) 1:14 != %d:%d (actual)", p.Errors[0].Line, p.Errors[0].Col)
+	}
+}
+
+func TestParseNestedRules(t *testing.T) {
+	p := New(&lexer.Lexer{Text: []rune("color: red; .a .b { top: 0 } div:hover span { x: y } --c: { d: e }; font: { family: f }; & > p {} 1; top: 0")})
+	items := p.ParseDeclarationList()
+
+	expected := []string{"color: red", ".a .b { top: 0 }", "div:hover span { x: y }", "--c: { d: e }", "font: { family: f }", "& > p {}", "top: 0"}
+	if len(items) != len(expected) {
+		t.Fatalf("len(items) (expected) %d != %d (actual) %v", len(expected), len(items), items)
+	}
+
+	for i, item := range items {
+		_, rule := item.(*QualifiedRule)
+		if item.String() != expected[i] || rule != (i == 1 || i == 2 || i == 5) {
+			t.Fatalf("%d. item (expected) %q != %q %T (actual)", i, expected[i], item.String(), item)
+		}
+	}
+
+	if len(p.Errors) != 1 || p.Errors[0].Msg != "expected a declaration" || p.Errors[0].Col != 99 {
+		t.Fatalf("errors (expected) expected a declaration at 1:99 != %v (actual)", p.Errors)
+	}
+}
+
+func TestParseSingleValues(t *testing.T) {
+	if r, err := New(&lexer.Lexer{Text: []rune("  a { }  ")}).ParseRule(); err != nil || r.String() != "a { }" {
+		t.Fatalf("ParseRule returned %v, %v", r, err)
+	}
+
+	if _, err := New(&lexer.Lexer{Text: []rune("a { } b { }")}).ParseRule(); err == nil {
+		t.Fatal("ParseRule expected an error for two rules")
+	}
+
+	if d, err := New(&lexer.Lexer{Text: []rune(" color:blue ")}).ParseDeclaration(); err != nil || d.String() != "color:blue" {
+		t.Fatalf("ParseDeclaration returned %v, %v", d, err)
+	}
+
+	d := &Declaration{Token: lexer.Token{Val: []rune("color")}, Value: []ComponentValue{&Token{lexer.Token{Val: []rune("red")}}}, Important: true}
+	if d.String() != "color: red !important" {
+		t.Fatalf("declaration (expected) %q != %q (actual)", "color: red !important", d.String())
+	}
+
+	for _, p := range []*Parser{New(&lexer.Lexer{Text: []rune(" ")}), NewFromValues(nil)} {
+		if _, err := p.ParseComponentValue(); !errors.Is(err, ErrUnexpectedEOF) {
+			t.Fatalf("ParseComponentValue (expected) %v != %v (actual)", ErrUnexpectedEOF, err)
+		}
+	}
+
+	if _, err := New(&lexer.Lexer{Text: []rune("color blue")}).ParseDeclaration(); err == nil {
+		t.Fatal("ParseDeclaration expected an error without a colon")
+	}
+
+	if v, err := New(&lexer.Lexer{Text: []rune(" rgb(1, 2, 3) ")}).ParseComponentValue(); err != nil || v.String() != "rgb(1, 2, 3)" {
+		t.Fatalf("ParseComponentValue returned %v, %v", v, err)
+	}
+
+	lists := New(&lexer.Lexer{Text: []rune("a, b c,(d, e)")}).ParseCommaSeparatedComponentValueList()
+	if len(lists) != 3 || writeString(lists[2]) != "(d, e)" {
+		t.Fatalf("unexpected comma separated lists %v", lists)
+	}
+}
+
+func TestParsePositions(t *testing.T) {
+	s, _ := parse("a {\n  color: red;\n}\n@x y;")
+	rule := s.Rules[0].(*QualifiedRule)
+	if rule.Start != 0 || rule.Stop != 19 || rule.Line != 1 || rule.Col != 1 {
+		t.Fatalf("unexpected rule span %+v", rule.Span)
+	}
+
+	d := rule.Block.Declarations()[0].(*Declaration)
+	if d.Start != 6 || d.Stop != 16 || d.Line != 2 || d.Col != 3 {
+		t.Fatalf("unexpected declaration span %+v", d.Span)
+	}
+
+	at := s.Rules[1].(*AtRule)
+	if at.Start != 20 || at.Stop != 25 || at.Line != 4 || at.Col != 1 {
+		t.Fatalf("unexpected at-rule span %+v", at.Span)
+	}
+}
+
+func TestParseRoundTrip(t *testing.T) {
+	css := "<!-- @import url(a.css) screen;\n\n/* a */ a:hover, b[x=\"y\"] { color: #fff; background: url( b.png ) no-repeat }\n@font-face { font-family: \"A\\\"B\"; src: local(x) }\n@media print { a { b: c } } -->\n"
+	s, _ := parse(css)
+	if s.String() != css {
+		t.Fatalf("round trip (expected) %q != %q (actual)", css, s.String())
+	}
+
+	again, _ := parse(s.String())
+	if again.String() != s.String() {
+		t.Fatalf("second round trip (expected) %q != %q (actual)", s.String(), again.String())
+	}
+}
+
+func writeString(values []ComponentValue) string {
+	s := ""
+	for _, v := range values {
+		s += v.String()
+	}
+
+	return s
+}
+
+func TestDialectValues(t *testing.T) {
+	values := New(&lexer.Lexer{Text: []rune("#{$a} x"), Dialect: lexer.SCSS}).ParseComponentValueList()
+	b, ok := values[0].(*SimpleBlock)
+	if !ok || !b.Closed || b.Close() != lexer.RightBraceToken || b.String() != "#{$a}" {
+		t.Fatalf("unexpected interpolation %#v", values[0])
+	}
+
+	values = New(&lexer.Lexer{Text: []rune(".m(@a; 1) x"), Dialect: lexer.Less}).ParseComponentValueList()
+	f, ok := values[0].(*Function)
+	if !ok || !f.Closed || f.Name() != "m" || f.String() != ".m(@a; 1)" {
+		t.Fatalf("unexpected mixin call %#v", values[0])
+	}
+
+	for _, v := range []struct {
+		dialect lexer.Dialect
+		css     string
+	}{
+		{lexer.SCSS, "$a : 1px; &:hover { top: 0 }"},
+		{lexer.Less, "@a : 1px; &:hover { top: 0 }"},
+	} {
+		p := New(&lexer.Lexer{Text: []rune(v.css), Dialect: v.dialect})
+		items := p.ParseDeclarationList()
+		if len(p.Errors) != 0 || len(items) != 2 {
+			t.Fatalf("%s: unexpected items %v and errors %v", v.css, items, p.Errors)
+		}
+
+		d, ok := items[0].(*Declaration)
+		if !ok || d.Name() != "a" || d.Token.Type != lexer.VariableToken || d.String() != v.css[:8] {
+			t.Fatalf("%s: unexpected variable declaration %#v", v.css, items[0])
+		}
+	}
+}
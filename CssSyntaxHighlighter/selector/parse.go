@@ -0,0 +1,448 @@
+package selector
+
+import (
+	"strings"
+
+	"github.com/QuickOrBeDead/GoLangLearning/lexer"
+	"github.com/QuickOrBeDead/GoLangLearning/parser"
+)
+
+// Parse parses a selector list from the prelude of a qualified rule.
+// https://www.w3.org/TR/selectors-4/#parse-selector
+func Parse(prelude []parser.ComponentValue) (List, error) {
+	return parseList(prelude, false, false)
+}
+
+// ParseString parses a selector list from text.
+func ParseString(s string) (List, error) {
+	p := parser.New(&lexer.Lexer{Text: []rune(s)})
+	return Parse(p.ParseComponentValueList())
+}
+
+// parseList parses a comma separated list of complex selectors. A forgiving
+// list drops the invalid selectors instead of failing, a relative list
+// allows a leading combinator.
+func parseList(values []parser.ComponentValue, forgiving bool, relative bool) (List, error) {
+	list := List{}
+	start := 0
+	for i := 0; i <= len(values); i++ {
+		if i < len(values) && !isToken(values[i], lexer.CommaToken) {
+			continue
+		}
+
+		c, err := parseComplex(values[start:i], relative)
+		if err != nil {
+			if !forgiving {
+				return nil, err
+			}
+		} else {
+			list = append(list, c)
+		}
+		start = i + 1
+	}
+
+	return list, nil
+}
+
+type scanner struct {
+	values []parser.ComponentValue
+	pos    int
+}
+
+func parseComplex(values []parser.ComponentValue, relative bool) (*Complex, error) {
+	s := &scanner{values: values}
+	s.skipWhitespace()
+	if s.done() {
+		return nil, s.errorAt(values, "expected a selector")
+	}
+
+	c := &Complex{Span: s.peek().Position()}
+	if relative {
+		if comb, ok := s.combinator(); ok {
+			c.Leading = comb
+			s.skipWhitespace()
+		}
+	}
+
+	for {
+		compound, err := s.compound()
+		if err != nil {
+			return nil, err
+		}
+		c.Compounds = append(c.Compounds, compound)
+		c.Stop = compound.Stop
+
+		whitespace := s.skipWhitespace()
+		if s.done() {
+			return c, nil
+		}
+
+		comb, ok := s.combinator()
+		if ok {
+			s.skipWhitespace()
+		} else if whitespace {
+			comb = Descendant
+		} else {
+			return nil, s.errorAt(values, "unexpected "+s.peek().String()+" in selector")
+		}
+
+		if s.done() {
+			return nil, s.errorAt(values, "expected a selector after the combinator")
+		}
+		c.Combinators = append(c.Combinators, comb)
+	}
+}
+
+// https://www.w3.org/TR/selectors-4/#typedef-compound-selector
+func (s *scanner) compound() (*Compound, error) {
+	c := &Compound{Span: s.peek().Position()}
+	start := s.pos
+
+	t, err := s.typeSelector()
+	if err != nil {
+		return nil, err
+	}
+	c.Type = t
+
+	for !s.done() {
+		v := s.peek()
+		switch {
+		case isToken(v, lexer.HashToken):
+			if v.(*parser.Token).Flag != lexer.IDFlag {
+				return nil, s.errorAt(nil, "invalid id selector "+v.String())
+			}
+			s.next()
+			c.Subclasses = append(c.Subclasses, &ID{Name: string(v.(*parser.Token).Value)})
+		case isDelim(v, '.'):
+			s.next()
+			name := s.peek()
+			if !isToken(name, lexer.IdentToken) {
+				return nil, s.errorAt(nil, "expected a class name after '.'")
+			}
+			s.next()
+			c.Subclasses = append(c.Subclasses, &Class{Name: string(name.(*parser.Token).Value)})
+		case isBlock(v, lexer.LeftBracketToken):
+			s.next()
+			a, err := parseAttribute(v.(*parser.SimpleBlock))
+			if err != nil {
+				return nil, err
+			}
+			c.Subclasses = append(c.Subclasses, a)
+		case isToken(v, lexer.ColonToken):
+			if isToken(s.peekAt(1), lexer.ColonToken) {
+				s.pos += 2
+				p, err := s.pseudoElement(false)
+				if err != nil {
+					return nil, err
+				}
+				c.PseudoElements = append(c.PseudoElements, p)
+				continue
+			}
+
+			s.next()
+			if isLegacyPseudoElement(s.peek()) {
+				p, err := s.pseudoElement(true)
+				if err != nil {
+					return nil, err
+				}
+				c.PseudoElements = append(c.PseudoElements, p)
+				continue
+			}
+
+			p, err := s.pseudoClass()
+			if err != nil {
+				return nil, err
+			}
+
+			if n := len(c.PseudoElements); n > 0 {
+				c.PseudoElements[n-1].PseudoClasses = append(c.PseudoElements[n-1].PseudoClasses, p)
+			} else {
+				c.Subclasses = append(c.Subclasses, p)
+			}
+		default:
+			if s.pos == start {
+				return nil, s.errorAt(nil, "unexpected "+v.String()+" in selector")
+			}
+
+			c.Stop = s.values[s.pos-1].Position().Stop
+			return c, nil
+		}
+	}
+
+	if s.pos == start {
+		return nil, s.errorAt(nil, "expected a selector")
+	}
+
+	c.Stop = s.values[s.pos-1].Position().Stop
+	return c, nil
+}
+
+// https://www.w3.org/TR/selectors-4/#typedef-type-selector
+func (s *scanner) typeSelector() (*Type, error) {
+	ns, name, ok, err := s.wqName(true)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	return &Type{Namespace: ns, Name: name}, nil
+}
+
+// wqName consumes an optionally namespace qualified name. It reports false
+// without consuming anything when the input does not start with one.
+func (s *scanner) wqName(allowUniversal bool) (*string, string, bool, error) {
+	v := s.peek()
+	if isDelim(v, '|') {
+		s.next()
+		name, ok := s.nameOrUniversal(allowUniversal)
+		if !ok {
+			return nil, "", false, s.errorAt(nil, "expected a name after '|'")
+		}
+
+		ns := ""
+		return &ns, name, true, nil
+	}
+
+	first, ok := s.nameOrUniversal(true)
+	if !ok {
+		return nil, "", false, nil
+	}
+
+	if isDelim(s.peek(), '|') && (isToken(s.peekAt(1), lexer.IdentToken) || (allowUniversal && isDelim(s.peekAt(1), '*'))) {
+		s.next()
+		name, _ := s.nameOrUniversal(allowUniversal)
+		return &first, name, true, nil
+	}
+
+	if first == "*" && !allowUniversal {
+		return nil, "", false, s.errorAt(nil, "expected a name after '*'")
+	}
+
+	return nil, first, true, nil
+}
+
+func (s *scanner) nameOrUniversal(allowUniversal bool) (string, bool) {
+	v := s.peek()
+	if isToken(v, lexer.IdentToken) {
+		s.next()
+		return string(v.(*parser.Token).Value), true
+	}
+
+	if allowUniversal && isDelim(v, '*') {
+		s.next()
+		return "*", true
+	}
+
+	return "", false
+}
+
+// https://www.w3.org/TR/selectors-4/#typedef-attribute-selector
+func parseAttribute(b *parser.SimpleBlock) (*Attribute, error) {
+	s := &scanner{values: b.Values}
+	s.skipWhitespace()
+	ns, name, ok, err := s.wqName(false)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return nil, s.errorAt(b.Values, "expected an attribute name")
+	}
+
+	a := &Attribute{Namespace: ns, Name: name}
+	s.skipWhitespace()
+	if s.done() {
+		return a, nil
+	}
+
+	v := s.next()
+	if isDelim(v, '=') {
+		a.Matcher = "="
+	} else if t, ok := v.(*parser.Token); ok && isMatchToken(t.Type) {
+		a.Matcher = string(t.Val)
+	} else {
+		return nil, s.errorAt(nil, "expected an attribute matcher")
+	}
+
+	s.skipWhitespace()
+	v = s.next()
+	if !isToken(v, lexer.IdentToken) && !isToken(v, lexer.StringToken) {
+		return nil, s.errorAt(b.Values, "expected an attribute value")
+	}
+	a.Value = string(v.(*parser.Token).Value)
+
+	s.skipWhitespace()
+	if v := s.peek(); isToken(v, lexer.IdentToken) {
+		m := strings.ToLower(string(v.(*parser.Token).Value))
+		if m != "i" && m != "s" {
+			return nil, s.errorAt(nil, "invalid attribute modifier "+v.String())
+		}
+		s.next()
+		a.Modifier = rune(m[0])
+		s.skipWhitespace()
+	}
+
+	if !s.done() {
+		return nil, s.errorAt(nil, "unexpected "+s.peek().String()+" in attribute selector")
+	}
+
+	return a, nil
+}
+
+// pseudoClass parses a pseudo-class after its colon.
+// https://www.w3.org/TR/selectors-4/#typedef-pseudo-class-selector
+func (s *scanner) pseudoClass() (*PseudoClass, error) {
+	v := s.next()
+	if isToken(v, lexer.IdentToken) {
+		return &PseudoClass{Name: string(v.(*parser.Token).Value)}, nil
+	}
+
+	f, ok := v.(*parser.Function)
+	if !ok {
+		return nil, s.errorAt(nil, "expected a pseudo-class name")
+	}
+
+	p := &PseudoClass{Name: f.Name(), Function: true, Args: f.Values}
+	var err error
+	switch strings.ToLower(p.Name) {
+	case "is", "where", "matches", "-webkit-any", "-moz-any":
+		p.Selectors, err = parseList(f.Values, true, false)
+	case "not":
+		p.Selectors, err = parseList(f.Values, false, false)
+	case "has":
+		p.Selectors, err = parseList(f.Values, false, true)
+	case "nth-child", "nth-last-child":
+		for i, arg := range f.Values {
+			if isIdentValue(arg, "of") {
+				p.Selectors, err = parseList(f.Values[i+1:], false, false)
+				break
+			}
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// pseudoElement parses a pseudo-element after its colons.
+// https://www.w3.org/TR/selectors-4/#typedef-pseudo-element-selector
+func (s *scanner) pseudoElement(legacy bool) (*PseudoElement, error) {
+	v := s.next()
+	if isToken(v, lexer.IdentToken) {
+		return &PseudoElement{Name: string(v.(*parser.Token).Value), Legacy: legacy}, nil
+	}
+
+	if f, ok := v.(*parser.Function); ok {
+		return &PseudoElement{Name: f.Name(), Function: true, Args: f.Values, Legacy: legacy}, nil
+	}
+
+	return nil, s.errorAt(nil, "expected a pseudo-element name")
+}
+
+// https://www.w3.org/TR/selectors-4/#typedef-combinator
+func (s *scanner) combinator() (Combinator, bool) {
+	v := s.peek()
+	for _, c := range []Combinator{Child, NextSibling, SubsequentSibling} {
+		if isDelim(v, rune(c)) {
+			s.next()
+			return c, true
+		}
+	}
+
+	if isToken(v, lexer.ColumnToken) {
+		s.next()
+		return Column, true
+	}
+
+	return 0, false
+}
+
+func (s *scanner) done() bool {
+	return s.pos >= len(s.values)
+}
+
+func (s *scanner) peek() parser.ComponentValue {
+	return s.peekAt(0)
+}
+
+func (s *scanner) peekAt(c int) parser.ComponentValue {
+	if s.pos+c >= len(s.values) {
+		return nil
+	}
+
+	return s.values[s.pos+c]
+}
+
+func (s *scanner) next() parser.ComponentValue {
+	v := s.peek()
+	if v != nil {
+		s.pos++
+	}
+
+	return v
+}
+
+func (s *scanner) skipWhitespace() bool {
+	skipped := false
+	for v := s.peek(); isTrivia(v); v = s.peek() {
+		s.next()
+		skipped = true
+	}
+
+	return skipped
+}
+
+// errorAt returns an error at the current value, or at the end of values
+// when the input is exhausted.
+func (s *scanner) errorAt(values []parser.ComponentValue, msg string) error {
+	var span parser.Span
+	if v := s.peek(); v != nil {
+		span = v.Position()
+	} else if s.pos > 0 {
+		span = s.values[s.pos-1].Position()
+	} else if len(values) > 0 {
+		span = values[0].Position()
+	}
+
+	return parser.Error{Line: span.Line, Col: span.Col, Msg: msg}
+}
+
+func isLegacyPseudoElement(v parser.ComponentValue) bool {
+	return isIdentValue(v, "before") || isIdentValue(v, "after") || isIdentValue(v, "first-line") || isIdentValue(v, "first-letter")
+}
+
+func isMatchToken(t lexer.TokenType) bool {
+	switch t {
+	case lexer.IncludeMatchToken, lexer.DashMatchToken, lexer.PrefixMatchToken, lexer.SuffixMatchToken, lexer.SubstringMatchToken:
+		return true
+	default:
+		return false
+	}
+}
+
+func isToken(v parser.ComponentValue, tokenType lexer.TokenType) bool {
+	t, ok := v.(*parser.Token)
+	return ok && t.Type == tokenType
+}
+
+func isTrivia(v parser.ComponentValue) bool {
+	t, ok := v.(*parser.Token)
+	return ok && t.Type.IsTrivia()
+}
+
+func isBlock(v parser.ComponentValue, open lexer.TokenType) bool {
+	b, ok := v.(*parser.SimpleBlock)
+	return ok && b.Open.Type == open
+}
+
+func isDelim(v parser.ComponentValue, r rune) bool {
+	t, ok := v.(*parser.Token)
+	return ok && t.Type == lexer.DelimToken && len(t.Val) == 1 && t.Val[0] == r
+}
+
+func isIdentValue(v parser.ComponentValue, name string) bool {
+	t, ok := v.(*parser.Token)
+	return ok && t.Type == lexer.IdentToken && strings.EqualFold(string(t.Value), name)
+}
@@ -0,0 +1,386 @@
+package selector
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/QuickOrBeDead/GoLangLearning/parser"
+)
+
+// List is a selector list, the prelude of a style rule.
+// https://www.w3.org/TR/selectors-4/#grouping
+type List []*Complex
+
+// Complex is a chain of compound selectors joined by combinators.
+// https://www.w3.org/TR/selectors-4/#complex
+type Complex struct {
+	parser.Span
+	// Leading is the combinator a relative selector, like the arguments of
+	// :has(), starts with. It is zero for other selectors.
+	Leading   Combinator
+	Compounds []*Compound
+	// Combinators[i] joins Compounds[i] and Compounds[i+1].
+	Combinators []Combinator
+}
+
+type Combinator rune
+
+const (
+	Descendant        Combinator = ' '
+	Child             Combinator = '>'
+	NextSibling       Combinator = '+'
+	SubsequentSibling Combinator = '~'
+	Column            Combinator = '|'
+)
+
+// Compound is a sequence of simple selectors that are not separated by a
+// combinator.
+// https://www.w3.org/TR/selectors-4/#compound
+type Compound struct {
+	parser.Span
+	// Type is nil when the compound selector has no type or universal
+	// selector.
+	Type       *Type
+	Subclasses []Simple
+	// PseudoElements are the pseudo-elements at the end of the compound
+	// selector, each with the pseudo-classes that follow it.
+	PseudoElements []*PseudoElement
+}
+
+// Simple is an *ID, *Class, *Attribute or *PseudoClass selector.
+type Simple interface {
+	Specificity() Specificity
+	String() string
+}
+
+// Type is a type selector, or the universal selector when Name is "*".
+// Namespace is nil when the selector has no namespace prefix.
+type Type struct {
+	Namespace *string
+	Name      string
+}
+
+type ID struct {
+	Name string
+}
+
+type Class struct {
+	Name string
+}
+
+// https://www.w3.org/TR/selectors-4/#attribute-selectors
+type Attribute struct {
+	Namespace *string
+	Name      string
+	// Matcher is one of "", "=", "~=", "|=", "^=", "$=" and "*=". Value and
+	// Modifier are only set when there is a matcher.
+	Matcher  string
+	Value    string
+	Modifier rune
+}
+
+// PseudoClass is a pseudo-class like :hover, or a functional one like
+// :not(). Selectors holds the parsed selector argument of :is(), :not(),
+// :where(), :has() and the "of S" part of :nth-child().
+type PseudoClass struct {
+	Name      string
+	Function  bool
+	Args      []parser.ComponentValue
+	Selectors List
+}
+
+// PseudoElement is a pseudo-element like ::before. Legacy is set for the
+// ones written with a single colon.
+type PseudoElement struct {
+	Name          string
+	Function      bool
+	Args          []parser.ComponentValue
+	Legacy        bool
+	PseudoClasses []*PseudoClass
+}
+
+// Specificity is the (a, b, c) specificity of a selector.
+// https://www.w3.org/TR/selectors-4/#specificity-rules
+type Specificity [3]int
+
+func (s Specificity) Add(o Specificity) Specificity {
+	return Specificity{s[0] + o[0], s[1] + o[1], s[2] + o[2]}
+}
+
+// Compare returns -1, 0 or 1 when s is less, equally or more specific than o.
+func (s Specificity) Compare(o Specificity) int {
+	for i := range s {
+		if s[i] < o[i] {
+			return -1
+		} else if s[i] > o[i] {
+			return 1
+		}
+	}
+
+	return 0
+}
+
+func (s Specificity) String() string {
+	return fmt.Sprintf("(%d,%d,%d)", s[0], s[1], s[2])
+}
+
+// Specificity returns the specificity of the most specific selector of the
+// list.
+func (l List) Specificity() Specificity {
+	max := Specificity{}
+	for _, c := range l {
+		if s := c.Specificity(); s.Compare(max) > 0 {
+			max = s
+		}
+	}
+
+	return max
+}
+
+func (c *Complex) Specificity() Specificity {
+	s := Specificity{}
+	for _, compound := range c.Compounds {
+		s = s.Add(compound.Specificity())
+	}
+
+	return s
+}
+
+func (c *Compound) Specificity() Specificity {
+	s := Specificity{}
+	if c.Type != nil {
+		s = s.Add(c.Type.Specificity())
+	}
+
+	for _, simple := range c.Subclasses {
+		s = s.Add(simple.Specificity())
+	}
+
+	for _, p := range c.PseudoElements {
+		s = s.Add(p.Specificity())
+	}
+
+	return s
+}
+
+func (t *Type) Specificity() Specificity {
+	if t.Name == "*" {
+		return Specificity{}
+	}
+
+	return Specificity{0, 0, 1}
+}
+
+func (*ID) Specificity() Specificity {
+	return Specificity{1, 0, 0}
+}
+
+func (*Class) Specificity() Specificity {
+	return Specificity{0, 1, 0}
+}
+
+func (*Attribute) Specificity() Specificity {
+	return Specificity{0, 1, 0}
+}
+
+func (p *PseudoClass) Specificity() Specificity {
+	switch strings.ToLower(p.Name) {
+	case "where":
+		return Specificity{}
+	case "is", "not", "has", "matches", "-webkit-any", "-moz-any":
+		return p.Selectors.Specificity()
+	default:
+		return Specificity{0, 1, 0}.Add(p.Selectors.Specificity())
+	}
+}
+
+func (p *PseudoElement) Specificity() Specificity {
+	s := Specificity{0, 0, 1}
+	for _, c := range p.PseudoClasses {
+		s = s.Add(c.Specificity())
+	}
+
+	return s
+}
+
+func (l List) String() string {
+	items := make([]string, len(l))
+	for i, c := range l {
+		items[i] = c.String()
+	}
+
+	return strings.Join(items, ", ")
+}
+
+func (c *Complex) String() string {
+	var sb strings.Builder
+	if c.Leading != 0 {
+		sb.WriteString(c.Leading.String())
+		sb.WriteString(" ")
+	}
+
+	for i, compound := range c.Compounds {
+		if i > 0 {
+			sb.WriteString(" ")
+			if comb := c.Combinators[i-1]; comb != Descendant {
+				sb.WriteString(comb.String())
+				sb.WriteString(" ")
+			}
+		}
+		sb.WriteString(compound.String())
+	}
+
+	return sb.String()
+}
+
+func (c Combinator) String() string {
+	if c == Column {
+		return "||"
+	}
+
+	return string(c)
+}
+
+func (c *Compound) String() string {
+	var sb strings.Builder
+	if c.Type != nil {
+		sb.WriteString(c.Type.String())
+	}
+
+	for _, s := range c.Subclasses {
+		sb.WriteString(s.String())
+	}
+
+	for _, p := range c.PseudoElements {
+		sb.WriteString(p.String())
+	}
+
+	return sb.String()
+}
+
+func (t *Type) String() string {
+	if t.Name == "*" {
+		return namespacePrefix(t.Namespace) + t.Name
+	}
+
+	return namespacePrefix(t.Namespace) + escapeIdent(t.Name)
+}
+
+func (id *ID) String() string {
+	return "#" + escapeIdent(id.Name)
+}
+
+func (c *Class) String() string {
+	return "." + escapeIdent(c.Name)
+}
+
+func (a *Attribute) String() string {
+	var sb strings.Builder
+	sb.WriteString("[")
+	sb.WriteString(namespacePrefix(a.Namespace))
+	sb.WriteString(escapeIdent(a.Name))
+	if a.Matcher != "" {
+		sb.WriteString(a.Matcher)
+		sb.WriteString(quoteString(a.Value))
+		if a.Modifier != 0 {
+			sb.WriteString(" ")
+			sb.WriteRune(a.Modifier)
+		}
+	}
+	sb.WriteString("]")
+
+	return sb.String()
+}
+
+func (p *PseudoClass) String() string {
+	return ":" + escapeIdent(p.Name) + functionArgs(p.Function, p.Args)
+}
+
+func (p *PseudoElement) String() string {
+	var sb strings.Builder
+	if p.Legacy {
+		sb.WriteString(":")
+	} else {
+		sb.WriteString("::")
+	}
+	sb.WriteString(escapeIdent(p.Name))
+	sb.WriteString(functionArgs(p.Function, p.Args))
+	for _, c := range p.PseudoClasses {
+		sb.WriteString(c.String())
+	}
+
+	return sb.String()
+}
+
+func namespacePrefix(ns *string) string {
+	if ns == nil {
+		return ""
+	}
+
+	if *ns == "*" {
+		return "*|"
+	}
+
+	return escapeIdent(*ns) + "|"
+}
+
+// https://www.w3.org/TR/cssom-1/#serialize-an-identifier
+func escapeIdent(name string) string {
+	var sb strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		switch {
+		case r == 0:
+			sb.WriteRune('\uFFFD')
+		case (r >= 0x01 && r <= 0x1F) || r == 0x7F,
+			r >= '0' && r <= '9' && (i == 0 || (i == 1 && runes[0] == '-')):
+			sb.WriteString(fmt.Sprintf("\\%x ", r))
+		case i == 0 && r == '-' && len(runes) == 1:
+			sb.WriteString("\\-")
+		case r >= 0x80, r == '-', r == '_', r >= '0' && r <= '9', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+			sb.WriteRune(r)
+		default:
+			sb.WriteRune('\\')
+			sb.WriteRune(r)
+		}
+	}
+
+	return sb.String()
+}
+
+// https://www.w3.org/TR/cssom-1/#serialize-a-string
+func quoteString(s string) string {
+	var sb strings.Builder
+	sb.WriteRune('"')
+	for _, r := range s {
+		switch {
+		case r == 0:
+			sb.WriteRune('\uFFFD')
+		case (r >= 0x01 && r <= 0x1F) || r == 0x7F:
+			sb.WriteString(fmt.Sprintf("\\%x ", r))
+		case r == '"', r == '\\':
+			sb.WriteRune('\\')
+			sb.WriteRune(r)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	sb.WriteRune('"')
+
+	return sb.String()
+}
+
+func functionArgs(function bool, args []parser.ComponentValue) string {
+	if !function {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("(")
+	for _, v := range args {
+		sb.WriteString(v.String())
+	}
+	sb.WriteString(")")
+
+	return sb.String()
+}
@@ -0,0 +1,115 @@
+package selector
+
+import (
+	"testing"
+)
+
+func TestParseString(t *testing.T) {
+	values := []struct {
+		selector    string
+		output      string
+		specificity Specificity
+	}{
+		{"*", "*", Specificity{0, 0, 0}},
+		{"li", "li", Specificity{0, 0, 1}},
+		{"ul li", "ul li", Specificity{0, 0, 2}},
+		{"ul  >  ol+li", "ul > ol + li", Specificity{0, 0, 3}},
+		{"h1 + *[rel=up]", "h1 + *[rel=\"up\"]", Specificity{0, 1, 1}},
+		{"ul ol li.red", "ul ol li.red", Specificity{0, 1, 3}},
+		{"li.red.level", "li.red.level", Specificity{0, 2, 1}},
+		{"#x34y", "#x34y", Specificity{1, 0, 0}},
+		{"#s12:not(FOO)", "#s12:not(FOO)", Specificity{1, 0, 1}},
+		{".foo :is(.bar, #baz)", ".foo :is(.bar, #baz)", Specificity{1, 1, 0}},
+		{"a:where(.b, #c)", "a:where(.b, #c)", Specificity{0, 0, 1}},
+		{"a:has(> img, + .x)", "a:has(> img, + .x)", Specificity{0, 1, 1}},
+		{"li:nth-child(2n+1 of .a, #b)", "li:nth-child(2n+1 of .a, #b)", Specificity{1, 1, 1}},
+		{"li:nth-child(2n+1)", "li:nth-child(2n+1)", Specificity{0, 1, 1}},
+		{"a::before", "a::before", Specificity{0, 0, 2}},
+		{"a:after", "a:after", Specificity{0, 0, 2}},
+		{"p::first-line:hover", "p::first-line:hover", Specificity{0, 1, 2}},
+		{"::part(label)", "::part(label)", Specificity{0, 0, 1}},
+		{"svg|a", "svg|a", Specificity{0, 0, 1}},
+		{"*|*", "*|*", Specificity{0, 0, 0}},
+		{"|a", "|a", Specificity{0, 0, 1}},
+		{"[ data-x ]", "[data-x]", Specificity{0, 1, 0}},
+		{"[lang|=en]", "[lang|=\"en\"]", Specificity{0, 1, 0}},
+		{"[href$='.pdf' i]", "[href$=\".pdf\" i]", Specificity{0, 1, 0}},
+		{"[xlink|href^=\"#\"]", "[xlink|href^=\"#\"]", Specificity{0, 1, 0}},
+		{"col.selected || td", "col.selected || td", Specificity{0, 1, 2}},
+		{".a\\:b", ".a\\:b", Specificity{0, 1, 0}},
+		{"a, b#c, d.e", "a, b#c, d.e", Specificity{1, 0, 1}},
+		{"a /* c */ b", "a b", Specificity{0, 0, 2}},
+	}
+
+	for _, v := range values {
+		l, err := ParseString(v.selector)
+		if err != nil {
+			t.Fatalf("%s unexpected error %v", v.selector, err)
+		}
+
+		if l.String() != v.output {
+			t.Fatalf("%s (expected) %q != %q (actual)", v.selector, v.output, l.String())
+		}
+
+		if s := l.Specificity(); s != v.specificity {
+			t.Fatalf("%s specificity (expected) %v != %v (actual)", v.selector, v.specificity, s)
+		}
+	}
+}
+
+func TestParseStringErrors(t *testing.T) {
+	values := []string{
+		"",
+		"a,",
+		"a >",
+		"> a",
+		"a..b",
+		"#123",
+		"a[=x]",
+		"a[x=]",
+		"a[x=y z]",
+		"a[x=y q]",
+		"a:not(,)",
+		"a:has()",
+		"a:",
+		"{}",
+		"a!",
+	}
+
+	for _, v := range values {
+		if l, err := ParseString(v); err == nil {
+			t.Fatalf("%q expected an error, got %q", v, l.String())
+		}
+	}
+}
+
+func TestParseForgivingList(t *testing.T) {
+	l, err := ParseString(":is(.a, !, .b) :where(#c, 1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if l.String() != ":is(.a, !, .b) :where(#c, 1)" {
+		t.Fatalf("unexpected selector %q", l.String())
+	}
+
+	is := l[0].Compounds[0].Subclasses[0].(*PseudoClass)
+	if len(is.Selectors) != 2 || is.Selectors.String() != ".a, .b" {
+		t.Fatalf("unexpected :is() selectors %q", is.Selectors.String())
+	}
+}
+
+func TestParsePositions(t *testing.T) {
+	l, err := ParseString("a b,\n  .c > d")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if l[0].Start != 0 || l[0].Stop != 3 || l[1].Start != 7 || l[1].Stop != 13 || l[1].Line != 2 || l[1].Col != 3 {
+		t.Fatalf("unexpected spans %+v %+v", l[0].Span, l[1].Span)
+	}
+
+	if c := l[1].Compounds[1]; c.Start != 12 || c.Stop != 13 {
+		t.Fatalf("unexpected compound span %+v", c.Span)
+	}
+}
@@ -0,0 +1,298 @@
+// Package sourcemap writes and reads Source Map v3 files, which link the
+// positions of a generated file to the positions in its sources.
+// https://tc39.es/ecma426/
+package sourcemap
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+// Map is the JSON form of a source map.
+type Map struct {
+	Version    int      `json:"version"`
+	File       string   `json:"file,omitempty"`
+	SourceRoot string   `json:"sourceRoot,omitempty"`
+	Sources    []string `json:"sources"`
+	// SourcesContent is only read, the sources are served next to the map.
+	SourcesContent []*string `json:"sourcesContent,omitempty"`
+	Names          []string  `json:"names"`
+	Mappings       string    `json:"mappings"`
+}
+
+// Mapping links a position of the generated file to one in a source. Lines
+// and columns are 0-based as in the format, and columns count UTF-16 code
+// units.
+type Mapping struct {
+	GeneratedLine int
+	GeneratedCol  int
+	Source        string
+	OriginalLine  int
+	OriginalCol   int
+}
+
+// Generator records the mappings of an output while the output is written to
+// it. It does not keep the output, so it is written to both. The zero value
+// is an empty generator.
+type Generator struct {
+	// line and col are the position of the next write.
+	line, col int
+	afterCR   bool
+	mappings  []Mapping
+	sources   map[string]*sourceText
+}
+
+// sourceText is what a generator knows of the text of a source: where the
+// characters outside of the BMP are, which are one rune but two UTF-16 code
+// units.
+type sourceText struct {
+	// line and col are the position of the next write in runes.
+	line, col int
+	afterCR   bool
+	// astral are the rune columns of the characters outside of the BMP by
+	// line.
+	astral map[int][]int
+}
+
+// Write advances the generated position past p.
+func (g *Generator) Write(p []byte) (int, error) {
+	return g.WriteString(string(p))
+}
+
+// WriteString advances the generated position past s. Line breaks are the
+// ones of CSS: LF, CR, CRLF and form feed.
+func (g *Generator) WriteString(s string) (int, error) {
+	for _, r := range s {
+		switch {
+		case r == '\n' && g.afterCR:
+		case r == '\n' || r == '\r' || r == '\f':
+			g.line++
+			g.col = 0
+		default:
+			g.col += len(utf16.Encode([]rune{r}))
+		}
+
+		g.afterCR = r == '\r'
+	}
+
+	return len(s), nil
+}
+
+// WriteSource records the text s of source, which is written in order as it
+// is read, so that Add can count its columns in UTF-16 code units. Line
+// breaks are the ones of CSS like in WriteString. A source whose text is not
+// written is taken to have no characters outside of the BMP.
+func (g *Generator) WriteSource(source string, s string) {
+	if g.sources == nil {
+		g.sources = make(map[string]*sourceText)
+	}
+
+	t := g.sources[source]
+	if t == nil {
+		t = &sourceText{astral: make(map[int][]int)}
+		g.sources[source] = t
+	}
+
+	for _, r := range s {
+		switch {
+		case r == '\n' && t.afterCR:
+		case r == '\n' || r == '\r' || r == '\f':
+			t.line++
+			t.col = 0
+		default:
+			if r > 0xFFFF {
+				t.astral[t.line] = append(t.astral[t.line], t.col)
+			}
+			t.col++
+		}
+
+		t.afterCR = r == '\r'
+	}
+}
+
+// Add maps the generated position of the next write to a position in
+// source. line and col are 1-based like the ones of tokens and col counts
+// runes; it is converted to UTF-16 code units with the text of the source
+// written with WriteSource.
+func (g *Generator) Add(source string, line, col int) {
+	m := Mapping{GeneratedLine: g.line, GeneratedCol: g.col, Source: source, OriginalLine: line - 1, OriginalCol: col - 1}
+	if t := g.sources[source]; t != nil {
+		for _, c := range t.astral[m.OriginalLine] {
+			if c >= col-1 {
+				break
+			}
+			m.OriginalCol++
+		}
+	}
+
+	if n := len(g.mappings); n > 0 && g.mappings[n-1].GeneratedLine == m.GeneratedLine && g.mappings[n-1].GeneratedCol == m.GeneratedCol {
+		g.mappings[n-1] = m
+		return
+	}
+
+	g.mappings = append(g.mappings, m)
+}
+
+// Append adds the mappings of other as if its output was written to g.
+func (g *Generator) Append(other *Generator) {
+	for _, m := range other.mappings {
+		if m.GeneratedLine == 0 {
+			m.GeneratedCol += g.col
+		}
+		m.GeneratedLine += g.line
+
+		if n := len(g.mappings); n > 0 && g.mappings[n-1].GeneratedLine == m.GeneratedLine && g.mappings[n-1].GeneratedCol == m.GeneratedCol {
+			g.mappings = g.mappings[:n-1]
+		}
+		g.mappings = append(g.mappings, m)
+	}
+
+	if other.line == 0 {
+		g.col += other.col
+	} else {
+		g.line += other.line
+		g.col = other.col
+		g.afterCR = other.afterCR
+	}
+}
+
+func (g *Generator) Mappings() []Mapping {
+	return g.mappings
+}
+
+// Map returns the source map of the generated file file. The sources are
+// listed in the order of their first mapping.
+func (g *Generator) Map(file string) *Map {
+	return Encode(file, g.mappings)
+}
+
+// Encode returns the source map of mappings, which are sorted by their
+// generated position.
+// https://tc39.es/ecma426/#sec-mappings
+func Encode(file string, mappings []Mapping) *Map {
+	m := &Map{Version: 3, File: file, Sources: []string{}, Names: []string{}}
+	sources := make(map[string]int)
+	var sb strings.Builder
+	var line, col, source, originalLine, originalCol int
+	for i, mapping := range mappings {
+		if mapping.GeneratedLine != line || i == 0 {
+			for ; line < mapping.GeneratedLine; line++ {
+				sb.WriteByte(';')
+			}
+			col = 0
+		} else {
+			sb.WriteByte(',')
+		}
+
+		index, ok := sources[mapping.Source]
+		if !ok {
+			index = len(m.Sources)
+			sources[mapping.Source] = index
+			m.Sources = append(m.Sources, mapping.Source)
+		}
+
+		writeVLQ(&sb, mapping.GeneratedCol-col)
+		writeVLQ(&sb, index-source)
+		writeVLQ(&sb, mapping.OriginalLine-originalLine)
+		writeVLQ(&sb, mapping.OriginalCol-originalCol)
+		col, source, originalLine, originalCol = mapping.GeneratedCol, index, mapping.OriginalLine, mapping.OriginalCol
+	}
+	m.Mappings = sb.String()
+
+	return m
+}
+
+// Parse reads the JSON of a source map.
+func Parse(data []byte) (*Map, error) {
+	m := &Map{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, err
+	}
+
+	if m.Version != 3 {
+		return nil, fmt.Errorf("unsupported source map version %d", m.Version)
+	}
+
+	return m, nil
+}
+
+// Decode returns the mappings of m. Segments without a source are skipped
+// and the source root is not added to the sources.
+func Decode(m *Map) ([]Mapping, error) {
+	mappings := []Mapping{}
+	var col, source, originalLine, originalCol int
+	for line, text := range strings.Split(m.Mappings, ";") {
+		col = 0
+		for _, segment := range strings.Split(text, ",") {
+			if segment == "" {
+				continue
+			}
+
+			fields := []int{}
+			for rest := segment; rest != ""; {
+				v, r, err := readVLQ(rest)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: segment %q: %v", line+1, segment, err)
+				}
+
+				fields = append(fields, v)
+				rest = r
+			}
+
+			if len(fields) != 1 && len(fields) != 4 && len(fields) != 5 {
+				return nil, fmt.Errorf("line %d: segment %q has %d fields", line+1, segment, len(fields))
+			}
+
+			col += fields[0]
+			if col < 0 {
+				return nil, fmt.Errorf("line %d: segment %q is out of range", line+1, segment)
+			}
+
+			if len(fields) == 1 {
+				continue
+			}
+
+			source += fields[1]
+			originalLine += fields[2]
+			originalCol += fields[3]
+			if originalLine < 0 || originalCol < 0 || source < 0 || source >= len(m.Sources) {
+				return nil, fmt.Errorf("line %d: segment %q is out of range", line+1, segment)
+			}
+
+			mappings = append(mappings, Mapping{
+				GeneratedLine: line,
+				GeneratedCol:  col,
+				Source:        m.Sources[source],
+				OriginalLine:  originalLine,
+				OriginalCol:   originalCol,
+			})
+		}
+	}
+
+	return mappings, nil
+}
+
+// Lookup returns the mapping of a generated position: the last one on the
+// same line that does not come after it.
+func Lookup(mappings []Mapping, line, col int) (Mapping, bool) {
+	found, ok := Mapping{}, false
+	for _, m := range mappings {
+		if m.GeneratedLine > line || (m.GeneratedLine == line && m.GeneratedCol > col) {
+			break
+		}
+
+		if m.GeneratedLine == line {
+			found, ok = m, true
+		}
+	}
+
+	return found, ok
+}
+
+// Comment returns the comment at the end of a stylesheet that points to its
+// source map.
+func Comment(url string) string {
+	return "/*# sourceMappingURL=" + url + " */\n"
+}
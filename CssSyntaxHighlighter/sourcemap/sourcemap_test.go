@@ -0,0 +1,171 @@
+package sourcemap
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestVLQ(t *testing.T) {
+	values := []struct {
+		v    int
+		text string
+	}{
+		{0, "A"},
+		{1, "C"},
+		{-1, "D"},
+		{15, "e"},
+		{16, "gB"},
+		{-16, "hB"},
+		{123, "2H"},
+		{1 << 20, "ggggC"},
+	}
+
+	for _, v := range values {
+		var sb strings.Builder
+		writeVLQ(&sb, v.v)
+		if sb.String() != v.text {
+			t.Fatalf("%d (expected) %q != %q (actual)", v.v, v.text, sb.String())
+		}
+
+		n, rest, err := readVLQ(v.text + "A")
+		if err != nil || n != v.v || rest != "A" {
+			t.Fatalf("%q (expected) %d != %d (actual), %q, %v", v.text, v.v, n, rest, err)
+		}
+	}
+
+	for _, s := range []string{"", "g", "!", "gggggggB"} {
+		if _, _, err := readVLQ(s); err == nil {
+			t.Fatalf("%q without error", s)
+		}
+	}
+}
+
+func TestGenerator(t *testing.T) {
+	var g Generator
+	g.Add("a.css", 1, 1)
+	g.WriteString("a{")
+	g.Add("a.css", 1, 5)
+	g.WriteString("color:red}\r\n")
+	g.Add("b.css", 3, 2)
+	g.Add("b.css", 3, 3)
+	g.WriteString("é𝒳")
+
+	var other Generator
+	other.Add("a.css", 2, 1)
+	other.WriteString("b{}\n")
+	other.Add("c.css", 1, 1)
+	g.Append(&other)
+
+	expected := []Mapping{
+		{0, 0, "a.css", 0, 0},
+		{0, 2, "a.css", 0, 4},
+		{1, 0, "b.css", 2, 2},
+		{1, 3, "a.css", 1, 0},
+		{2, 0, "c.css", 0, 0},
+	}
+	if !reflect.DeepEqual(g.Mappings(), expected) {
+		t.Fatalf("(expected) %v != %v (actual)", expected, g.Mappings())
+	}
+
+	m := g.Map("out.css")
+	if m.Mappings != "AAAA,EAAI;ACEF,GDDF;AEDA" {
+		t.Fatalf("mappings (expected) %q != %q (actual)", "AAAA,EAAI;ACEF,GDDF;AEDA", m.Mappings)
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := `{"version":3,"file":"out.css","sources":["a.css","b.css","c.css"],"names":[],"mappings":"AAAA,EAAI;ACEF,GDDF;AEDA"}`; string(data) != expected {
+		t.Fatalf("(expected) %s != %s (actual)", expected, data)
+	}
+
+	parsed, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := Decode(parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(decoded, expected) {
+		t.Fatalf("decoded (expected) %v != %v (actual)", expected, decoded)
+	}
+
+	lookups := []struct {
+		line, col int
+		mapping   Mapping
+		ok        bool
+	}{
+		{0, 0, expected[0], true},
+		{0, 1, expected[0], true},
+		{0, 9, expected[1], true},
+		{1, 2, expected[2], true},
+		{1, 10, expected[3], true},
+		{2, 5, expected[4], true},
+		{3, 0, Mapping{}, false},
+	}
+
+	for _, v := range lookups {
+		if mapping, ok := Lookup(decoded, v.line, v.col); mapping != v.mapping || ok != v.ok {
+			t.Fatalf("%d:%d (expected) %v %v != %v %v (actual)", v.line, v.col, v.mapping, v.ok, mapping, ok)
+		}
+	}
+}
+
+func TestGeneratorWriteSource(t *testing.T) {
+	var g Generator
+	g.WriteSource("a.css", "/*😀*/ a{}\r")
+	g.WriteSource("a.css", "\n𝒳b😀 c")
+	g.Add("a.css", 1, 2)
+	g.WriteString("a")
+	g.Add("a.css", 1, 6)
+	g.WriteString("b")
+	g.Add("a.css", 2, 2)
+	g.WriteString("c")
+	g.Add("a.css", 2, 5)
+	g.WriteString("d")
+	g.Add("b.css", 1, 3)
+
+	expected := []Mapping{
+		{0, 0, "a.css", 0, 1},
+		{0, 1, "a.css", 0, 6},
+		{0, 2, "a.css", 1, 2},
+		{0, 3, "a.css", 1, 6},
+		{0, 4, "b.css", 0, 2},
+	}
+	if !reflect.DeepEqual(g.Mappings(), expected) {
+		t.Fatalf("(expected) %v != %v (actual)", expected, g.Mappings())
+	}
+}
+
+func TestDecode(t *testing.T) {
+	mappings, err := Decode(&Map{Version: 3, Sources: []string{"a.css"}, Mappings: ";;C,CAAC,CAAC,,E;AACA"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Mapping{
+		{2, 2, "a.css", 0, 1},
+		{2, 3, "a.css", 0, 2},
+		{3, 0, "a.css", 1, 2},
+	}
+	if !reflect.DeepEqual(mappings, expected) {
+		t.Fatalf("(expected) %v != %v (actual)", expected, mappings)
+	}
+
+	for _, s := range []string{"AA", "ACAA", "ADAA", "AAAA,D", "A!AA", "AAAAAA"} {
+		if _, err := Decode(&Map{Version: 3, Sources: []string{"a.css"}, Mappings: s}); err == nil {
+			t.Fatalf("%q without error", s)
+		}
+	}
+
+	if _, err := Parse([]byte(`{"version":2,"sources":[],"mappings":""}`)); err == nil {
+		t.Fatalf("version 2 without error")
+	}
+}
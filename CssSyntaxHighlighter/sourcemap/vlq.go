@@ -0,0 +1,65 @@
+package sourcemap
+
+import (
+	"fmt"
+	"strings"
+)
+
+const base64Digits = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+// Each base64 digit carries 5 bits of the value and a continuation bit. The
+// lowest bit of the first digit is the sign.
+// https://tc39.es/ecma426/#sec-base64-vlq
+const (
+	vlqShift        = 5
+	vlqContinuation = 1 << vlqShift
+	vlqMask         = vlqContinuation - 1
+)
+
+func writeVLQ(sb *strings.Builder, v int) {
+	u := v << 1
+	if v < 0 {
+		u = -v<<1 | 1
+	}
+
+	for {
+		digit := u & vlqMask
+		u >>= vlqShift
+		if u > 0 {
+			digit |= vlqContinuation
+		}
+
+		sb.WriteByte(base64Digits[digit])
+		if u == 0 {
+			return
+		}
+	}
+}
+
+// readVLQ reads the value at the start of s and returns it with the rest of
+// s.
+func readVLQ(s string) (int, string, error) {
+	u, shift := 0, 0
+	for i := 0; i < len(s); i++ {
+		digit := strings.IndexByte(base64Digits, s[i])
+		if digit < 0 {
+			return 0, "", fmt.Errorf("invalid base64 digit %q", s[i])
+		}
+
+		if shift > 30 {
+			return 0, "", fmt.Errorf("VLQ value out of range")
+		}
+
+		u |= (digit & vlqMask) << shift
+		shift += vlqShift
+		if digit&vlqContinuation == 0 {
+			if u&1 == 1 {
+				return -(u >> 1), s[i+1:], nil
+			}
+
+			return u >> 1, s[i+1:], nil
+		}
+	}
+
+	return 0, "", fmt.Errorf("unterminated VLQ value")
+}
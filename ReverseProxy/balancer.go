@@ -0,0 +1,251 @@
+package main
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// Upstream is one of the servers of a route.
+type Upstream struct {
+	Address *url.URL
+
+	active   atomic.Int64
+	requests atomic.Int64
+
+	// down is set when the last probe of the active health check failed.
+	down atomic.Bool
+	// failures are the failed requests in a row and ejectedUntil the end of
+	// the cooldown in Unix nanoseconds, for the passive health check.
+	failures     atomic.Int64
+	ejectedUntil atomic.Int64
+}
+
+// Connections returns the number of requests that the upstream is serving.
+func (u *Upstream) Connections() int64 {
+	return u.active.Load()
+}
+
+// Requests returns the number of requests that were sent to the upstream.
+func (u *Upstream) Requests() int64 {
+	return u.requests.Load()
+}
+
+// acquire counts a request sent to the upstream until release is called.
+func (u *Upstream) acquire() {
+	u.active.Add(1)
+	u.requests.Add(1)
+}
+
+func (u *Upstream) release() {
+	u.active.Add(-1)
+}
+
+// Balancer picks the upstream of a request. Next is called from many
+// goroutines and upstreams is never empty. It may be a part of the upstreams
+// the balancer was created for.
+type Balancer interface {
+	Next(r *http.Request, upstreams []*Upstream) *Upstream
+}
+
+const (
+	RoundRobin         = "round-robin"
+	WeightedRoundRobin = "weighted-round-robin"
+	LeastConnections   = "least-connections"
+	RandomTwoChoices   = "random-two-choices"
+	ConsistentHash     = "consistent-hash"
+)
+
+// NewBalancer returns the balancer with the name for the upstreams, round
+// robin when the name is empty. weights are the shares of the requests that
+// the upstreams get relative to each other, for the balancers that use them,
+// and 1 for each upstream when nil. They belong to the route rather than to
+// the upstreams, which may be shared with other routes. The consistent hash
+// balancer hashes the header or, when it is empty, the cookie with the given
+// name.
+func NewBalancer(name string, upstreams []*Upstream, weights []int, header, cookie string) (Balancer, error) {
+	weightOf := make(map[*Upstream]int, len(upstreams))
+	for i, u := range upstreams {
+		weightOf[u] = 1
+		if weights != nil {
+			weightOf[u] = weights[i]
+		}
+	}
+
+	switch name {
+	case "", RoundRobin:
+		return &roundRobin{}, nil
+	case WeightedRoundRobin:
+		return &weightedRoundRobin{weights: weightOf, current: make(map[*Upstream]int)}, nil
+	case LeastConnections:
+		return &leastConnections{}, nil
+	case RandomTwoChoices:
+		return &randomTwoChoices{}, nil
+	case ConsistentHash:
+		if header == "" && cookie == "" {
+			return nil, fmt.Errorf("the %s balancer needs a header or a cookie", name)
+		}
+		return newHashRing(upstreams, weightOf, header, cookie), nil
+	default:
+		return nil, fmt.Errorf("unknown balancer %q", name)
+	}
+}
+
+type roundRobin struct {
+	next atomic.Uint64
+}
+
+func (b *roundRobin) Next(r *http.Request, upstreams []*Upstream) *Upstream {
+	return upstreams[(b.next.Add(1)-1)%uint64(len(upstreams))]
+}
+
+// weightedRoundRobin spreads the turns of the upstreams with bigger weights
+// between the others instead of giving them in a row, like the smooth
+// weighted round robin of nginx.
+type weightedRoundRobin struct {
+	weights map[*Upstream]int
+	mu      sync.Mutex
+	current map[*Upstream]int
+}
+
+func (b *weightedRoundRobin) Next(r *http.Request, upstreams []*Upstream) *Upstream {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var best *Upstream
+	total := 0
+	for _, u := range upstreams {
+		b.current[u] += b.weights[u]
+		total += b.weights[u]
+		if best == nil || b.current[u] > b.current[best] {
+			best = u
+		}
+	}
+
+	b.current[best] -= total
+	return best
+}
+
+// leastConnections picks the upstream with the fewest active requests. The
+// ties are taken in turns.
+type leastConnections struct {
+	next atomic.Uint64
+}
+
+func (b *leastConnections) Next(r *http.Request, upstreams []*Upstream) *Upstream {
+	start := int(b.next.Add(1) % uint64(len(upstreams)))
+	var best *Upstream
+	for i := range upstreams {
+		u := upstreams[(start+i)%len(upstreams)]
+		if best == nil || u.Connections() < best.Connections() {
+			best = u
+		}
+	}
+
+	return best
+}
+
+// randomTwoChoices picks two random upstreams and takes the one with fewer
+// active requests, which avoids both the herd that least connections sends
+// to a new upstream and reading the counters of every upstream.
+type randomTwoChoices struct{}
+
+func (randomTwoChoices) Next(r *http.Request, upstreams []*Upstream) *Upstream {
+	if len(upstreams) == 1 {
+		return upstreams[0]
+	}
+
+	i := rand.Intn(len(upstreams))
+	j := rand.Intn(len(upstreams) - 1)
+	if j >= i {
+		j++
+	}
+
+	if upstreams[j].Connections() < upstreams[i].Connections() {
+		return upstreams[j]
+	}
+	return upstreams[i]
+}
+
+// hashRingReplicas is the number of points of an upstream with weight 1 on
+// the ring.
+const hashRingReplicas = 100
+
+// hashRing sends the requests with the same key to the same upstream, and
+// when an upstream is left out only its keys move to the others. Requests
+// without a key are taken in turns.
+type hashRing struct {
+	header string
+	cookie string
+	points []hashPoint
+	rr     roundRobin
+}
+
+type hashPoint struct {
+	hash     uint32
+	upstream *Upstream
+}
+
+func newHashRing(upstreams []*Upstream, weights map[*Upstream]int, header, cookie string) *hashRing {
+	ring := &hashRing{header: header, cookie: cookie}
+	for _, u := range upstreams {
+		for i := 0; i < hashRingReplicas*weights[u]; i++ {
+			ring.points = append(ring.points, hashPoint{hash(u.Address.String() + "#" + strconv.Itoa(i)), u})
+		}
+	}
+
+	sort.Slice(ring.points, func(i, j int) bool {
+		return ring.points[i].hash < ring.points[j].hash
+	})
+
+	return ring
+}
+
+func (b *hashRing) Next(r *http.Request, upstreams []*Upstream) *Upstream {
+	key := b.key(r)
+	if key == "" || len(b.points) == 0 {
+		return b.rr.Next(r, upstreams)
+	}
+
+	h := hash(key)
+	start := sort.Search(len(b.points), func(i int) bool {
+		return b.points[i].hash >= h
+	})
+
+	for i := range b.points {
+		p := b.points[(start+i)%len(b.points)]
+		for _, u := range upstreams {
+			if u == p.upstream {
+				return u
+			}
+		}
+	}
+
+	return b.rr.Next(r, upstreams)
+}
+
+func (b *hashRing) key(r *http.Request) string {
+	if b.header != "" {
+		return r.Header.Get(b.header)
+	}
+
+	if c, err := r.Cookie(b.cookie); err == nil {
+		return c.Value
+	}
+	return ""
+}
+
+// hash returns the position of s on the ring. The names of the points differ
+// only at the end, which MD5 spreads around the ring unlike the faster hashes,
+// as in ketama.
+func hash(s string) uint32 {
+	sum := md5.Sum([]byte(s))
+	return binary.LittleEndian.Uint32(sum[:4])
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func testUpstreams(n int) []*Upstream {
+	upstreams := make([]*Upstream, n)
+	for i := range upstreams {
+		upstreams[i] = &Upstream{Address: &url.URL{Scheme: "http", Host: fmt.Sprintf("u%d", i)}}
+	}
+
+	return upstreams
+}
+
+func testBalancer(t *testing.T, name string, upstreams []*Upstream, weights ...int) Balancer {
+	b, err := NewBalancer(name, upstreams, weights, "X-User", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return b
+}
+
+// picks returns the hosts of the upstreams picked for n requests.
+func picks(b Balancer, upstreams []*Upstream, n int) string {
+	hosts := make([]string, n)
+	r := httptest.NewRequest("GET", "/", nil)
+	for i := range hosts {
+		hosts[i] = b.Next(r, upstreams).Address.Host
+	}
+
+	return strings.Join(hosts, " ")
+}
+
+func TestBalancers(t *testing.T) {
+	values := []struct {
+		name    string
+		weights []int
+		picks   string
+	}{
+		{RoundRobin, []int{1, 1, 1}, "u0 u1 u2 u0 u1 u2"},
+		{RoundRobin, []int{5, 1}, "u0 u1 u0 u1"},
+		{WeightedRoundRobin, []int{1, 1}, "u0 u1 u0 u1"},
+		{WeightedRoundRobin, []int{3, 1}, "u0 u0 u1 u0 u0 u0 u1 u0"},
+		{WeightedRoundRobin, []int{5, 1, 1}, "u0 u0 u1 u0 u2 u0 u0 u0 u0 u1 u0 u2 u0 u0"},
+		{LeastConnections, []int{1, 1, 1}, "u1 u2 u0 u1 u2 u0"},
+	}
+
+	for _, v := range values {
+		upstreams := testUpstreams(len(v.weights))
+		if p := picks(testBalancer(t, v.name, upstreams, v.weights...), upstreams, strings.Count(v.picks, " ")+1); p != v.picks {
+			t.Fatalf("%s %v (expected) %s != %s (actual)", v.name, v.weights, v.picks, p)
+		}
+	}
+}
+
+func TestLeastConnections(t *testing.T) {
+	for _, name := range []string{LeastConnections, RandomTwoChoices} {
+		upstreams := testUpstreams(3)
+		upstreams[0].acquire()
+		upstreams[0].acquire()
+		upstreams[1].acquire()
+		b := testBalancer(t, name, upstreams)
+		r := httptest.NewRequest("GET", "/", nil)
+		for i := 0; i < 100; i++ {
+			u := b.Next(r, upstreams)
+			if u == upstreams[0] || (name == LeastConnections && u != upstreams[2]) {
+				t.Fatalf("%s (expected) not %s != %s (actual)", name, upstreams[0].Address.Host, u.Address.Host)
+			}
+		}
+
+		if u := b.Next(r, upstreams[:1]); u != upstreams[0] {
+			t.Fatalf("%s (expected) %s != %s (actual)", name, upstreams[0].Address.Host, u.Address.Host)
+		}
+	}
+}
+
+func TestConsistentHash(t *testing.T) {
+	upstreams := testUpstreams(4)
+	b := testBalancer(t, ConsistentHash, upstreams)
+	pick := func(user string, upstreams []*Upstream) *Upstream {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("X-User", user)
+		return b.Next(r, upstreams)
+	}
+
+	counts := make(map[*Upstream]int)
+	for i := 0; i < 1000; i++ {
+		user := fmt.Sprint("user", i)
+		u := pick(user, upstreams)
+		counts[u]++
+		if again := pick(user, upstreams); again != u {
+			t.Fatalf("%s (expected) %s != %s (actual)", user, u.Address.Host, again.Address.Host)
+		}
+
+		// Only the users of the left out upstream move.
+		if moved := pick(user, upstreams[1:]); u != upstreams[0] && moved != u {
+			t.Fatalf("%s without u0 (expected) %s != %s (actual)", user, u.Address.Host, moved.Address.Host)
+		}
+	}
+
+	for _, u := range upstreams {
+		if counts[u] < 100 {
+			t.Fatalf("%s has %d of 1000 users", u.Address.Host, counts[u])
+		}
+	}
+
+	cookie := testUpstreams(2)
+	b, err := NewBalancer(ConsistentHash, cookie, nil, "", "session")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	if p := picks(b, cookie, 4); p != "u0 u1 u0 u1" {
+		t.Fatalf("without a cookie (expected) u0 u1 u0 u1 != %s (actual)", p)
+	}
+
+	r.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+	u := b.Next(r, cookie)
+	for i := 0; i < 10; i++ {
+		if again := b.Next(r, cookie); again != u {
+			t.Fatalf("session abc (expected) %s != %s (actual)", u.Address.Host, again.Address.Host)
+		}
+	}
+}
+
+func TestBalancersConcurrent(t *testing.T) {
+	for _, name := range []string{RoundRobin, WeightedRoundRobin, LeastConnections, RandomTwoChoices, ConsistentHash} {
+		upstreams := testUpstreams(3)
+		b := testBalancer(t, name, upstreams, 1, 2, 3)
+		var wg sync.WaitGroup
+		for i := 0; i < 6; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				r := httptest.NewRequest("GET", "/", nil)
+				r.Header.Set("X-User", fmt.Sprint(i))
+				for j := 0; j < 1000; j++ {
+					u := b.Next(r, upstreams)
+					u.acquire()
+					u.release()
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		total := int64(0)
+		for _, u := range upstreams {
+			if u.Connections() != 0 {
+				t.Fatalf("%s %s has %d connections", name, u.Address.Host, u.Connections())
+			}
+			total += u.Requests()
+		}
+
+		if total != 6000 {
+			t.Fatalf("%s (expected) 6000 != %d (actual)", name, total)
+		}
+
+		if name == RoundRobin && upstreams[0].Requests() != upstreams[2].Requests() {
+			t.Fatalf("%s (expected) %d != %d (actual)", name, upstreams[0].Requests(), upstreams[2].Requests())
+		}
+
+		if name == WeightedRoundRobin && upstreams[2].Requests() != 3*upstreams[0].Requests() {
+			t.Fatalf("%s (expected) %d != %d (actual)", name, 3*upstreams[0].Requests(), upstreams[2].Requests())
+		}
+	}
+}
+
+func TestReverseProxyBalancing(t *testing.T) {
+	var servers []string
+	for i := 0; i < 3; i++ {
+		name := fmt.Sprint("backend", i)
+		backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, name)
+		}))
+		defer backend.Close()
+		servers = append(servers, backend.URL)
+	}
+
+	proxy := httptest.NewServer(&ReverseProxyHandler{routes: testRoutes(t, []RouteConfig{
+		{Path: "/rr", RemoteAddress: servers[0], Upstreams: []UpstreamConfig{{Address: servers[1]}, {Address: servers[2]}}},
+		{Path: "/weighted", Balancer: WeightedRoundRobin, Upstreams: []UpstreamConfig{{Address: servers[0], Weight: 2}, {Address: servers[1]}}},
+		{Path: "/sticky", Balancer: ConsistentHash, HashCookie: "session", Upstreams: []UpstreamConfig{{Address: servers[0]}, {Address: servers[1]}, {Address: servers[2]}}},
+	})})
+	defer proxy.Close()
+
+	get := func(path string, session string) string {
+		req, _ := http.NewRequest("GET", proxy.URL+path, nil)
+		if session != "" {
+			req.AddCookie(&http.Cookie{Name: "session", Value: session})
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	values := []struct {
+		path    string
+		session string
+		bodies  string
+	}{
+		{"/rr", "", "backend0 backend1 backend2 backend0"},
+		{"/weighted", "", "backend0 backend1 backend0 backend0 backend1 backend0"},
+	}
+
+	for _, v := range values {
+		bodies := make([]string, strings.Count(v.bodies, " ")+1)
+		for i := range bodies {
+			bodies[i] = get(v.path, v.session)
+		}
+
+		if b := strings.Join(bodies, " "); b != v.bodies {
+			t.Fatalf("%s (expected) %s != %s (actual)", v.path, v.bodies, b)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for i := 0; i < 30; i++ {
+		session := fmt.Sprint("session", i)
+		first := get("/sticky", session)
+		seen[first] = true
+		for j := 0; j < 3; j++ {
+			if body := get("/sticky", session); body != first {
+				t.Fatalf("%s (expected) %s != %s (actual)", session, first, body)
+			}
+		}
+	}
+
+	if len(seen) != 3 {
+		t.Fatalf("30 sessions went to %d backends", len(seen))
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HealthCheck probes the upstreams of a route with a GET request to Path on
+// their hosts every Interval. An upstream whose response does not come in
+// Timeout or has a status out of MinStatus-MaxStatus is skipped until a probe
+// succeeds again.
+type HealthCheck struct {
+	Path      string
+	Interval  time.Duration
+	Timeout   time.Duration
+	MinStatus int
+	MaxStatus int
+}
+
+// PassiveHealthCheck ejects an upstream for Cooldown after MaxFailures
+// requests in a row fail to connect or get a 5xx response.
+type PassiveHealthCheck struct {
+	MaxFailures int
+	Cooldown    time.Duration
+}
+
+// parseStatusRange parses a status like 200 or a range like 200-399.
+func parseStatusRange(s string) (low, high int, err error) {
+	first, last, found := strings.Cut(s, "-")
+	low, err = strconv.Atoi(strings.TrimSpace(first))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid status range %q", s)
+	}
+
+	high = low
+	if found {
+		high, err = strconv.Atoi(strings.TrimSpace(last))
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid status range %q", s)
+		}
+	}
+
+	if low < 100 || high > 599 || low > high {
+		return 0, 0, fmt.Errorf("invalid status range %q", s)
+	}
+
+	return low, high, nil
+}
+
+// Healthy reports whether the last probe of the upstream succeeded and it is
+// not ejected by the passive health check at the time now.
+func (u *Upstream) Healthy(now time.Time) bool {
+	return !u.down.Load() && now.UnixNano() >= u.ejectedUntil.Load()
+}
+
+// probe sends a health check request to the upstream and marks it up or down.
+func (u *Upstream) probe(ctx context.Context, client *http.Client, check *HealthCheck) {
+	target := *u.Address
+	target.Path, target.RawPath, target.RawQuery = check.Path, "", ""
+
+	probeCtx, cancel := context.WithTimeout(ctx, check.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(probeCtx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		u.setDown(true, err.Error())
+		return
+	}
+
+	resp, err := client.Do(req)
+	if ctx.Err() != nil {
+		// The health checks are stopped.
+		return
+	}
+
+	if err != nil {
+		u.setDown(true, err.Error())
+		return
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < check.MinStatus || resp.StatusCode > check.MaxStatus {
+		u.setDown(true, resp.Status)
+		return
+	}
+	u.setDown(false, resp.Status)
+}
+
+func (u *Upstream) setDown(down bool, reason string) {
+	if u.down.Swap(down) == down {
+		return
+	}
+
+	if down {
+		log.Printf("upstream %s is unhealthy: %s", u.Address, reason)
+	} else {
+		log.Printf("upstream %s is healthy again", u.Address)
+	}
+}
+
+// report counts the result of a request to the upstream for the passive
+// health check.
+func (u *Upstream) report(check *PassiveHealthCheck, failed bool, now time.Time) {
+	if !failed {
+		u.failures.Store(0)
+		return
+	}
+
+	if u.failures.Add(1) < int64(check.MaxFailures) {
+		return
+	}
+
+	u.failures.Store(0)
+	u.ejectedUntil.Store(now.Add(check.Cooldown).UnixNano())
+	log.Printf("upstream %s is ejected for %s after %d failures", u.Address, check.Cooldown, check.MaxFailures)
+}
+
+// healthyUpstreams returns the upstreams of the route that are healthy at the
+// time now.
+func (route *Route) healthyUpstreams(now time.Time) []*Upstream {
+	healthy := make([]*Upstream, 0, len(route.Upstreams))
+	for _, u := range route.Upstreams {
+		if u.Healthy(now) {
+			healthy = append(healthy, u)
+		}
+	}
+
+	return healthy
+}
+
+// retryAfter returns the seconds until an upstream of the route may be
+// healthy again: the end of the first cooldown, or the next probe for the
+// upstreams that failed the active health check.
+func (route *Route) retryAfter(now time.Time) int {
+	var wait time.Duration
+	for i, u := range route.Upstreams {
+		d := time.Duration(u.ejectedUntil.Load() - now.UnixNano())
+		if u.down.Load() && route.HealthCheck != nil && d < route.HealthCheck.Interval {
+			d = route.HealthCheck.Interval
+		}
+
+		if i == 0 || d < wait {
+			wait = d
+		}
+	}
+
+	seconds := int((wait + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
+
+// CheckHealth probes the upstreams of the routes with a health check until
+// ctx is done. The first probes are sent right away.
+func (r *Routes) CheckHealth(ctx context.Context) {
+	client := &http.Client{
+		// 3xx responses are checked like the others.
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	for _, route := range r.routes {
+		if route.HealthCheck != nil {
+			go checkRoute(ctx, client, route)
+		}
+	}
+}
+
+func checkRoute(ctx context.Context, client *http.Client, route *Route) {
+	ticker := time.NewTicker(route.HealthCheck.Interval)
+	defer ticker.Stop()
+
+	for {
+		var wg sync.WaitGroup
+		for _, u := range route.Upstreams {
+			wg.Add(1)
+			go func(u *Upstream) {
+				defer wg.Done()
+				u.probe(ctx, client, route.HealthCheck)
+			}(u)
+		}
+		wg.Wait()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestParseStatusRange(t *testing.T) {
+	values := []struct {
+		s         string
+		low, high int
+		valid     bool
+	}{
+		{"200", 200, 200, true},
+		{"200-399", 200, 399, true},
+		{" 200 - 204 ", 200, 204, true},
+		{"399-200", 0, 0, false},
+		{"200-", 0, 0, false},
+		{"ok", 0, 0, false},
+		{"99", 0, 0, false},
+		{"200-600", 0, 0, false},
+	}
+
+	for _, v := range values {
+		low, high, err := parseStatusRange(v.s)
+		if (err == nil) != v.valid || low != v.low || high != v.high {
+			t.Fatalf("%q (expected) %d-%d %v != %d-%d %v (actual)", v.s, v.low, v.high, v.valid, low, high, err)
+		}
+	}
+}
+
+func TestHealthCheckConfig(t *testing.T) {
+	conf := new(ReverseProxyConfig)
+	err := yaml.Unmarshal([]byte(`
+routes:
+  - path: "/"
+    remoteAddress: "http://localhost:8881"
+    healthCheck:
+      path: "/health"
+      interval: 5s
+      timeout: 500ms
+      status: "200-299"
+    passiveHealthCheck:
+      maxFailures: 5
+  - path: "/other"
+    remoteAddress: "http://localhost:8882"
+    healthCheck: {}
+`), conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	routes := testRoutes(t, conf.Routes)
+	values := []struct {
+		check   HealthCheck
+		passive *PassiveHealthCheck
+	}{
+		{HealthCheck{"/health", 5 * time.Second, 500 * time.Millisecond, 200, 299}, &PassiveHealthCheck{5, 30 * time.Second}},
+		{HealthCheck{"/", 10 * time.Second, 2 * time.Second, 200, 399}, nil},
+	}
+
+	for i, v := range values {
+		route := routes.routes[i]
+		if *route.HealthCheck != v.check {
+			t.Fatalf("route %d (expected) %+v != %+v (actual)", i, v.check, *route.HealthCheck)
+		}
+
+		if (route.PassiveHealthCheck == nil) != (v.passive == nil) || (v.passive != nil && *route.PassiveHealthCheck != *v.passive) {
+			t.Fatalf("route %d (expected) %+v != %+v (actual)", i, v.passive, route.PassiveHealthCheck)
+		}
+	}
+
+	invalid := []RouteConfig{
+		{Path: "/", RemoteAddress: "http://a", HealthCheck: &HealthCheckConfig{Status: "ok"}},
+		{Path: "/", RemoteAddress: "http://a", HealthCheck: &HealthCheckConfig{Interval: -time.Second}},
+		{Path: "/", RemoteAddress: "http://a", PassiveHealthCheck: &PassiveHealthCheckConfig{MaxFailures: -1}},
+	}
+
+	for _, v := range invalid {
+		if _, err := loadRoutes(&ReverseProxyConfig{Routes: []RouteConfig{v}}); err == nil {
+			t.Fatalf("%+v without error", v)
+		}
+	}
+}
+
+// testGet sends a GET request to the URL and returns the status, the
+// Retry-After header and the body of the response.
+func testGet(t *testing.T, url string) (int, string, string) {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, resp.Header.Get("Retry-After"), string(body)
+}
+
+func TestPassiveHealthCheck(t *testing.T) {
+	var failing atomic.Bool
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		io.WriteString(w, "backend")
+	}))
+	defer backend.Close()
+
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+
+	proxy := httptest.NewServer(&ReverseProxyHandler{routes: testRoutes(t, []RouteConfig{
+		{
+			Path:               "/",
+			Upstreams:          []UpstreamConfig{{Address: dead.URL}, {Address: backend.URL}},
+			PassiveHealthCheck: &PassiveHealthCheckConfig{MaxFailures: 2, Cooldown: 200 * time.Millisecond},
+		},
+	})})
+	defer proxy.Close()
+
+	values := []struct {
+		status     int
+		retryAfter string
+		body       string
+	}{
+		// The dead upstream is ejected after the second connection error.
+		{http.StatusBadGateway, "", "Bad Gateway\n"},
+		{http.StatusOK, "", "backend"},
+		{http.StatusBadGateway, "", "Bad Gateway\n"},
+		{http.StatusOK, "", "backend"},
+		{http.StatusOK, "", "backend"},
+		{http.StatusOK, "", "backend"},
+	}
+
+	check := func(values []struct {
+		status     int
+		retryAfter string
+		body       string
+	}) {
+		for i, v := range values {
+			status, retryAfter, body := testGet(t, proxy.URL)
+			if status != v.status || retryAfter != v.retryAfter || body != v.body {
+				t.Fatalf("request %d (expected) %d %q %q != %d %q %q (actual)", i, v.status, v.retryAfter, v.body, status, retryAfter, body)
+			}
+		}
+	}
+	check(values)
+
+	// 5xx responses eject the other upstream too.
+	failing.Store(true)
+	values = values[:3]
+	values[0].status, values[0].body = http.StatusInternalServerError, "backend"
+	values[1].status, values[1].body = http.StatusInternalServerError, "backend"
+	values[2].status, values[2].retryAfter, values[2].body = http.StatusServiceUnavailable, "1", "Service Unavailable\n"
+	check(values)
+
+	// Both come back after the cooldown.
+	time.Sleep(250 * time.Millisecond)
+	failing.Store(false)
+	if status, _, _ := testGet(t, proxy.URL); status != http.StatusBadGateway {
+		t.Fatalf("(expected) %d != %d (actual)", http.StatusBadGateway, status)
+	}
+	if status, _, _ := testGet(t, proxy.URL); status != http.StatusOK {
+		t.Fatalf("(expected) %d != %d (actual)", http.StatusOK, status)
+	}
+}
+
+func TestPassiveHealthCheckCancelled(t *testing.T) {
+	started := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			close(started)
+			<-r.Context().Done()
+			return
+		}
+		io.WriteString(w, "backend")
+	}))
+	defer backend.Close()
+
+	proxy := &ReverseProxyHandler{routes: testRoutes(t, []RouteConfig{
+		{
+			Path:               "/",
+			Upstreams:          []UpstreamConfig{{Address: backend.URL}},
+			PassiveHealthCheck: &PassiveHealthCheckConfig{MaxFailures: 1, Cooldown: time.Minute},
+		},
+	})}
+
+	// The client hangs up while the upstream is still answering.
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+	proxy.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/slow", nil).WithContext(ctx))
+
+	w := httptest.NewRecorder()
+	proxy.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "backend" {
+		t.Fatalf("(expected) 200 backend != %d %s (actual)", w.Code, w.Body.String())
+	}
+}
+
+func TestActiveHealthCheck(t *testing.T) {
+	var healthy [2]atomic.Bool
+	var backends [2]*httptest.Server
+	for i := range backends {
+		i := i
+		healthy[i].Store(true)
+		backends[i] = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/health" {
+				if !healthy[i].Load() {
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+				return
+			}
+			io.WriteString(w, "backend"+string(rune('0'+i)))
+		}))
+		defer backends[i].Close()
+	}
+
+	routes := testRoutes(t, []RouteConfig{
+		{
+			Path:        "/",
+			Upstreams:   []UpstreamConfig{{Address: backends[0].URL + "/app"}, {Address: backends[1].URL}},
+			HealthCheck: &HealthCheckConfig{Path: "/health", Interval: 10 * time.Millisecond, Timeout: time.Second, Status: "200"},
+		},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	routes.CheckHealth(ctx)
+
+	proxy := httptest.NewServer(&ReverseProxyHandler{routes: routes})
+	defer proxy.Close()
+
+	upstreams := routes.routes[0].Upstreams
+	wait := func(want ...bool) {
+		deadline := time.Now().Add(2 * time.Second)
+		for i := 0; i < len(upstreams); {
+			if upstreams[i].Healthy(time.Now()) == want[i] {
+				i++
+				continue
+			}
+
+			if time.Now().After(deadline) {
+				t.Fatalf("upstream %d (expected) healthy %v", i, want[i])
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	healthy[0].Store(false)
+	wait(false, true)
+	for i := 0; i < 4; i++ {
+		if _, _, body := testGet(t, proxy.URL); body != "backend1" {
+			t.Fatalf("(expected) backend1 != %s (actual)", body)
+		}
+	}
+
+	healthy[1].Store(false)
+	wait(false, false)
+	if status, retryAfter, _ := testGet(t, proxy.URL); status != http.StatusServiceUnavailable || retryAfter != "1" {
+		t.Fatalf("(expected) 503 1 != %d %s (actual)", status, retryAfter)
+	}
+
+	healthy[0].Store(true)
+	healthy[1].Store(true)
+	wait(true, true)
+	bodies := []string{}
+	for i := 0; i < 2; i++ {
+		_, _, body := testGet(t, proxy.URL)
+		bodies = append(bodies, body)
+	}
+
+	if b := strings.Join(bodies, " "); b != "backend0 backend1" && b != "backend1 backend0" {
+		t.Fatalf("(expected) both backends != %s (actual)", b)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Now()
+	upstreams := testUpstreams(2)
+	route := &Route{Upstreams: upstreams, HealthCheck: &HealthCheck{Interval: 10 * time.Second}}
+	upstreams[0].ejectedUntil.Store(now.Add(2500 * time.Millisecond).UnixNano())
+	upstreams[1].down.Store(true)
+	if s := route.retryAfter(now); s != 3 {
+		t.Fatalf("(expected) 3 != %d (actual)", s)
+	}
+
+	upstreams[0].ejectedUntil.Store(now.Add(time.Minute).UnixNano())
+	if s := route.retryAfter(now); s != 10 {
+		t.Fatalf("(expected) 10 != %d (actual)", s)
+	}
+}
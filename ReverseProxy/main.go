@@ -1,31 +1,55 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"log"
 	"mime"
 	"net"
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v2"
 )
 
-type Routes struct {
-	Map *map[string]*url.URL
+type UpstreamConfig struct {
+	Address string `yaml:"address"`
+	Weight  int    `yaml:"weight"`
 }
 
-func (r *Routes) GetRemoteAddress(path string) (*url.URL, bool) {
-	v, ok := (*r.Map)[path]
+type HealthCheckConfig struct {
+	Path     string        `yaml:"path"`
+	Interval time.Duration `yaml:"interval"`
+	Timeout  time.Duration `yaml:"timeout"`
+	// Status is the expected status or range of statuses, like 200-399.
+	Status string `yaml:"status"`
+}
 
-	return v, ok
+type PassiveHealthCheckConfig struct {
+	MaxFailures int           `yaml:"maxFailures"`
+	Cooldown    time.Duration `yaml:"cooldown"`
 }
 
 type RouteConfig struct {
-	Path          string `yaml:"path"`
-	RemoteAddress string `yaml:"remoteAddress"`
+	Path          string   `yaml:"path"`
+	Host          string   `yaml:"host"`
+	Methods       []string `yaml:"methods"`
+	StripPrefix   *bool    `yaml:"stripPrefix"`
+	RewritePrefix string   `yaml:"rewritePrefix"`
+	// RemoteAddress is the address of a route with a single upstream.
+	RemoteAddress string           `yaml:"remoteAddress"`
+	Upstreams     []UpstreamConfig `yaml:"upstreams"`
+	Balancer      string           `yaml:"balancer"`
+	HashHeader    string           `yaml:"hashHeader"`
+	HashCookie    string           `yaml:"hashCookie"`
+
+	HealthCheck        *HealthCheckConfig        `yaml:"healthCheck"`
+	PassiveHealthCheck *PassiveHealthCheckConfig `yaml:"passiveHealthCheck"`
 }
 
 type ReverseProxyConfig struct {
@@ -59,22 +83,43 @@ func (proxy *ReverseProxyHandler) CopyResponse(w http.ResponseWriter, r io.Reade
 }
 
 func (proxy *ReverseProxyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	remoteAddress, ok := proxy.routes.GetRemoteAddress(r.URL.Path)
-	if !ok {
+	match, allowed := proxy.routes.Match(r)
+	if match == nil {
+		if len(allowed) > 0 {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
 
-	r.Host = remoteAddress.Host
-	r.URL.Host = remoteAddress.Host
-	r.URL.Scheme = remoteAddress.Scheme
-	r.URL.Path = remoteAddress.Path
+	route := match.Route
+	upstreams := route.healthyUpstreams(time.Now())
+	if len(upstreams) == 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(route.retryAfter(time.Now())))
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+
+	upstream := route.Balancer.Next(r, upstreams)
+	upstream.acquire()
+	defer upstream.release()
+
+	r.URL = match.Target(upstream, r.URL)
+	r.Host = r.URL.Host
 	r.RequestURI = ""
 
 	remoteResp, err := http.DefaultClient.Do(r)
+	// A request that the client cancelled says nothing about the upstream.
+	if route.PassiveHealthCheck != nil && r.Context().Err() == nil {
+		upstream.report(route.PassiveHealthCheck, err != nil || remoteResp.StatusCode >= 500, time.Now())
+	}
+
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		fmt.Fprint(w, err)
+		log.Printf("upstream %s: %v", upstream.Address, err)
+		http.Error(w, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
 		return
 	}
 
@@ -127,23 +172,136 @@ func main() {
 		panic(err)
 	}
 
-	err = http.ListenAndServe(conf.Host, &ReverseProxyHandler{routes: loadRoutes(conf)})
+	routes, err := loadRoutes(conf)
+	if err != nil {
+		panic(err)
+	}
+	routes.CheckHealth(context.Background())
+
+	err = http.ListenAndServe(conf.Host, &ReverseProxyHandler{routes: routes})
 	if err != nil {
 		panic(err)
 	}
 }
 
-func loadRoutes(conf *ReverseProxyConfig) *Routes {
-	routes := make(map[string]*url.URL, len(conf.Routes))
-	for _, v := range conf.Routes {
-		remoteAddress, err := url.Parse(v.RemoteAddress)
+// loadRoutes returns the routes of the config. The routes with the same
+// upstream address share one Upstream, so that its counters and health state
+// cover all the requests sent to it.
+func loadRoutes(conf *ReverseProxyConfig) (*Routes, error) {
+	routes := make([]*Route, len(conf.Routes))
+	shared := make(map[string]*Upstream)
+	for i, v := range conf.Routes {
+		configs := v.Upstreams
+		if v.RemoteAddress != "" {
+			configs = append([]UpstreamConfig{{Address: v.RemoteAddress}}, configs...)
+		}
+
+		// An upstream listed twice gets the sum of its weights.
+		upstreams := make([]*Upstream, 0, len(configs))
+		weights := make([]int, 0, len(configs))
+		index := make(map[*Upstream]int)
+		for _, u := range configs {
+			address, err := url.Parse(u.Address)
+			if err != nil {
+				return nil, err
+			}
+
+			weight := u.Weight
+			if weight == 0 {
+				weight = 1
+			}
+			if weight < 0 {
+				return nil, fmt.Errorf("route %d: the weight of %s is negative", i, address)
+			}
+
+			upstream := shared[address.String()]
+			if upstream == nil {
+				upstream = &Upstream{Address: address}
+				shared[address.String()] = upstream
+			}
+
+			if j, ok := index[upstream]; ok {
+				weights[j] += weight
+				continue
+			}
+
+			index[upstream] = len(upstreams)
+			upstreams = append(upstreams, upstream)
+			weights = append(weights, weight)
+		}
+
+		balancer, err := NewBalancer(v.Balancer, upstreams, weights, v.HashHeader, v.HashCookie)
+		if err != nil {
+			return nil, fmt.Errorf("route %d: %v", i, err)
+		}
+
+		healthCheck, err := loadHealthCheck(v.HealthCheck)
+		if err != nil {
+			return nil, fmt.Errorf("route %d: %v", i, err)
+		}
+
+		routes[i] = &Route{
+			Host:               v.Host,
+			Methods:            v.Methods,
+			Path:               v.Path,
+			StripPrefix:        v.StripPrefix == nil || *v.StripPrefix,
+			RewritePrefix:      v.RewritePrefix,
+			Upstreams:          upstreams,
+			Balancer:           balancer,
+			HealthCheck:        healthCheck,
+			PassiveHealthCheck: loadPassiveHealthCheck(v.PassiveHealthCheck),
+		}
+	}
+
+	return NewRoutes(routes)
+}
+
+// loadHealthCheck returns the health check of the config, which probes / every
+// 10 seconds with a timeout of 2 seconds and expects a 2xx or 3xx status
+// unless it is set.
+func loadHealthCheck(conf *HealthCheckConfig) (*HealthCheck, error) {
+	if conf == nil {
+		return nil, nil
+	}
+
+	check := &HealthCheck{Path: conf.Path, Interval: conf.Interval, Timeout: conf.Timeout, MinStatus: 200, MaxStatus: 399}
+	if check.Path == "" {
+		check.Path = "/"
+	}
+	if check.Interval == 0 {
+		check.Interval = 10 * time.Second
+	}
+	if check.Timeout == 0 {
+		check.Timeout = 2 * time.Second
+	}
+
+	if conf.Status != "" {
+		var err error
+		check.MinStatus, check.MaxStatus, err = parseStatusRange(conf.Status)
 		if err != nil {
-			panic(err)
+			return nil, err
 		}
+	}
 
-		routes[v.Path] = remoteAddress
+	return check, nil
+}
+
+// loadPassiveHealthCheck returns the passive health check of the config,
+// which ejects upstreams for 30 seconds after 3 failures unless it is set.
+func loadPassiveHealthCheck(conf *PassiveHealthCheckConfig) *PassiveHealthCheck {
+	if conf == nil {
+		return nil
+	}
+
+	check := &PassiveHealthCheck{MaxFailures: conf.MaxFailures, Cooldown: conf.Cooldown}
+	if check.MaxFailures == 0 {
+		check.MaxFailures = 3
+	}
+	if check.Cooldown == 0 {
+		check.Cooldown = 30 * time.Second
 	}
-	return &Routes{Map: &routes}
+
+	return check
 }
 
 func loadConfig() (*ReverseProxyConfig, error) {
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Route sends the requests whose host, method and path match it to one of its
+// upstreams, picked by the balancer.
+//
+// The path is a pattern that matches the request path and the paths below
+// it, whole segments only: /api matches /api and /api/users but not /apis.
+// A {name} segment matches any one segment, which can be used in
+// RewritePrefix and in the paths of the upstreams as well.
+type Route struct {
+	// Host is the host the Host header must have, any host when empty. A
+	// leading *. matches the subdomains.
+	Host    string
+	Methods []string
+	Path    string
+	// StripPrefix replaces the matched part of the path with RewritePrefix
+	// before it is appended to the path of the upstream. Otherwise the whole
+	// path is appended.
+	StripPrefix   bool
+	RewritePrefix string
+	Upstreams     []*Upstream
+	// Balancer is round robin when nil.
+	Balancer Balancer
+	// HealthCheck and PassiveHealthCheck skip the unhealthy upstreams when
+	// they are set.
+	HealthCheck        *HealthCheck
+	PassiveHealthCheck *PassiveHealthCheck
+
+	// index is the position of the route in the config.
+	index int
+}
+
+type Routes struct {
+	routes []*Route
+}
+
+// RouteMatch is a route that matches a request.
+type RouteMatch struct {
+	Route  *Route
+	Params map[string]string
+	// Prefix is the part of the escaped request path that matched the route,
+	// Rest is the part after it.
+	Prefix string
+	Rest   string
+}
+
+func NewRoutes(routes []*Route) (*Routes, error) {
+	for i, route := range routes {
+		if !strings.HasPrefix(route.Path, "/") {
+			return nil, fmt.Errorf("route %d: the path %q does not start with /", i, route.Path)
+		}
+
+		params, err := patternParams(route.Path)
+		if err != nil {
+			return nil, fmt.Errorf("route %d: %v", i, err)
+		}
+
+		if len(route.Upstreams) == 0 {
+			return nil, fmt.Errorf("route %d: no upstreams", i)
+		}
+
+		patterns := []string{route.RewritePrefix}
+		for _, u := range route.Upstreams {
+			patterns = append(patterns, u.Address.Path)
+		}
+
+		if c := route.HealthCheck; c != nil && (c.Interval <= 0 || c.Timeout <= 0 || c.MinStatus > c.MaxStatus) {
+			return nil, fmt.Errorf("route %d: invalid health check %+v", i, *c)
+		}
+
+		if c := route.PassiveHealthCheck; c != nil && (c.MaxFailures < 1 || c.Cooldown <= 0) {
+			return nil, fmt.Errorf("route %d: invalid passive health check %+v", i, *c)
+		}
+
+		for _, s := range patterns {
+			used, err := patternParams(s)
+			if err != nil {
+				return nil, fmt.Errorf("route %d: %v", i, err)
+			}
+
+			for name := range used {
+				if !params[name] {
+					return nil, fmt.Errorf("route %d: {%s} is not in the path %q", i, name, route.Path)
+				}
+			}
+		}
+
+		for j, m := range route.Methods {
+			route.Methods[j] = strings.ToUpper(m)
+		}
+		route.Host = strings.ToLower(route.Host)
+		if route.Balancer == nil {
+			route.Balancer = &roundRobin{}
+		}
+		route.index = i
+	}
+
+	return &Routes{routes: routes}, nil
+}
+
+// patternParams returns the names of the {name} segments of a pattern.
+func patternParams(pattern string) (map[string]bool, error) {
+	params := make(map[string]bool)
+	for _, segment := range strings.Split(pattern, "/") {
+		if !strings.ContainsAny(segment, "{}") {
+			continue
+		}
+
+		name := strings.TrimSuffix(strings.TrimPrefix(segment, "{"), "}")
+		if len(name)+2 != len(segment) || name == "" || strings.ContainsAny(name, "{}") {
+			return nil, fmt.Errorf("invalid segment %q in %q", segment, pattern)
+		}
+
+		if params[name] {
+			return nil, fmt.Errorf("{%s} is used twice in %q", name, pattern)
+		}
+		params[name] = true
+	}
+
+	return params, nil
+}
+
+// Match returns the route of the request. Routes for the host of the request
+// come before the routes for any host, then the route that matches the
+// longest part of the path wins, then the one with fewer parameters, then
+// the first one. When no route matches, allowed are the methods of the
+// routes that only do not match the method.
+func (r *Routes) Match(req *http.Request) (match *RouteMatch, allowed []string) {
+	host := requestHost(req)
+	path := req.URL.EscapedPath()
+	type candidate struct {
+		match    *RouteMatch
+		hostRank int
+	}
+
+	candidates := []candidate{}
+	for _, route := range r.routes {
+		hostRank := matchHost(route.Host, host)
+		if hostRank < 0 {
+			continue
+		}
+
+		m, ok := matchPath(route.Path, path)
+		if !ok {
+			continue
+		}
+
+		if !route.allows(req.Method) {
+			allowed = append(allowed, route.Methods...)
+			continue
+		}
+
+		m.Route = route
+		candidates = append(candidates, candidate{m, hostRank})
+	}
+
+	if len(candidates) == 0 {
+		return nil, uniqueSorted(allowed)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		switch {
+		case a.hostRank != b.hostRank:
+			return a.hostRank > b.hostRank
+		case len(a.match.Prefix) != len(b.match.Prefix):
+			return len(a.match.Prefix) > len(b.match.Prefix)
+		case len(a.match.Params) != len(b.match.Params):
+			return len(a.match.Params) < len(b.match.Params)
+		default:
+			return a.match.Route.index < b.match.Route.index
+		}
+	})
+
+	return candidates[0].match, nil
+}
+
+// allows reports whether the route takes requests with the method. Routes
+// that take GET take HEAD too.
+func (route *Route) allows(method string) bool {
+	if len(route.Methods) == 0 {
+		return true
+	}
+
+	for _, m := range route.Methods {
+		if m == method || (m == http.MethodGet && method == http.MethodHead) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// requestHost returns the lowercase host of the request without the port.
+func requestHost(req *http.Request) string {
+	host := req.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	return strings.ToLower(host)
+}
+
+// matchHost returns -1 when the host does not match the route host, 0 for
+// routes for any host, 1 for subdomain patterns and 2 for exact hosts.
+func matchHost(pattern string, host string) int {
+	switch {
+	case pattern == "":
+		return 0
+	case pattern == host:
+		return 2
+	case strings.HasPrefix(pattern, "*.") && strings.HasSuffix(host, pattern[1:]):
+		return 1
+	default:
+		return -1
+	}
+}
+
+// matchPath matches the escaped request path against the pattern of a
+// route.
+func matchPath(pattern string, path string) (*RouteMatch, bool) {
+	m := &RouteMatch{Params: make(map[string]string)}
+	i := 0
+	for len(pattern) > 0 {
+		if pattern[0] != '{' {
+			if i >= len(path) || path[i] != pattern[0] {
+				return nil, false
+			}
+
+			i++
+			pattern = pattern[1:]
+			continue
+		}
+
+		end := strings.IndexByte(pattern, '}')
+		segment := strings.IndexByte(path[i:], '/')
+		if segment < 0 {
+			segment = len(path) - i
+		}
+
+		if segment == 0 {
+			return nil, false
+		}
+
+		value, err := url.PathUnescape(path[i : i+segment])
+		if err != nil {
+			return nil, false
+		}
+
+		m.Params[pattern[1:end]] = value
+		i += segment
+		pattern = pattern[end+1:]
+	}
+
+	// Only whole segments match.
+	if i < len(path) && path[i] != '/' && path[i-1] != '/' {
+		return nil, false
+	}
+
+	m.Prefix, m.Rest = path[:i], path[i:]
+	return m, true
+}
+
+// Target returns the URL that the request to u is sent to on the upstream:
+// the path of the upstream, followed by the rewritten or the whole request
+// path, and the queries of both.
+func (m *RouteMatch) Target(upstream *Upstream, u *url.URL) *url.URL {
+	remote := upstream.Address
+	rest := u.EscapedPath()
+	if m.Route.StripPrefix {
+		rest = m.expand(m.Route.RewritePrefix) + m.Rest
+	}
+
+	rawPath := joinPaths(m.expand(remote.EscapedPath()), rest)
+	target := *remote
+	target.Path, _ = url.PathUnescape(rawPath)
+	target.RawPath = rawPath
+	target.RawQuery = remote.RawQuery
+	switch {
+	case remote.RawQuery == "":
+		target.RawQuery = u.RawQuery
+	case u.RawQuery != "":
+		target.RawQuery = remote.RawQuery + "&" + u.RawQuery
+	}
+
+	return &target
+}
+
+// expand replaces the {name} segments of an escaped path with the
+// parameters. The braces may be escaped too, as URL.EscapedPath escapes them.
+func (m *RouteMatch) expand(path string) string {
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		if unescaped, err := url.PathUnescape(s); err == nil && strings.HasPrefix(unescaped, "{") && strings.HasSuffix(unescaped, "}") {
+			segments[i] = url.PathEscape(m.Params[unescaped[1:len(unescaped)-1]])
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// joinPaths joins two escaped paths with a single slash between them.
+func joinPaths(a, b string) string {
+	switch {
+	case b == "":
+		if a == "" {
+			return "/"
+		}
+		return a
+	case strings.HasSuffix(a, "/") && strings.HasPrefix(b, "/"):
+		return a + b[1:]
+	case !strings.HasSuffix(a, "/") && !strings.HasPrefix(b, "/"):
+		return a + "/" + b
+	default:
+		return a + b
+	}
+}
+
+func uniqueSorted(list []string) []string {
+	if len(list) == 0 {
+		return nil
+	}
+
+	sort.Strings(list)
+	unique := list[:1]
+	for _, s := range list[1:] {
+		if s != unique[len(unique)-1] {
+			unique = append(unique, s)
+		}
+	}
+
+	return unique
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func testRoutes(t *testing.T, configs []RouteConfig) *Routes {
+	routes, err := loadRoutes(&ReverseProxyConfig{Routes: configs})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return routes
+}
+
+func TestRoutesMatch(t *testing.T) {
+	no := false
+	routes := testRoutes(t, []RouteConfig{
+		{Path: "/", RemoteAddress: "http://root"},
+		{Path: "/stream", RemoteAddress: "http://stream/stream"},
+		{Path: "/api/", RemoteAddress: "http://api/v2?key=1"},
+		{Path: "/api/admin", Methods: []string{"post", "DELETE"}, RemoteAddress: "http://admin"},
+		{Path: "/api/old/", RewritePrefix: "/legacy/", RemoteAddress: "http://api"},
+		{Path: "/keep", StripPrefix: &no, RemoteAddress: "http://keep/base"},
+		{Path: "/users/{id}", RemoteAddress: "http://users/accounts/{id}"},
+		{Path: "/users/{id}/posts/{post}", RewritePrefix: "/p/{post}", RemoteAddress: "http://posts/{id}"},
+		{Path: "/users/me", RemoteAddress: "http://me"},
+		{Path: "/", Host: "example.com", RemoteAddress: "http://example"},
+		{Path: "/", Host: "*.example.com", RemoteAddress: "http://sub"},
+	})
+
+	values := []struct {
+		method string
+		host   string
+		path   string
+		target string
+	}{
+		{"GET", "localhost:8080", "/", "http://root/"},
+		{"GET", "localhost", "/a/b?c=d", "http://root/a/b?c=d"},
+		{"GET", "localhost", "/stream", "http://stream/stream"},
+		{"GET", "localhost", "/stream/1?x", "http://stream/stream/1?x"},
+		{"GET", "localhost", "/streams", "http://root/streams"},
+		{"GET", "localhost", "/api", "http://root/api"},
+		{"GET", "localhost", "/api/users/42?a=b", "http://api/v2/users/42?key=1&a=b"},
+		{"POST", "localhost", "/api/admin/x", "http://admin/x"},
+		{"HEAD", "localhost", "/api/admin/x", "http://api/v2/admin/x?key=1"},
+		{"GET", "localhost", "/api/old/a%2Fb/c", "http://api/legacy/a%2Fb/c"},
+		{"GET", "localhost", "/keep/x", "http://keep/base/keep/x"},
+		{"GET", "localhost", "/users/42", "http://users/accounts/42"},
+		{"GET", "localhost", "/users/a%20b/x", "http://users/accounts/a%20b/x"},
+		{"GET", "localhost", "/users/me", "http://me/"},
+		{"GET", "localhost", "/users/me/posts/7/comments", "http://posts/me/p/7/comments"},
+		{"GET", "localhost", "/users/", "http://root/users/"},
+		{"GET", "EXAMPLE.com:80", "/x", "http://example/x"},
+		{"GET", "a.example.com", "/stream", "http://sub/stream"},
+		{"GET", "example.org", "/stream", "http://stream/stream"},
+	}
+
+	for _, v := range values {
+		r := httptest.NewRequest(v.method, "http://"+v.host+v.path, nil)
+		m, allowed := routes.Match(r)
+		if m == nil {
+			t.Fatalf("%s %s%s did not match, allowed %v", v.method, v.host, v.path, allowed)
+		}
+
+		if target := m.Target(m.Route.Upstreams[0], r.URL).String(); target != v.target {
+			t.Fatalf("%s %s%s (expected) %s != %s (actual)", v.method, v.host, v.path, v.target, target)
+		}
+	}
+}
+
+func TestRoutesNoMatch(t *testing.T) {
+	routes := testRoutes(t, []RouteConfig{
+		{Path: "/api/", Methods: []string{"GET"}, RemoteAddress: "http://api"},
+		{Path: "/api/", Methods: []string{"PUT", "GET"}, Host: "a.com", RemoteAddress: "http://api"},
+		{Path: "/b", Host: "b.com", RemoteAddress: "http://b"},
+	})
+
+	values := []struct {
+		method  string
+		url     string
+		allowed string
+	}{
+		{"GET", "http://localhost/", ""},
+		{"GET", "http://localhost/b", ""},
+		{"GET", "http://localhost/api", ""},
+		{"POST", "http://localhost/api/x", "GET"},
+		{"POST", "http://a.com/api/x", "GET, PUT"},
+	}
+
+	for _, v := range values {
+		m, allowed := routes.Match(httptest.NewRequest(v.method, v.url, nil))
+		if m != nil || strings.Join(allowed, ", ") != v.allowed {
+			t.Fatalf("%s %s (expected) %q != %v %q (actual)", v.method, v.url, v.allowed, m, allowed)
+		}
+	}
+
+	invalid := []RouteConfig{
+		{Path: "api", RemoteAddress: "http://api"},
+		{Path: "/users/{id", RemoteAddress: "http://api"},
+		{Path: "/users/{}", RemoteAddress: "http://api"},
+		{Path: "/{a}/{a}", RemoteAddress: "http://api"},
+		{Path: "/users", RemoteAddress: "http://api/{id}"},
+		{Path: "/users/{id}", RewritePrefix: "/{name}", RemoteAddress: "http://api"},
+		{Path: "/users"},
+		{Path: "/users", Upstreams: []UpstreamConfig{{Address: "http://a", Weight: -1}}},
+		{Path: "/users", RemoteAddress: "http://api", Balancer: "fastest"},
+		{Path: "/users", RemoteAddress: "http://api", Balancer: ConsistentHash},
+	}
+
+	for _, v := range invalid {
+		if _, err := loadRoutes(&ReverseProxyConfig{Routes: []RouteConfig{v}}); err == nil {
+			t.Fatalf("%+v without error", v)
+		}
+	}
+}
+
+func TestLoadRoutesSharedUpstreams(t *testing.T) {
+	routes := testRoutes(t, []RouteConfig{
+		{Path: "/shop/", Balancer: WeightedRoundRobin, Upstreams: []UpstreamConfig{{Address: "http://a:8881", Weight: 3}, {Address: "http://a:8882"}}},
+		{Path: "/cart/", Balancer: WeightedRoundRobin, Upstreams: []UpstreamConfig{{Address: "http://a:8882"}, {Address: "http://a:8881"}, {Address: "http://a:8882"}}},
+	}).routes
+
+	shop, cart := routes[0].Upstreams, routes[1].Upstreams
+	if len(cart) != 2 || shop[0] != cart[1] || shop[1] != cart[0] {
+		t.Fatalf("upstreams of /cart/ (expected) %v %v != %v (actual)", shop[1], shop[0], cart)
+	}
+
+	// The weights stay with the routes, and one listed twice is summed.
+	if p := picks(routes[0].Balancer, shop, 4); p != "a:8881 a:8881 a:8882 a:8881" {
+		t.Fatalf("/shop/ picks (expected) a:8881 a:8881 a:8882 a:8881 != %s (actual)", p)
+	}
+
+	if p := picks(routes[1].Balancer, cart, 3); p != "a:8882 a:8881 a:8882" {
+		t.Fatalf("/cart/ picks (expected) a:8882 a:8881 a:8882 != %s (actual)", p)
+	}
+}
+
+func TestReverseProxyHandler(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Method+" "+r.URL.RequestURI())
+	}))
+	defer backend.Close()
+
+	proxy := httptest.NewServer(&ReverseProxyHandler{routes: testRoutes(t, []RouteConfig{
+		{Path: "/api/", Methods: []string{"GET"}, RemoteAddress: backend.URL + "/v1"},
+	})})
+	defer proxy.Close()
+
+	values := []struct {
+		method string
+		path   string
+		status int
+		body   string
+	}{
+		{"GET", "/api/users/42?a=b&c", http.StatusOK, "GET /v1/users/42?a=b&c"},
+		{"POST", "/api/users", http.StatusMethodNotAllowed, ""},
+		{"GET", "/other", http.StatusNotFound, ""},
+	}
+
+	for _, v := range values {
+		req, _ := http.NewRequest(v.method, proxy.URL+v.path, nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != v.status || string(body) != v.body {
+			t.Fatalf("%s %s (expected) %d %q != %d %q (actual)", v.method, v.path, v.status, v.body, resp.StatusCode, body)
+		}
+	}
+}
+
+func TestJoinPaths(t *testing.T) {
+	values := []struct {
+		a, b, path string
+	}{
+		{"", "", "/"},
+		{"", "a", "/a"},
+		{"/v1", "", "/v1"},
+		{"/v1/", "/a", "/v1/a"},
+		{"/v1", "/a", "/v1/a"},
+		{"/v1/", "a", "/v1/a"},
+	}
+
+	for _, v := range values {
+		if path := joinPaths(v.a, v.b); path != v.path {
+			t.Fatalf("%q %q (expected) %q != %q (actual)", v.a, v.b, v.path, path)
+		}
+	}
+}